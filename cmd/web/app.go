@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sync/atomic"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/animethemes"
+	"github.com/AdamBeresnev/op-rating-app/internal/config"
+	"github.com/AdamBeresnev/op-rating-app/internal/linkcheck"
+	"github.com/AdamBeresnev/op-rating-app/internal/media"
+	"github.com/AdamBeresnev/op-rating-app/internal/ratelimit"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	"github.com/alexedwards/scs/v2"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Everything the handlers need to build services, SQL backed normally and in-memory with --demo
+type repositories struct {
+	tournaments store.TournamentRepository
+	users       store.UserRepository
+	uploads     store.UploadRepository
+}
+
+// Built once in main and shared by every request. Tests build their own with in-memory stores
+type application struct {
+	config         config.Config
+	db             *sqlx.DB // nil in demo mode
+	repos          repositories
+	sessionManager *scs.SessionManager
+	mediaLibrary   *media.Library // nil when uploads are disabled
+	themeResolver  animethemes.Resolver
+	localUserID    uuid.UUID // everyone is logged in as this user in local mode, uuid.Nil otherwise
+	rateLimits     rateLimits
+	draining       atomic.Bool // flipped on SIGTERM, /readyz starts failing
+
+	tournaments *service.TournamentService
+	matches     *service.MatchService
+	users       *service.UserService
+	uploads     *service.UploadService
+	linkChecks  *service.LinkCheckService
+}
+
+// One limiter per route group, all nil when rate limiting is off
+type rateLimits struct {
+	auth, create, edit, vote ratelimit.Limiter
+}
+
+func newRateLimits(cfg config.RateLimit) rateLimits {
+	if !cfg.Enabled {
+		return rateLimits{}
+	}
+	return rateLimits{
+		auth:   ratelimit.NewMemory(cfg.Auth.Count, cfg.Auth.Per),
+		create: ratelimit.NewMemory(cfg.Create.Count, cfg.Create.Per),
+		edit:   ratelimit.NewMemory(cfg.Edit.Count, cfg.Edit.Per),
+		vote:   ratelimit.NewMemory(cfg.Vote.Count, cfg.Vote.Per),
+	}
+}
+
+func newApplication(cfg config.Config, db *sqlx.DB, repos repositories, sessionManager *scs.SessionManager, mediaLibrary *media.Library, linkChecker *linkcheck.Checker, themeResolver animethemes.Resolver) *application {
+	return &application{
+		config:         cfg,
+		db:             db,
+		repos:          repos,
+		sessionManager: sessionManager,
+		mediaLibrary:   mediaLibrary,
+		themeResolver:  themeResolver,
+		rateLimits:     newRateLimits(cfg.RateLimit),
+
+		tournaments: service.NewTournamentService(repos.tournaments).WithLimits(service.Limits{
+			MaxEntries:            int(cfg.Limits.MaxEntries),
+			MaxTournamentsPerUser: int(cfg.Limits.MaxTournamentsPerUser),
+		}),
+		matches:    service.NewMatchService(repos.tournaments),
+		users:      service.NewUserService(repos.users),
+		uploads:    service.NewUploadService(mediaLibrary, repos.uploads),
+		linkChecks: service.NewLinkCheckService(repos.tournaments, linkChecker),
+	}
+}
@@ -0,0 +1,671 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/animethemes"
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/config"
+	"github.com/AdamBeresnev/op-rating-app/internal/linkcheck"
+	"github.com/AdamBeresnev/op-rating-app/internal/media"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/ratelimit"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/alexedwards/scs/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver struct{}
+
+func (fakeResolver) Resolve(ctx context.Context, themeURL string) (*animethemes.ThemeInfo, error) {
+	return &animethemes.ThemeInfo{Name: "Fake OP", VideoURL: themeURL}, nil
+}
+
+type testServer struct {
+	app    *application
+	server *httptest.Server
+	client *http.Client
+}
+
+// Spins up the whole router against in-memory stores. The client keeps cookies and doesn't follow redirects.
+// configure runs before the routes are built
+func newTestServer(t *testing.T, configure ...func(app *application)) *testServer {
+	t.Helper()
+
+	library, err := media.NewLibrary(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+
+	repos := repositories{
+		tournaments: store.NewMemoryTournamentStore(),
+		users:       store.NewMemoryUserStore(),
+		uploads:     store.NewMemoryUploadStore(),
+	}
+	app := newApplication(config.Default(), nil, repos, scs.New(), library, linkcheck.NewChecker(nil), fakeResolver{})
+	t.Cleanup(app.linkChecks.Close)
+
+	for _, fn := range configure {
+		fn(app)
+	}
+
+	server := httptest.NewServer(app.routes())
+	t.Cleanup(server.Close)
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return &testServer{app: app, server: server, client: client}
+}
+
+func (ts *testServer) get(t *testing.T, path string) *http.Response {
+	t.Helper()
+	resp, err := ts.client.Get(ts.server.URL + path)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+// Reads the CSRF token straight from the session store instead of scraping it out of the rendered page,
+// that's where the middleware keeps it. Any page view hands out a session first if there isn't one yet
+func (ts *testServer) csrfToken(t *testing.T) string {
+	t.Helper()
+	serverURL, err := url.Parse(ts.server.URL)
+	require.NoError(t, err)
+
+	for range 2 {
+		for _, cookie := range ts.client.Jar.Cookies(serverURL) {
+			if cookie.Name != ts.app.sessionManager.Cookie.Name {
+				continue
+			}
+			b, found, err := ts.app.sessionManager.Store.Find(cookie.Value)
+			require.NoError(t, err)
+			if !found {
+				continue
+			}
+			_, values, err := ts.app.sessionManager.Codec.Decode(b)
+			require.NoError(t, err)
+			if token, ok := values["csrfToken"].(string); ok {
+				return token
+			}
+		}
+		ts.get(t, "/login")
+	}
+	t.Fatal("no CSRF token in the session")
+	return ""
+}
+
+// Posts like htmx would, CSRF header included
+func (ts *testServer) post(t *testing.T, path string, form url.Values) *http.Response {
+	t.Helper()
+	return ts.send(t, http.MethodPost, path, form)
+}
+
+func (ts *testServer) send(t *testing.T, method string, path string, form url.Values) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.server.URL+path, strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("HX-Request", "true")
+	req.Header.Set(middleware.CSRFHeader, ts.csrfToken(t))
+	resp, err := ts.client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func (ts *testServer) loginAsGuest(t *testing.T) {
+	t.Helper()
+	resp := ts.post(t, "/auth/guest", nil)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	require.Equal(t, "/", resp.Header.Get("Location"))
+}
+
+// Creates a tournament through the form handler and returns its ID
+func (ts *testServer) createTournament(t *testing.T, tournamentType string, names ...string) string {
+	t.Helper()
+	form := url.Values{"name": {"E2E Tournament"}, "type": {tournamentType}}
+	for i, name := range names {
+		form.Set("entry_name_"+strconv.Itoa(i), name)
+	}
+	resp := ts.post(t, "/tournaments", form)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	redirect := resp.Header.Get("HX-Redirect")
+	require.True(t, strings.HasPrefix(redirect, "/tournaments/"), redirect)
+	return strings.TrimPrefix(redirect, "/tournaments/")
+}
+
+func TestRequiresLogin(t *testing.T) {
+	ts := newTestServer(t)
+
+	resp := ts.get(t, "/")
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/login", resp.Header.Get("Location"))
+
+	resp = ts.post(t, "/tournaments", url.Values{"name": {"Nope"}})
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	resp = ts.get(t, "/login")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestCreateViewAdvance(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	ctx := context.Background()
+
+	resp := ts.get(t, "/")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	tournamentID := ts.createTournament(t, "single", "Entry 1", "Entry 2", "Entry 3", "Entry 4")
+
+	resp = ts.get(t, "/tournaments/"+tournamentID)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = ts.get(t, "/tournaments/"+tournamentID+"/results")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	data, err := ts.app.tournaments.GetTournamentData(ctx, tournamentID)
+	require.NoError(t, err)
+	assert.Equal(t, "E2E Tournament", data.Tournament.Name)
+	require.Len(t, data.Entries, 4)
+	require.Len(t, data.Matches, 3)
+
+	// Play every match through the HTTP handlers, entry 1 of each match wins
+	for data.NextMatchID != nil {
+		matchID := data.NextMatchID.String()
+		resp = ts.get(t, "/matches/"+matchID)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		match, err := ts.app.matches.GetMatchViewData(ctx, matchID)
+		require.NoError(t, err)
+		resp = ts.post(t, "/matches/"+matchID+"/advance", url.Values{"winner_id": {match.Entry1.ID.String()}})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		data, err = ts.app.tournaments.GetTournamentData(ctx, tournamentID)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, bracket.TournamentCompleted, data.Tournament.Status)
+	for _, m := range data.Matches {
+		assert.Equal(t, bracket.MatchFinished, m.Status)
+	}
+
+	resp = ts.get(t, "/tournaments/"+tournamentID+"/history")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	history, err := ts.app.tournaments.GetHistory(ctx, tournamentID)
+	require.NoError(t, err)
+	require.Len(t, history.Events, 4)
+	for _, e := range history.Events[:3] {
+		assert.Equal(t, bracket.AuditMatchDecided, e.Action)
+		assert.NotEmpty(t, e.ActorName)
+	}
+	assert.Equal(t, bracket.AuditTournamentCreated, history.Events[3].Action)
+
+	resp = ts.get(t, "/tournaments/"+tournamentID+"/replay?step=2")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = ts.get(t, "/tournaments/"+tournamentID+"/replay?step=last")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAdvanceErrors(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	ctx := context.Background()
+
+	tournamentID := ts.createTournament(t, "double", "Entry 1", "Entry 2", "Entry 3", "Entry 4")
+	matches, err := ts.app.repos.tournaments.GetMatches(ctx, tournamentID)
+	require.NoError(t, err)
+
+	var first, second bracket.Match
+	for _, m := range matches {
+		if m.BracketSide == bracket.WinnersSide && m.RoundNumber == 1 {
+			if m.MatchOrder == 1 {
+				first = m
+			} else {
+				second = m
+			}
+		}
+	}
+
+	// Out of order
+	resp := ts.post(t, "/matches/"+second.ID.String()+"/advance", url.Values{"winner_id": {second.Entry1ID.String()}})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Equal(t, "#toasts", resp.Header.Get("HX-Retarget"))
+
+	// Winner from another match
+	resp = ts.post(t, "/matches/"+first.ID.String()+"/advance", url.Values{"winner_id": {second.Entry1ID.String()}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = ts.post(t, "/matches/not-a-uuid/advance", url.Values{"winner_id": {first.Entry1ID.String()}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = ts.post(t, "/matches/"+uuid.NewString()+"/advance", url.Values{"winner_id": {first.Entry1ID.String()}})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = ts.get(t, "/tournaments/"+uuid.NewString())
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	unchanged, err := ts.app.repos.tournaments.GetMatch(ctx, second.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.MatchPending, unchanged.Status)
+}
+
+func TestManageTournament(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	ctx := context.Background()
+
+	tournamentID := ts.createTournament(t, "single", "Entry 1", "Entry 2", "Entry 3")
+
+	resp := ts.post(t, "/tournaments/"+tournamentID+"/clone", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	cloneID := strings.TrimPrefix(resp.Header.Get("HX-Redirect"), "/tournaments/")
+	clone, err := ts.app.tournaments.GetTournamentData(ctx, cloneID)
+	require.NoError(t, err)
+	assert.Equal(t, "E2E Tournament (copy)", clone.Tournament.Name)
+	assert.Equal(t, bracket.TournamentDraft, clone.Tournament.Status)
+	require.Len(t, clone.Entries, 3)
+	assert.Equal(t, "Entry 3", clone.Entries[2].Name)
+
+	// Drafts can't be voted on until they're started, and only started once
+	match := clone.Matches[1]
+	resp = ts.post(t, "/matches/"+match.ID.String()+"/advance", url.Values{"winner_id": {match.Entry1ID.String()}})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp = ts.post(t, "/tournaments/"+cloneID+"/start", nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = ts.post(t, "/tournaments/"+cloneID+"/start", nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp = ts.post(t, "/matches/"+match.ID.String()+"/advance", url.Values{"winner_id": {match.Entry1ID.String()}})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = ts.post(t, "/tournaments/"+tournamentID+"/archive", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/", resp.Header.Get("HX-Redirect"))
+	resp = ts.get(t, "/tournaments/archived")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = ts.get(t, "/tournaments/"+tournamentID)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	guestCtx := context.WithValue(ctx, middleware.UserIDKey, uuid.MustParse(middleware.SuperUserID))
+	active, err := ts.app.tournaments.GetTournamentsForUser(guestCtx)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, cloneID, active[0].ID.String())
+	archived, err := ts.app.tournaments.GetArchivedTournamentsForUser(guestCtx)
+	require.NoError(t, err)
+	require.Len(t, archived, 1)
+	assert.Equal(t, tournamentID, archived[0].ID.String())
+
+	resp = ts.post(t, "/tournaments/"+tournamentID+"/unarchive", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	active, err = ts.app.tournaments.GetTournamentsForUser(guestCtx)
+	require.NoError(t, err)
+	assert.Len(t, active, 2)
+
+	resp = ts.send(t, http.MethodDelete, "/tournaments/"+tournamentID, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/", resp.Header.Get("HX-Redirect"))
+	resp = ts.get(t, "/tournaments/"+tournamentID)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = ts.send(t, http.MethodDelete, "/tournaments/"+tournamentID, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestEditEntry(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	ctx := context.Background()
+
+	tournamentID := ts.createTournament(t, "single", "Entry 1", "Entry 2")
+	entries, err := ts.app.repos.tournaments.GetEntries(ctx, tournamentID)
+	require.NoError(t, err)
+	entryID := entries[0].ID.String()
+
+	resp := ts.get(t, "/entries/"+entryID+"/edit")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = ts.post(t, "/entries/"+entryID, url.Values{"name": {"Renamed"}, "entry_artist": {"Someone"}, "entry_season": {"fall"}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/tournaments/"+tournamentID+"/results", resp.Header.Get("HX-Redirect"))
+
+	entry, err := ts.app.repos.tournaments.GetEntry(ctx, entryID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", entry.Name)
+	assert.Equal(t, "Someone", *entry.Artist)
+
+	resp = ts.post(t, "/entries/"+entryID, url.Values{"name": {"Renamed"}, "entry_season": {"monsoon"}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = ts.post(t, "/entries/"+entryID, url.Values{"name": {"Renamed"}, "entry_thumbnail": {"javascript:alert(1)"}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	entry, err = ts.app.repos.tournaments.GetEntry(ctx, entryID)
+	require.NoError(t, err)
+	assert.Nil(t, entry.ThumbnailURL)
+}
+
+func TestEditEntry_NotOwner(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+
+	// Someone else's tournament, made straight through the service
+	owner := &users.User{ID: uuid.New(), Username: "owner"}
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, owner.ID)
+	ctx = context.WithValue(ctx, users.UserKey, owner)
+	id, err := ts.app.tournaments.CreateTournament(ctx, "Not yours", bracket.SingleElimination, []service.EntryInput{{Name: "A"}, {Name: "B"}})
+	require.NoError(t, err)
+	entries, err := ts.app.repos.tournaments.GetEntries(ctx, id.String())
+	require.NoError(t, err)
+	entryID := entries[0].ID.String()
+
+	resp := ts.get(t, "/entries/"+entryID+"/edit")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = ts.post(t, "/entries/"+entryID, url.Values{"name": {"Hijacked"}})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	entry, err := ts.app.repos.tournaments.GetEntry(ctx, entryID)
+	require.NoError(t, err)
+	assert.Equal(t, "A", entry.Name)
+}
+
+func TestCheckLinks_NotOwner(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+
+	owner := &users.User{ID: uuid.New(), Username: "owner"}
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, owner.ID)
+	ctx = context.WithValue(ctx, users.UserKey, owner)
+	id, err := ts.app.tournaments.CreateTournament(ctx, "Not yours", bracket.SingleElimination, []service.EntryInput{{Name: "A"}, {Name: "B"}})
+	require.NoError(t, err)
+
+	resp := ts.post(t, "/tournaments/"+id.String()+"/check-links", url.Values{})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = ts.post(t, "/tournaments/"+uuid.NewString()+"/check-links", url.Values{})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestLocalModeAutoLogin(t *testing.T) {
+	ts := newTestServer(t, func(app *application) {
+		user, err := app.users.EnsureLocalUser(context.Background(), "Organizer")
+		require.NoError(t, err)
+		app.localUserID = user.ID
+	})
+
+	resp := ts.get(t, "/")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	tournamentID := ts.createTournament(t, "single", "Entry 1", "Entry 2")
+	data, err := ts.app.tournaments.GetTournamentData(context.Background(), tournamentID)
+	require.NoError(t, err)
+	assert.Equal(t, ts.app.localUserID, data.Tournament.OwnerID)
+
+	user, err := ts.app.users.GetUser(context.Background(), ts.app.localUserID.String())
+	require.NoError(t, err)
+	assert.True(t, user.IsAdmin)
+}
+
+func TestHealthChecks(t *testing.T) {
+	ts := newTestServer(t)
+
+	assert.Equal(t, http.StatusOK, ts.get(t, "/healthz").StatusCode)
+	assert.Equal(t, http.StatusOK, ts.get(t, "/readyz").StatusCode)
+
+	ts.app.draining.Store(true)
+	assert.Equal(t, http.StatusOK, ts.get(t, "/healthz").StatusCode)
+	assert.Equal(t, http.StatusServiceUnavailable, ts.get(t, "/readyz").StatusCode)
+}
+
+func TestMetrics(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	id := ts.createTournament(t, string(bracket.SingleElimination), "A", "B")
+
+	resp := ts.get(t, "/tournaments/"+id)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("X-Request-Id"))
+	ts.get(t, "/no/such/page")
+
+	resp, err := ts.client.Get(ts.server.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `op_rating_http_requests_total{method="GET",route="/tournaments/{id}",status="200"}`)
+	assert.Contains(t, string(body), `route="unmatched",status="404"`)
+	assert.Contains(t, string(body), "op_rating_tournaments_created_total")
+	assert.NotContains(t, string(body), id)
+}
+
+func TestCSRF(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	id := ts.createTournament(t, string(bracket.SingleElimination), "A", "B")
+	matches, err := ts.app.repos.tournaments.GetMatches(context.Background(), id)
+	require.NoError(t, err)
+	match := matches[0]
+
+	// What a form on another site would send: the session cookie rides along, the token doesn't
+	forge := func(path string, form url.Values, header http.Header) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, ts.server.URL+path, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err := ts.client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	advance := "/matches/" + match.ID.String() + "/advance"
+	winner := url.Values{"winner_id": {match.Entry1ID.String()}}
+
+	assert.Equal(t, http.StatusForbidden, forge(advance, winner, nil).StatusCode)
+	assert.Equal(t, http.StatusForbidden, forge(advance, winner, http.Header{middleware.CSRFHeader: {"guessed"}}).StatusCode)
+	assert.Equal(t, http.StatusForbidden, forge("/tournaments", url.Values{"name": {"Forged"}, "entry_name_0": {"X"}}, nil).StatusCode)
+	assert.Equal(t, http.StatusForbidden, forge("/logout", nil, nil).StatusCode)
+	assert.Equal(t, http.StatusForbidden, forge("/auth/guest", nil, nil).StatusCode)
+
+	unchanged, err := ts.app.repos.tournaments.GetMatch(context.Background(), match.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.MatchPending, unchanged.Status)
+
+	// Plain forms send the token as a field instead of a header
+	assert.Equal(t, http.StatusFound, forge("/auth/guest", url.Values{middleware.CSRFFormField: {ts.csrfToken(t)}}, nil).StatusCode)
+	assert.Equal(t, http.StatusOK, ts.post(t, advance, winner).StatusCode)
+}
+
+func TestRateLimit(t *testing.T) {
+	ts := newTestServer(t, func(app *application) {
+		app.rateLimits.create = ratelimit.NewMemory(2, time.Hour)
+	})
+	ts.loginAsGuest(t)
+
+	ts.createTournament(t, "single", "A", "B")
+	ts.createTournament(t, "single", "A", "B")
+
+	resp := ts.post(t, "/tournaments", url.Values{"name": {"Third"}, "entry_name_0": {"A"}})
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "1800", resp.Header.Get("Retry-After"))
+
+	// Other groups aren't affected
+	assert.Equal(t, http.StatusFound, ts.post(t, "/auth/guest", nil).StatusCode)
+}
+
+func TestTournamentSettings(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	ctx := context.Background()
+
+	tournamentID := ts.createTournament(t, "single", "Entry 1", "Entry 2", "Entry 3", "Entry 4")
+
+	resp := ts.get(t, "/tournaments/"+tournamentID+"/settings")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	form := url.Values{
+		"name":              {"Renamed"},
+		"description":       {"Best openings of the decade"},
+		"cover_image_url":   {"https://example.com/cover.png"},
+		"visibility":        {"public"},
+		"score_requirement": {"3"},
+		"type":              {"double"},
+		"ordering":          {"interleaved"},
+	}
+	resp = ts.post(t, "/tournaments/"+tournamentID+"/settings", form)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/tournaments/"+tournamentID, resp.Header.Get("HX-Redirect"))
+
+	data, err := ts.app.tournaments.GetTournamentData(ctx, tournamentID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", data.Tournament.Name)
+	assert.Equal(t, bracket.DoubleElimination, data.Tournament.Type)
+	assert.Equal(t, 3, data.Tournament.ScoreRequirement)
+
+	form.Set("cover_image_url", "ftp://example.com/cover.png")
+	resp = ts.post(t, "/tournaments/"+tournamentID+"/settings", form)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Once someone voted the bracket stays as it is
+	match := data.Matches[0]
+	for _, m := range data.Matches {
+		if m.Entry1ID != nil && m.Entry2ID != nil {
+			match = m
+			break
+		}
+	}
+	resp = ts.post(t, "/matches/"+match.ID.String()+"/advance", url.Values{"winner_id": {match.Entry1ID.String()}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	form.Set("cover_image_url", "")
+	form.Set("type", "single")
+	resp = ts.post(t, "/tournaments/"+tournamentID+"/settings", form)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestMatchOverrides(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	ctx := context.Background()
+
+	tournamentID := ts.createTournament(t, "single", "Entry 1", "Entry 2", "Entry 3", "Entry 4")
+	data, err := ts.app.tournaments.GetTournamentData(ctx, tournamentID)
+	require.NoError(t, err)
+	second := data.Matches[1]
+
+	resp := ts.post(t, "/matches/"+second.ID.String()+"/force", url.Values{"winner_id": {second.Entry2ID.String()}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/tournaments/"+tournamentID, resp.Header.Get("HX-Redirect"))
+	resp = ts.post(t, "/matches/"+second.ID.String()+"/force", url.Values{"winner_id": {second.Entry1ID.String()}})
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	resp = ts.post(t, "/entries/"+data.Entries[0].ID.String()+"/disqualify", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	data, err = ts.app.tournaments.GetTournamentData(ctx, tournamentID)
+	require.NoError(t, err)
+	assert.True(t, data.Entries[0].IsDisqualified())
+	assert.True(t, data.Matches[0].Forfeit)
+}
+
+func TestMatchSchedule(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	ctx := context.Background()
+
+	tournamentID := ts.createTournament(t, "single", "Entry 1", "Entry 2")
+	data, err := ts.app.tournaments.GetTournamentData(ctx, tournamentID)
+	require.NoError(t, err)
+	matchID := data.Matches[0].ID.String()
+
+	resp := ts.post(t, "/matches/"+matchID+"/schedule", url.Values{"scheduled_at": {"next tuesday"}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = ts.post(t, "/matches/"+matchID+"/schedule", url.Values{
+		"scheduled_at": {"2026-11-02T18:30:00.000Z"},
+		"stream_url":   {"https://example.com/live"},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/matches/"+matchID, resp.Header.Get("HX-Redirect"))
+
+	resp, err = ts.client.Get(ts.server.URL + "/tournaments/" + tournamentID + "/calendar.ics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/calendar; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), "DTSTART:20261102T183000Z\r\n")
+	assert.Contains(t, string(body), "SUMMARY:E2E Tournament: Entry 1 vs Entry 2\r\n")
+
+	// Calendar apps poll without cookies, that shouldn't leave a session behind every time
+	resp, err = http.Get(ts.server.URL + "/tournaments/" + tournamentID + "/calendar.ics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Empty(t, resp.Cookies())
+
+	resp = ts.post(t, "/matches/"+matchID+"/schedule", url.Values{"scheduled_at": {""}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	data, err = ts.app.tournaments.GetTournamentData(ctx, tournamentID)
+	require.NoError(t, err)
+	assert.Nil(t, data.Matches[0].ScheduledAt)
+	assert.Equal(t, bracket.MatchPending, data.Matches[0].Status)
+}
+
+func TestGroupStage(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	ctx := context.Background()
+
+	form := url.Values{"name": {"Groups"}, "type": {"single"}, "group_count": {"2"}, "group_advance": {"3"}}
+	for i, name := range []string{"Entry 1", "Entry 2", "Entry 3", "Entry 4"} {
+		form.Set("entry_name_"+strconv.Itoa(i), name)
+	}
+	resp := ts.post(t, "/tournaments", form)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	form.Set("group_advance", "1")
+	resp = ts.post(t, "/tournaments", form)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	tournamentID := strings.TrimPrefix(resp.Header.Get("HX-Redirect"), "/tournaments/")
+
+	data, err := ts.app.tournaments.GetTournamentData(ctx, tournamentID)
+	require.NoError(t, err)
+	require.Len(t, data.Groups, 2)
+	require.Len(t, data.Matches, 2)
+	resp = ts.get(t, "/tournaments/"+tournamentID)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Both groups decided, the two winners meet in the final
+	for _, m := range data.Matches {
+		resp = ts.post(t, "/matches/"+m.ID.String()+"/advance", url.Values{"winner_id": {m.Entry1ID.String()}})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	data, err = ts.app.tournaments.GetTournamentData(ctx, tournamentID)
+	require.NoError(t, err)
+	require.Len(t, data.Matches, 3)
+	var winners, finalists []uuid.UUID
+	for _, m := range data.Matches {
+		if m.BracketSide == bracket.GroupSide {
+			winners = append(winners, *m.Entry1ID)
+		} else {
+			finalists = append(finalists, *m.Entry1ID, *m.Entry2ID)
+		}
+	}
+	assert.ElementsMatch(t, winners, finalists)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/httputil"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/AdamBeresnev/op-rating-app/views"
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/markbates/goth/gothic"
+)
+
+type authHandler struct {
+	users          *service.UserService
+	sessionManager *scs.SessionManager
+	providers      []string
+	allowGuest     bool
+}
+
+func (h *authHandler) loginPage(w http.ResponseWriter, r *http.Request) {
+	views.LoginPage(h.providers, h.allowGuest).Render(r.Context(), w)
+}
+
+func (h *authHandler) begin(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	r = r.WithContext(context.WithValue(r.Context(), "provider", provider))
+
+	gothic.BeginAuthHandler(w, r)
+}
+
+func (h *authHandler) callback(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	r = r.WithContext(context.WithValue(r.Context(), "provider", provider))
+
+	gothUser, err := gothic.CompleteUserAuth(w, r)
+	if err != nil {
+		httputil.BadRequest(w, r, "Authentication failure", err)
+		return
+	}
+
+	user, err := h.users.FindOrCreateUserByProvider(r.Context(), gothUser)
+	if err != nil {
+		httputil.InternalServerError(w, r, "Failed to find or create user", err)
+		return
+	}
+
+	h.sessionManager.Put(r.Context(), "userID", user.ID.String())
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (h *authHandler) guest(w http.ResponseWriter, r *http.Request) {
+	if !h.allowGuest {
+		httputil.NotFound(w, r, "Guest login is disabled", nil)
+		return
+	}
+	user, err := h.users.EnsureGuestUser(r.Context())
+	if err != nil {
+		httputil.InternalServerError(w, r, "Failed to login as guest", err)
+		return
+	}
+
+	h.sessionManager.Put(r.Context(), "userID", user.ID.String())
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (h *authHandler) logout(w http.ResponseWriter, r *http.Request) {
+	h.sessionManager.Destroy(r.Context())
+	if r.Header.Get("HX-Request") != "" {
+		w.Header().Set("HX-Redirect", "/login")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusFound)
+}
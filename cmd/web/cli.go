@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/config"
+	"github.com/AdamBeresnev/op-rating-app/internal/db"
+	"github.com/AdamBeresnev/op-rating-app/internal/demo"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/jmoiron/sqlx"
+)
+
+const usage = `Usage: web [command] [flags] [args]
+
+Commands:
+  serve [--demo] [--local]         start the web server, the default when no command is given
+  local                            same as serve --local, for running on your own computer
+  migrate up                       apply all pending migrations
+  migrate down [n]                 roll back the last n migrations (default 1)
+  migrate status                   show the current migration version
+  backup <file>                    copy the SQLite database to file, safe while the server is running
+  restore <file>                   replace the SQLite database with a backup, stop the server first
+  user list
+  user promote <id>                make a user an admin
+  user delete <id>                 delete a user along with their tournaments
+  tournament list
+  tournament export <id> [file]    write a tournament as JSON to file or stdout
+  tournament import [--owner <user id>] <file>
+  tournament history <id>          who changed what, also works for deleted tournaments
+  tournament delete <id>
+  seed-demo                        add the demo tournaments to the database
+
+Every command takes --config <file>, see config.example.toml.
+`
+
+// What runs when the binary is started without a command. Desktop builds set it to local so double-clicking just works:
+// go build -ldflags "-X main.defaultCommand=local" ./cmd/web
+var defaultCommand = "serve"
+
+// Dispatches os.Args to a subcommand. Anything starting with a flag goes to the default command so `web --demo` keeps working
+func run(args []string, stdout io.Writer) error {
+	command := defaultCommand
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		return runServe(args)
+	case "local":
+		return runServe(append([]string{"--local"}, args...))
+	case "migrate":
+		return runMigrate(args, stdout)
+	case "backup":
+		return runBackup(args, stdout)
+	case "restore":
+		return runRestore(args, stdout)
+	case "user":
+		return runAdmin(args, stdout, userCommand)
+	case "tournament":
+		return runAdmin(args, stdout, tournamentCommand)
+	case "seed-demo":
+		return runSeedDemo(args, stdout)
+	case "help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+}
+
+// Every command gets its own flag set with --config on it
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), usage) }
+	configPath := fs.String("config", "", "optional TOML or YAML config file, CONFIG_FILE works too")
+	return fs, configPath
+}
+
+func loadConfig(path string) (config.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+func runServe(args []string) error {
+	fs, configPath := newFlagSet("serve")
+	demoMode := fs.Bool("demo", false, "run against an in-memory store seeded with sample tournaments, nothing is written to disk")
+	localMode := fs.Bool("local", false, "single organizer mode: random port, data in your user folder, no login, opens the browser")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if *demoMode {
+		cfg.Features.Demo = true
+	}
+	if *localMode && !cfg.Features.Local {
+		if err := cfg.UseLocalMode(); err != nil {
+			return err
+		}
+	}
+
+	slog.SetDefault(newLogger(cfg.LogFormat, os.Stderr))
+
+	// Listen first, local mode asks for a free port and only knows its URL afterwards
+	listener, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return err
+	}
+	if cfg.Features.Local {
+		cfg.BaseURL = "http://" + listener.Addr().String()
+	}
+
+	middleware.InitAuth(cfg.OAuth)
+
+	app, err := buildApplication(cfg)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	if app.db != nil {
+		defer app.db.Close()
+	}
+	// Runs before the database closes, deferred calls go last in first out. Waits for the periodic job too
+	defer app.linkChecks.Close()
+
+	// SIGTERM is what fly sends on deploys, Ctrl+C locally
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Re-check links of unfinished tournaments in the background, e.g. LINK_CHECK_INTERVAL=6h
+	if cfg.LinkCheckInterval > 0 {
+		app.linkChecks.StartPeriodic(cfg.LinkCheckInterval)
+	}
+
+	server := &http.Server{
+		Handler:           app.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	log.Printf("Server starting on %s", cfg.BaseURL)
+	if cfg.Features.Local {
+		log.Printf("Data is stored in %s", cfg.DataDir)
+		openBrowser(cfg.BaseURL)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.Serve(listener) }()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop() // a second Ctrl+C kills the process right away
+
+	return shutdown(app, server, cfg.Server.DrainDelay, cfg.Server.ShutdownGracePeriod)
+}
+
+// The log package goes through the same handler once this is the default, so log.Printf lines come out as JSON too
+func newLogger(format string, w io.Writer) *slog.Logger {
+	if format == "text" {
+		return slog.New(slog.NewTextHandler(w, nil))
+	}
+	return slog.New(slog.NewJSONHandler(w, nil))
+}
+
+// Stops taking new connections and waits for in-flight requests, so an AdvanceWinner halfway
+// through its transaction gets to commit. The database is closed by the caller only after this returns.
+// /readyz fails for drainDelay first, the proxy needs a health check or two to stop sending traffic here
+func shutdown(app *application, server *http.Server, drainDelay time.Duration, grace time.Duration) error {
+	app.draining.Store(true)
+	if drainDelay > 0 {
+		log.Printf("Shutting down, draining for %s", drainDelay)
+		time.Sleep(drainDelay)
+	}
+	log.Printf("Shutting down, waiting up to %s for requests to finish", grace)
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Printf("Grace period over, dropping the remaining requests")
+		server.Close()
+	}
+
+	// Both SQL session stores run a cleanup goroutine that would otherwise hit the closed database
+	if store, ok := app.sessionManager.Store.(interface{ StopCleanup() }); ok {
+		store.StopCleanup()
+	}
+	if err == nil {
+		log.Printf("All requests finished")
+	}
+	return err
+}
+
+// Opens the configured database without running migrations, the migrate command wants to do that itself
+func openDatabase(configPath string) (config.Config, *sqlx.DB, error) {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return cfg, nil, err
+	}
+	database, err := db.InitDB(cfg.Database.Driver, cfg.Database.DSN())
+	return cfg, database, err
+}
+
+func runMigrate(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("migrate needs one of up, down or status")
+	}
+	action := args[0]
+	fs, configPath := newFlagSet("migrate " + action)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	_, database, err := openDatabase(*configPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	switch action {
+	case "up":
+		if err := db.RunMigrations(database); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if fs.NArg() > 0 {
+			steps, err = strconv.Atoi(fs.Arg(0))
+			if err != nil || steps < 1 {
+				return fmt.Errorf("migrate down takes a positive number of steps, got %q", fs.Arg(0))
+			}
+		}
+		if err := db.RollbackMigrations(database, steps); err != nil {
+			return err
+		}
+	case "status":
+	default:
+		return fmt.Errorf("unknown migrate action %q, use up, down or status", action)
+	}
+
+	version, dirty, err := db.MigrationVersion(database)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Database is at migration %d", version)
+	if dirty {
+		fmt.Fprint(stdout, " (dirty, the last migration failed halfway and needs fixing by hand)")
+	}
+	fmt.Fprintln(stdout)
+	return nil
+}
+
+func runBackup(args []string, stdout io.Writer) error {
+	fs, configPath := newFlagSet("backup")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("backup needs a target file")
+	}
+
+	_, database, err := openDatabase(*configPath)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	if err := db.Backup(database, fs.Arg(0)); err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	fmt.Fprintf(stdout, "Backed up to %s\n", fs.Arg(0))
+	return nil
+}
+
+func runRestore(args []string, stdout io.Writer) error {
+	fs, configPath := newFlagSet("restore")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("restore needs a backup file")
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.Database.Driver != db.DriverSQLite {
+		return db.ErrBackupUnsupported
+	}
+	if err := db.Restore(fs.Arg(0), cfg.Database.FilePath()); err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+	fmt.Fprintf(stdout, "Restored %s from %s, migrations will run on the next start\n", cfg.Database.FilePath(), fs.Arg(0))
+	return nil
+}
+
+func runSeedDemo(args []string, stdout io.Writer) error {
+	fs, configPath := newFlagSet("seed-demo")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	app, err := openApplication(*configPath)
+	if err != nil {
+		return err
+	}
+	defer app.db.Close()
+
+	if err := demo.Seed(context.Background(), app.users, app.tournaments, app.matches); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "Added the demo tournaments, they belong to the guest user")
+	return nil
+}
+
+// The same application the server builds, minus the HTTP side. Always the real database, never demo mode
+func openApplication(configPath string) (*application, error) {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Features.Demo = false
+	return buildApplication(cfg)
+}
+
+// web <noun> <action> [flags] [args], parsed
+type adminArgs struct {
+	action string
+	args   []string
+	owner  string // only used by tournament import
+}
+
+type adminCommand func(ctx context.Context, app *application, cmd adminArgs, stdout io.Writer) error
+
+func runAdmin(args []string, stdout io.Writer, command adminCommand) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing action\n\n%s", usage)
+	}
+	fs, configPath := newFlagSet(args[0])
+	owner := fs.String("owner", "", "user ID that owns imported tournaments, defaults to the guest user")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	app, err := openApplication(*configPath)
+	if err != nil {
+		return err
+	}
+	defer app.db.Close()
+
+	return command(context.Background(), app, adminArgs{action: args[0], args: fs.Args(), owner: *owner}, stdout)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/google/uuid"
+)
+
+// Turns the store's sql.ErrNoRows into something that reads well in a terminal
+func notFound(err error, what, id string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no %s with ID %s", what, id)
+	}
+	return err
+}
+
+func requireArgs(cmd adminArgs, n int, usage string) error {
+	if len(cmd.args) != n {
+		return fmt.Errorf("usage: %s", usage)
+	}
+	return nil
+}
+
+func userCommand(ctx context.Context, app *application, cmd adminArgs, stdout io.Writer) error {
+	switch cmd.action {
+	case "list":
+		list, err := app.users.ListUsers(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSERNAME\tEMAIL\tPROVIDER\tADMIN\tCREATED")
+		for _, u := range list {
+			provider := "-"
+			if u.Provider != nil {
+				provider = *u.Provider
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\n", u.ID, u.Username, u.Email, provider, u.IsAdmin, u.CreatedAt.Format("2006-01-02"))
+		}
+		return w.Flush()
+
+	case "promote":
+		if err := requireArgs(cmd, 1, "web user promote <id>"); err != nil {
+			return err
+		}
+		user, err := app.users.PromoteUser(ctx, cmd.args[0])
+		if err != nil {
+			return notFound(err, "user", cmd.args[0])
+		}
+		fmt.Fprintf(stdout, "%s is now an admin\n", user.Username)
+		return nil
+
+	case "delete":
+		if err := requireArgs(cmd, 1, "web user delete <id>"); err != nil {
+			return err
+		}
+		if err := app.users.DeleteUser(ctx, cmd.args[0]); err != nil {
+			return notFound(err, "user", cmd.args[0])
+		}
+		fmt.Fprintf(stdout, "Deleted user %s\n", cmd.args[0])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown user action %q, use list, promote or delete", cmd.action)
+	}
+}
+
+func tournamentCommand(ctx context.Context, app *application, cmd adminArgs, stdout io.Writer) error {
+	switch cmd.action {
+	case "list":
+		list, err := app.tournaments.ListAllTournaments(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tTYPE\tSTATUS\tOWNER\tCREATED")
+		for _, t := range list {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Type, t.Status, t.OwnerID, t.CreatedAt.Format("2006-01-02"))
+		}
+		return w.Flush()
+
+	case "export":
+		if len(cmd.args) < 1 || len(cmd.args) > 2 {
+			return fmt.Errorf("usage: web tournament export <id> [file]")
+		}
+		export, err := app.tournaments.ExportTournament(ctx, cmd.args[0])
+		if err != nil {
+			return notFound(err, "tournament", cmd.args[0])
+		}
+		out := stdout
+		if len(cmd.args) == 2 {
+			f, err := os.Create(cmd.args[1])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(export)
+
+	case "import":
+		if err := requireArgs(cmd, 1, "web tournament import [--owner <user id>] <file>"); err != nil {
+			return err
+		}
+		ownerID := cmd.owner
+		if ownerID == "" {
+			ownerID = middleware.SuperUserID
+		}
+		owner, err := app.users.GetUser(ctx, ownerID)
+		if err != nil {
+			return notFound(err, "user", ownerID)
+		}
+
+		data, err := os.ReadFile(cmd.args[0])
+		if err != nil {
+			return err
+		}
+		var export service.TournamentExport
+		if err := json.Unmarshal(data, &export); err != nil {
+			return fmt.Errorf("%s is not a tournament export: %w", cmd.args[0], err)
+		}
+		id, err := app.tournaments.ImportTournament(ctx, &export, owner.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Imported %q as %s, owned by %s\n", export.Tournament.Name, id, owner.Username)
+		return nil
+
+	case "history":
+		if err := requireArgs(cmd, 1, "web tournament history <id>"); err != nil {
+			return err
+		}
+		events, err := app.tournaments.GetAuditLog(ctx, cmd.args[0])
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return fmt.Errorf("no history for tournament %s", cmd.args[0])
+		}
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIME\tACTION\tMATCH\tACTOR")
+		for _, e := range events {
+			match := "-"
+			if e.MatchID != nil {
+				match = e.MatchID.String()
+			}
+			actor := "-"
+			if e.ActorID != nil {
+				actor = fmt.Sprintf("%s (%s)", e.ActorName, e.ActorID)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.CreatedAt.Format(time.RFC3339), e.Action, match, actor)
+		}
+		return w.Flush()
+
+	case "delete":
+		if err := requireArgs(cmd, 1, "web tournament delete <id>"); err != nil {
+			return err
+		}
+		if _, err := uuid.Parse(cmd.args[0]); err != nil {
+			return fmt.Errorf("%q is not a tournament ID", cmd.args[0])
+		}
+		if err := app.tournaments.AdminDeleteTournament(ctx, cmd.args[0]); err != nil {
+			return notFound(err, "tournament", cmd.args[0])
+		}
+		fmt.Fprintf(stdout, "Deleted tournament %s\n", cmd.args[0])
+		return nil
+
+	default:
+		return fmt.Errorf("unknown tournament action %q, use list, export, import, history or delete", cmd.action)
+	}
+}
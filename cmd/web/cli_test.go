@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunUnknownCommand(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, run([]string{"help"}, &out))
+	assert.Contains(t, out.String(), "tournament export")
+
+	err := run([]string{"frobnicate"}, &out)
+	assert.ErrorContains(t, err, `unknown command "frobnicate"`)
+	err = run([]string{"migrate"}, &out)
+	assert.Error(t, err)
+}
+
+func TestTournamentCommands(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	ctx := context.Background()
+	tournamentID := ts.createTournament(t, "single", "Entry 1", "Entry 2", "Entry 3")
+
+	var out bytes.Buffer
+	require.NoError(t, tournamentCommand(ctx, ts.app, adminArgs{action: "list"}, &out))
+	assert.Contains(t, out.String(), tournamentID)
+
+	file := filepath.Join(t.TempDir(), "export.json")
+	require.NoError(t, tournamentCommand(ctx, ts.app, adminArgs{action: "export", args: []string{tournamentID, file}}, &out))
+
+	out.Reset()
+	require.NoError(t, tournamentCommand(ctx, ts.app, adminArgs{action: "import", args: []string{file}}, &out))
+	assert.Contains(t, out.String(), `Imported "E2E Tournament"`)
+
+	all, err := ts.app.tournaments.ListAllTournaments(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	require.NoError(t, tournamentCommand(ctx, ts.app, adminArgs{action: "delete", args: []string{tournamentID}}, &out))
+	err = tournamentCommand(ctx, ts.app, adminArgs{action: "delete", args: []string{tournamentID}}, &out)
+	assert.ErrorContains(t, err, "no tournament with ID")
+
+	// The history outlives the tournament
+	out.Reset()
+	require.NoError(t, tournamentCommand(ctx, ts.app, adminArgs{action: "history", args: []string{tournamentID}}, &out))
+	assert.Contains(t, out.String(), "tournament_created")
+	assert.Contains(t, out.String(), "tournament_deleted")
+
+	err = tournamentCommand(ctx, ts.app, adminArgs{action: "import", args: []string{file}, owner: "00000000-0000-0000-0000-000000000099"}, &out)
+	assert.ErrorContains(t, err, "no user with ID")
+}
+
+func TestUserCommands(t *testing.T) {
+	ts := newTestServer(t)
+	ts.loginAsGuest(t)
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	require.NoError(t, userCommand(ctx, ts.app, adminArgs{action: "promote", args: []string{middleware.SuperUserID}}, &out))
+
+	out.Reset()
+	require.NoError(t, userCommand(ctx, ts.app, adminArgs{action: "list"}, &out))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], "true")
+
+	err := userCommand(ctx, ts.app, adminArgs{action: "delete", args: []string{middleware.SuperUserID}}, &out)
+	assert.ErrorContains(t, err, "guest user can't be deleted")
+	err = userCommand(ctx, ts.app, adminArgs{action: "promote", args: []string{"nope"}}, &out)
+	assert.ErrorContains(t, err, "no user with ID")
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/animethemes"
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/httputil"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/AdamBeresnev/op-rating-app/internal/utils"
+	"github.com/AdamBeresnev/op-rating-app/internal/video"
+	"github.com/AdamBeresnev/op-rating-app/views"
+	"github.com/go-chi/chi/v5"
+)
+
+type entryHandler struct {
+	tournaments   *service.TournamentService
+	matches       *service.MatchService
+	themeResolver animethemes.Resolver
+}
+
+// Fills an entry row on the create form from an animethemes.moe link
+func (h *entryHandler) lookup(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httputil.BadRequest(w, r, "Invalid form data", err)
+		return
+	}
+	index, err := strconv.Atoi(r.Form.Get("index"))
+	if err != nil {
+		httputil.BadRequest(w, r, "Invalid entry index", err)
+		return
+	}
+	indexStr := strconv.Itoa(index)
+	link := strings.TrimSpace(r.Form.Get("entry_embed_link_" + indexStr))
+
+	info, err := h.themeResolver.Resolve(r.Context(), link)
+	if err != nil {
+		if errors.Is(err, animethemes.ErrNotAnimeThemesURL) || errors.Is(err, animethemes.ErrThemeNotFound) {
+			httputil.BadRequest(w, r, err.Error(), err)
+			return
+		}
+		httputil.InternalServerError(w, r, "Failed to look up theme", err)
+		return
+	}
+
+	values := views.EntryFormValues{
+		Name:      r.Form.Get("entry_name_" + indexStr),
+		EmbedLink: link,
+		Start:     r.Form.Get("entry_start_" + indexStr),
+		End:       r.Form.Get("entry_end_" + indexStr),
+		Metadata:  info.Metadata,
+	}
+	// Don't overwrite anything the user already typed in
+	if strings.TrimSpace(values.Name) == "" {
+		values.Name = info.Name
+	}
+	if info.VideoURL != "" {
+		values.EmbedLink = info.VideoURL
+	}
+	views.EntryPrefilled(index, values).Render(r.Context(), w)
+}
+
+func (h *entryHandler) edit(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	entry, err := h.tournaments.GetEntry(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	views.EntryEditPage(entry).Render(r.Context(), w)
+}
+
+func (h *entryHandler) update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := r.ParseForm(); err != nil {
+		httputil.BadRequest(w, r, "Invalid form data", err)
+		return
+	}
+	name := strings.TrimSpace(r.Form.Get("name"))
+	if name == "" || len(name) > 50 {
+		httputil.BadRequest(w, r, "Entry name must be between 1 and 50 characters", nil)
+		return
+	}
+	metadata, err := parseEntryMetadata(r.Form, "")
+	if err != nil {
+		httputil.BadRequest(w, r, err.Error(), err)
+		return
+	}
+
+	entry, err := h.tournaments.UpdateEntryMetadata(r.Context(), id, name, metadata)
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/tournaments/%s/results", entry.TournamentID))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *entryHandler) disqualify(w http.ResponseWriter, r *http.Request) {
+	tournamentID, err := h.matches.DisqualifyEntry(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/tournaments/%s", tournamentID))
+	w.WriteHeader(http.StatusOK)
+}
+
+// Empty timestamps are fine, they just mean "use whatever the link says"
+func parseOptionalTimestamp(value string) (*int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	seconds, err := video.ParseTimestamp(value)
+	if err != nil {
+		return nil, err
+	}
+	return &seconds, nil
+}
+
+// Reads the optional entry details. suffix is "_<index>" on the create form and empty on the edit page
+func parseEntryMetadata(form url.Values, suffix string) (bracket.EntryMetadata, error) {
+	var m bracket.EntryMetadata
+	m.SeriesTitle = utils.StringOrNil(form.Get("entry_series" + suffix))
+	m.SongTitle = utils.StringOrNil(form.Get("entry_song" + suffix))
+	m.Artist = utils.StringOrNil(form.Get("entry_artist" + suffix))
+	m.ThumbnailURL = utils.StringOrNil(form.Get("entry_thumbnail" + suffix))
+
+	if themeType := form.Get("entry_theme_type" + suffix); themeType != "" {
+		t := bracket.ThemeType(themeType)
+		if t != bracket.ThemeOpening && t != bracket.ThemeEnding && t != bracket.ThemeInsert {
+			return m, fmt.Errorf("unknown theme type %q", themeType)
+		}
+		m.ThemeType = &t
+	}
+
+	if season := form.Get("entry_season" + suffix); season != "" {
+		s := bracket.Season(season)
+		if !slices.Contains(bracket.Seasons, s) {
+			return m, fmt.Errorf("unknown season %q", season)
+		}
+		m.Season = &s
+	}
+
+	if seq := strings.TrimSpace(form.Get("entry_theme_seq" + suffix)); seq != "" {
+		n, err := strconv.Atoi(seq)
+		if err != nil || n < 1 {
+			return m, fmt.Errorf("theme number must be a positive number")
+		}
+		m.ThemeSequence = &n
+	}
+
+	if year := strings.TrimSpace(form.Get("entry_year" + suffix)); year != "" {
+		n, err := strconv.Atoi(year)
+		if err != nil || n < 1900 || n > 2100 {
+			return m, fmt.Errorf("year must be between 1900 and 2100")
+		}
+		m.Year = &n
+	}
+
+	return m, nil
+}
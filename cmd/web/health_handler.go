@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/db"
+	"github.com/jmoiron/sqlx"
+)
+
+// Probes for fly.io. /healthz only says the process is up, /readyz says it should get traffic
+type healthHandler struct {
+	db       *sqlx.DB     // nil in demo mode, always ready then
+	draining *atomic.Bool // set once shutdown starts so the proxy stops sending new requests
+}
+
+func (h *healthHandler) live(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "ok")
+}
+
+func (h *healthHandler) ready(w http.ResponseWriter, r *http.Request) {
+	if err := h.check(r.Context()); err != nil {
+		slog.Warn("not ready", "error", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (h *healthHandler) check(ctx context.Context) error {
+	if h.draining.Load() {
+		return fmt.Errorf("shutting down")
+	}
+	if h.db == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	want, err := db.LatestMigration(h.db.DriverName())
+	if err != nil {
+		return err
+	}
+	return db.CheckReady(ctx, h.db, want)
+}
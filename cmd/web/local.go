@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log"
+	"os/user"
+	"strings"
+
+	"github.com/pkg/browser"
+)
+
+// Whoever is logged into the computer, without the Windows domain in front
+func localUserName() string {
+	current, err := user.Current()
+	if err != nil {
+		return "Organizer"
+	}
+	if current.Name != "" {
+		return current.Name
+	}
+	if i := strings.LastIndexByte(current.Username, '\\'); i >= 0 {
+		return current.Username[i+1:]
+	}
+	return current.Username
+}
+
+// Not fatal, the URL is in the log for when there's no browser to open
+func openBrowser(url string) {
+	if err := browser.OpenURL(url); err != nil {
+		log.Printf("Couldn't open a browser, go to %s yourself: %v", url, err)
+	}
+}
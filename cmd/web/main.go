@@ -1,39 +1,109 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
+	"os"
 	"time"
 
+	"github.com/AdamBeresnev/op-rating-app/internal/animethemes"
+	"github.com/AdamBeresnev/op-rating-app/internal/config"
 	"github.com/AdamBeresnev/op-rating-app/internal/db"
-	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
-	"github.com/alexedwards/scs/sqlite3store"
+	"github.com/AdamBeresnev/op-rating-app/internal/demo"
+	"github.com/AdamBeresnev/op-rating-app/internal/linkcheck"
+	"github.com/AdamBeresnev/op-rating-app/internal/media"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	"github.com/AdamBeresnev/op-rating-app/static"
 	"github.com/alexedwards/scs/v2"
-	"github.com/joho/godotenv"
+	"github.com/jmoiron/sqlx"
 )
 
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using environment variables")
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Fatal(err)
 	}
+}
 
-	database := db.InitDB()
-	defer database.Close()
+// Opens the database (or the in-memory stores for demo mode) and wires everything together
+func buildApplication(cfg config.Config) (*application, error) {
+	if cfg.StaticDir != "" {
+		static.Use(static.NewDir(cfg.StaticDir))
+	}
+
+	sessionManager := scs.New()
+	sessionManager.Lifetime = cfg.SessionLifetime
+	sessionManager.Cookie.Secure = cfg.SecureCookies()
 
-	if err := db.RunMigrations(database.DB); err != nil {
-		log.Fatal("Failed to run migrations:", err)
+	themeClient := animethemes.NewClient(cfg.AnimeThemesURL, nil)
+	linkChecker := linkcheck.NewChecker(nil)
+
+	if cfg.Features.Demo {
+		repos := repositories{
+			tournaments: store.NewMemoryTournamentStore(),
+			users:       store.NewMemoryUserStore(),
+			uploads:     store.NewMemoryUploadStore(),
+		}
+		// Sessions stay in scs's default memory store and uploads are switched off
+		app := newApplication(cfg, nil, repos, sessionManager, nil, linkChecker, themeClient)
+
+		if err := demo.Seed(context.Background(), app.users, app.tournaments, app.matches); err != nil {
+			return nil, fmt.Errorf("failed to seed demo data: %w", err)
+		}
+		log.Println("Running in demo mode, nothing will be saved")
+		return app, nil
 	}
 
-	middleware.InitAuth()
+	if cfg.Features.Local {
+		if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create data folder: %w", err)
+		}
+	}
 
-	sessionManager := scs.New()
-	sessionManager.Lifetime = 24 * time.Hour
-	sessionManager.Store = sqlite3store.New(database.DB)
+	database, err := db.InitDB(cfg.Database.Driver, cfg.Database.DSN())
+	if err != nil {
+		return nil, err
+	}
+	if err := db.RunMigrations(database); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	sessionManager.Store = db.NewSessionStore(database)
 
-	router := newRouter(sessionManager)
+	var mediaLibrary *media.Library
+	if cfg.Features.Uploads {
+		mediaLibrary, err = media.NewLibrary(cfg.Uploads.Dir, cfg.Uploads.MaxBytes())
+		if err != nil {
+			database.Close()
+			return nil, fmt.Errorf("failed to set up media library: %w", err)
+		}
+	}
 
-	log.Println("Server starting on http://localhost:8080")
-	if err := http.ListenAndServe(":8080", router); err != nil {
-		log.Fatal(err)
+	// Lookups are cached for a week, theme metadata basically never changes
+	themeResolver := animethemes.NewCachedResolver(themeClient, store.NewLookupCacheStore(database), 7*24*time.Hour)
+
+	app := newApplication(cfg, database, sqlRepositories(database), sessionManager, mediaLibrary, linkChecker, themeResolver)
+
+	if cfg.Features.Local {
+		user, err := app.users.EnsureLocalUser(context.Background(), localUserName())
+		if err != nil {
+			database.Close()
+			return nil, fmt.Errorf("failed to create the local user: %w", err)
+		}
+		app.localUserID = user.ID
+	}
+	return app, nil
+}
+
+func sqlRepositories(database *sqlx.DB) repositories {
+	return repositories{
+		tournaments: store.InstrumentTournaments(store.NewTournamentStore(database)),
+		users:       store.InstrumentUsers(store.NewUserStore(database)),
+		uploads:     store.InstrumentUploads(store.NewUploadStore(database)),
 	}
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/httputil"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/AdamBeresnev/op-rating-app/views"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type matchHandler struct {
+	matches *service.MatchService
+}
+
+func (h *matchHandler) show(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	data, err := h.matches.GetMatchViewData(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	views.MatchView(data.Tournament, data.Match, data.Entry1, data.Entry2, data.NextMatchID).Render(r.Context(), w)
+}
+
+func (h *matchHandler) advance(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	matchID, err := uuid.Parse(idStr)
+	if err != nil {
+		httputil.BadRequest(w, r, "Invalid match ID", err)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		httputil.BadRequest(w, r, "Invalid form data", err)
+		return
+	}
+	winnerIDStr := r.Form.Get("winner_id")
+	winnerID, err := uuid.Parse(winnerIDStr)
+	if err != nil {
+		httputil.BadRequest(w, r, "Invalid winner ID", err)
+		return
+	}
+	tournamentID, err := h.matches.AdvanceWinner(r.Context(), matchID, winnerID)
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+
+	data, err := h.matches.GetMatchViewData(r.Context(), matchID.String())
+	if err != nil {
+		httputil.InternalServerError(w, r, "Failed to get next match info", err)
+		return
+	}
+	winnerSlot := 0
+	if data.Match.WinnerSlot != nil {
+		winnerSlot = *data.Match.WinnerSlot
+	}
+	views.MatchVotingResult(data.NextMatchID, tournamentID, winnerSlot).Render(r.Context(), w)
+}
+
+// Owner override, decides the match as a forfeit regardless of the order
+func (h *matchHandler) force(w http.ResponseWriter, r *http.Request) {
+	matchID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.BadRequest(w, r, "Invalid match ID", err)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		httputil.BadRequest(w, r, "Invalid form data", err)
+		return
+	}
+	winnerID, err := uuid.Parse(r.Form.Get("winner_id"))
+	if err != nil {
+		httputil.BadRequest(w, r, "Invalid winner ID", err)
+		return
+	}
+	tournamentID, err := h.matches.ForceResult(r.Context(), matchID, winnerID)
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/tournaments/%s", tournamentID))
+	w.WriteHeader(http.StatusOK)
+}
+
+// scheduled_at comes in as RFC 3339, the form converts the browser's local time before sending. Empty clears the schedule
+func (h *matchHandler) schedule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := r.ParseForm(); err != nil {
+		httputil.BadRequest(w, r, "Invalid form data", err)
+		return
+	}
+	schedule := service.MatchSchedule{StreamURL: r.Form.Get("stream_url")}
+	if at := r.Form.Get("scheduled_at"); at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			httputil.BadRequest(w, r, "Invalid date and time", err)
+			return
+		}
+		schedule.At = &t
+	}
+	if _, err := h.matches.ScheduleMatch(r.Context(), id, schedule); err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/matches/%s", id))
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/httputil"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/AdamBeresnev/op-rating-app/views"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type tournamentHandler struct {
+	tournaments *service.TournamentService
+	uploads     *service.UploadService
+	linkChecks  *service.LinkCheckService
+	// For the absolute links in the calendar feed
+	baseURL string
+}
+
+func (h *tournamentHandler) index(w http.ResponseWriter, r *http.Request) {
+	tournaments, err := h.tournaments.GetTournamentsForUser(r.Context())
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	upcoming, err := h.tournaments.GetUpcomingMatchesForUser(r.Context())
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	views.Index(tournaments, upcoming).Render(r.Context(), w)
+}
+
+func (h *tournamentHandler) archived(w http.ResponseWriter, r *http.Request) {
+	tournaments, err := h.tournaments.GetArchivedTournamentsForUser(r.Context())
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	views.ArchivedTournaments(tournaments).Render(r.Context(), w)
+}
+
+func (h *tournamentHandler) createPage(w http.ResponseWriter, r *http.Request) {
+	views.CreateTournamentPage().Render(r.Context(), w)
+}
+
+// Renders one more empty entry row on the create form
+func (h *tournamentHandler) newEntry(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httputil.BadRequest(w, r, "Invalid form data", err)
+		return
+	}
+	keys := make([]int, 0, len(r.Form))
+	for key := range r.Form {
+		if strings.HasPrefix(key, "entry_name_") {
+			indexStr := strings.TrimPrefix(key, "entry_name_")
+			index, err := strconv.Atoi(indexStr)
+			if err == nil {
+				keys = append(keys, index)
+			}
+		}
+	}
+	newIndex := 0
+	if len(keys) > 0 {
+		maxKey := keys[0]
+		for _, key := range keys {
+			if key > maxKey {
+				maxKey = key
+			}
+		}
+		newIndex = maxKey + 1
+	}
+
+	views.Entry(newIndex).Render(r.Context(), w)
+}
+
+func (h *tournamentHandler) create(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httputil.BadRequest(w, r, "Invalid form data", err)
+		return
+	}
+	name := r.Form.Get("name")
+	typeStr := r.Form.Get("type")
+	// Default to single elim
+	tournamentType := bracket.SingleElimination
+	if typeStr == "double" {
+		tournamentType = bracket.DoubleElimination
+	}
+	stage, err := parseGroupStage(r.Form)
+	if err != nil {
+		httputil.BadRequest(w, r, "Invalid group stage", err)
+		return
+	}
+
+	var entryIndices []int
+	for key := range r.Form {
+		if strings.HasPrefix(key, "entry_name_") {
+			indexStr := strings.TrimPrefix(key, "entry_name_")
+			if index, err := strconv.Atoi(indexStr); err == nil {
+				entryIndices = append(entryIndices, index)
+			}
+		}
+	}
+	sort.Ints(entryIndices)
+
+	var entries []service.EntryInput
+	for _, index := range entryIndices {
+		indexStr := strconv.Itoa(index)
+		entryName := r.Form.Get("entry_name_" + indexStr)
+		if len(entryName) > 50 {
+			httputil.BadRequest(w, r, fmt.Sprintf("Entry name '%s' exceeds 50 characters", entryName), nil)
+			return
+		}
+		if entryName != "" {
+			embedLink := r.Form.Get("entry_embed_link_" + indexStr)
+			startSeconds, err := parseOptionalTimestamp(r.Form.Get("entry_start_" + indexStr))
+			if err != nil {
+				httputil.BadRequest(w, r, fmt.Sprintf("Invalid start time for entry '%s'", entryName), err)
+				return
+			}
+			endSeconds, err := parseOptionalTimestamp(r.Form.Get("entry_end_" + indexStr))
+			if err != nil {
+				httputil.BadRequest(w, r, fmt.Sprintf("Invalid end time for entry '%s'", entryName), err)
+				return
+			}
+			if startSeconds != nil && endSeconds != nil && *endSeconds <= *startSeconds {
+				httputil.BadRequest(w, r, fmt.Sprintf("End time for entry '%s' must be after the start time", entryName), nil)
+				return
+			}
+			var uploadID *uuid.UUID
+			if uploadIDStr := r.Form.Get("entry_upload_id_" + indexStr); uploadIDStr != "" {
+				upload, err := h.uploads.GetUploadForUser(r.Context(), uploadIDStr)
+				if err != nil {
+					httputil.BadRequest(w, r, fmt.Sprintf("Invalid upload for entry '%s'", entryName), err)
+					return
+				}
+				uploadID = &upload.ID
+			}
+			metadata, err := parseEntryMetadata(r.Form, "_"+indexStr)
+			if err != nil {
+				httputil.BadRequest(w, r, fmt.Sprintf("Invalid details for entry '%s': %s", entryName, err), err)
+				return
+			}
+			entries = append(entries, service.EntryInput{
+				Name:         entryName,
+				EmbedLink:    embedLink,
+				UploadID:     uploadID,
+				StartSeconds: startSeconds,
+				EndSeconds:   endSeconds,
+				Metadata:     metadata,
+			})
+		}
+	}
+
+	var id uuid.UUID
+	if stage.Groups > 0 {
+		id, err = h.tournaments.CreateGroupTournament(r.Context(), name, tournamentType, stage, entries)
+	} else {
+		id, err = h.tournaments.CreateTournament(r.Context(), name, tournamentType, entries)
+	}
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	} else {
+		w.Header().Set("HX-Redirect", fmt.Sprintf("/tournaments/%s", id))
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h *tournamentHandler) show(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	data, err := h.tournaments.GetTournamentData(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+
+	views.TournamentView(data.Tournament, data.Entries, data.Matches, data.NextMatchID, data.Upcoming, data.Groups).Render(r.Context(), w)
+}
+
+func (h *tournamentHandler) calendar(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	calendar, err := h.tournaments.GetCalendar(r.Context(), id, h.baseURL)
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="tournament-%s.ics"`, id))
+	calendar.WriteTo(w)
+}
+
+func (h *tournamentHandler) results(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	filter := service.ResultsFilter{
+		Artist: r.URL.Query().Get("artist"),
+		Season: r.URL.Query().Get("season"),
+	}
+	data, err := h.tournaments.GetResults(r.Context(), id, filter)
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	views.TournamentResults(data).Render(r.Context(), w)
+}
+
+func (h *tournamentHandler) history(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	data, err := h.tournaments.GetHistory(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	views.TournamentHistory(data).Render(r.Context(), w)
+}
+
+// ?step=n shows the bracket after the nth pick, the page starts at the seeding
+func (h *tournamentHandler) replay(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	step := 0
+	if stepStr := r.URL.Query().Get("step"); stepStr != "" {
+		var err error
+		if step, err = strconv.Atoi(stepStr); err != nil {
+			httputil.BadRequest(w, r, "Invalid step", err)
+			return
+		}
+	}
+	data, err := h.tournaments.GetReplay(r.Context(), id, step)
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	views.TournamentReplay(data).Render(r.Context(), w)
+}
+
+func (h *tournamentHandler) settingsPage(w http.ResponseWriter, r *http.Request) {
+	data, err := h.tournaments.GetSettings(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	views.TournamentSettingsPage(data).Render(r.Context(), w)
+}
+
+func (h *tournamentHandler) updateSettings(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := r.ParseForm(); err != nil {
+		httputil.BadRequest(w, r, "Invalid form data", err)
+		return
+	}
+	scoreRequirement := 0
+	if scoreStr := strings.TrimSpace(r.Form.Get("score_requirement")); scoreStr != "" {
+		var err error
+		if scoreRequirement, err = strconv.Atoi(scoreStr); err != nil {
+			httputil.BadRequest(w, r, "Invalid score requirement", err)
+			return
+		}
+	}
+
+	stage, err := parseGroupStage(r.Form)
+	if err != nil {
+		httputil.BadRequest(w, r, "Invalid group stage", err)
+		return
+	}
+
+	settings := service.TournamentSettings{
+		Name:             r.Form.Get("name"),
+		Description:      r.Form.Get("description"),
+		CoverImageURL:    r.Form.Get("cover_image_url"),
+		Visibility:       bracket.Visibility(r.Form.Get("visibility")),
+		ScoreRequirement: scoreRequirement,
+		Type:             bracket.TournamentType(r.Form.Get("type")),
+		BronzeMatch:      r.Form.Get("bronze_match") == "on",
+		Groups:           stage,
+		Ordering:         bracket.Ordering(r.Form.Get("ordering")),
+	}
+	if err := h.tournaments.UpdateSettings(r.Context(), id, settings); err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/tournaments/%s", id))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *tournamentHandler) queue(w http.ResponseWriter, r *http.Request) {
+	data, err := h.tournaments.GetMatchQueue(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	views.MatchQueue(data).Render(r.Context(), w)
+}
+
+func (h *tournamentHandler) clone(w http.ResponseWriter, r *http.Request) {
+	id, err := h.tournaments.CloneTournament(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/tournaments/%s", id))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *tournamentHandler) start(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.tournaments.StartTournament(r.Context(), id); err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/tournaments/%s", id))
+	w.WriteHeader(http.StatusOK)
+}
+
+// Archiving goes back to the home page it just disappeared from, restoring stays on the tournament
+func (h *tournamentHandler) setArchived(archived bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+
+		if err := h.tournaments.SetTournamentArchived(r.Context(), id, archived); err != nil {
+			httputil.Error(w, r, err)
+			return
+		}
+		redirect := fmt.Sprintf("/tournaments/%s", id)
+		if archived {
+			redirect = "/"
+		}
+		w.Header().Set("HX-Redirect", redirect)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h *tournamentHandler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.tournaments.DeleteTournament(r.Context(), chi.URLParam(r, "id")); err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	w.Header().Set("HX-Redirect", "/")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *tournamentHandler) checkLinks(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.linkChecks.CheckTournament(r.Context(), id); err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	w.Header().Set("HX-Redirect", fmt.Sprintf("/tournaments/%s", id))
+	w.WriteHeader(http.StatusOK)
+}
+
+// Blank fields count as 0, no groups means a plain bracket
+func parseGroupStage(form url.Values) (service.GroupStage, error) {
+	var stage service.GroupStage
+	var err error
+	if value := strings.TrimSpace(form.Get("group_count")); value != "" {
+		if stage.Groups, err = strconv.Atoi(value); err != nil {
+			return stage, err
+		}
+	}
+	if value := strings.TrimSpace(form.Get("group_advance")); value != "" {
+		if stage.Advance, err = strconv.Atoi(value); err != nil {
+			return stage, err
+		}
+	}
+	return stage, nil
+}
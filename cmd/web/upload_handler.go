@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/httputil"
+	"github.com/AdamBeresnev/op-rating-app/internal/media"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/AdamBeresnev/op-rating-app/views"
+	"github.com/go-chi/chi/v5"
+)
+
+type uploadHandler struct {
+	uploads *service.UploadService
+	library *media.Library
+}
+
+func (h *uploadHandler) upload(w http.ResponseWriter, r *http.Request) {
+	if h.library == nil {
+		httputil.BadRequest(w, r, "Uploads are disabled", nil)
+		return
+	}
+
+	// Leave a bit of headroom for the multipart boundaries and the other fields
+	r.Body = http.MaxBytesReader(w, r.Body, h.library.MaxSize()+1<<20)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			httputil.BadRequest(w, r, media.ErrTooLarge.Error(), err)
+			return
+		}
+		httputil.BadRequest(w, r, "Invalid form data", err)
+		return
+	}
+	index, err := strconv.Atoi(r.Form.Get("index"))
+	if err != nil {
+		httputil.BadRequest(w, r, "Invalid entry index", err)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		httputil.BadRequest(w, r, "Missing file", err)
+		return
+	}
+	defer file.Close()
+
+	upload, err := h.uploads.Upload(r.Context(), header.Filename, file)
+	if err != nil {
+		if errors.Is(err, media.ErrUnsupportedType) || errors.Is(err, media.ErrTooLarge) {
+			httputil.BadRequest(w, r, err.Error(), err)
+			return
+		}
+		httputil.InternalServerError(w, r, "Failed to save upload", err)
+		return
+	}
+
+	views.UploadedFile(index, upload).Render(r.Context(), w)
+}
+
+func (h *uploadHandler) serve(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	upload, err := h.uploads.GetUpload(r.Context(), id)
+	if err != nil {
+		httputil.Error(w, r, err)
+		return
+	}
+	if h.library == nil {
+		httputil.NotFound(w, r, "Media file missing", nil)
+		return
+	}
+	if err := h.library.Serve(w, r, upload); err != nil {
+		httputil.NotFound(w, r, "Media file missing", err)
+		return
+	}
+}
@@ -0,0 +1,53 @@
+package animethemes
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Cache is implemented by store.LookupCacheStore, kept as an interface so tests don't need a database
+type Cache interface {
+	Get(ctx context.Context, key string) (payload []byte, fetchedAt time.Time, ok bool, err error)
+	Put(ctx context.Context, key string, payload []byte) error
+}
+
+// CachedResolver only asks the API about links it hasn't seen within ttl
+type CachedResolver struct {
+	next  Resolver
+	cache Cache
+	ttl   time.Duration
+}
+
+func NewCachedResolver(next Resolver, cache Cache, ttl time.Duration) *CachedResolver {
+	return &CachedResolver{next: next, cache: cache, ttl: ttl}
+}
+
+func (r *CachedResolver) Resolve(ctx context.Context, themeURL string) (*ThemeInfo, error) {
+	key := "animethemes:" + strings.TrimSpace(themeURL)
+
+	payload, fetchedAt, ok, err := r.cache.Get(ctx, key)
+	if err != nil {
+		// A broken cache shouldn't break lookups
+		slog.Warn("animethemes cache read failed", "error", err)
+	} else if ok && time.Since(fetchedAt) < r.ttl {
+		var info ThemeInfo
+		if err := json.Unmarshal(payload, &info); err == nil {
+			return &info, nil
+		}
+	}
+
+	info, err := r.next.Resolve(ctx, themeURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload, err := json.Marshal(info); err == nil {
+		if err := r.cache.Put(ctx, key, payload); err != nil {
+			slog.Warn("animethemes cache write failed", "error", err)
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,286 @@
+package animethemes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+)
+
+var (
+	ErrNotAnimeThemesURL = errors.New("not an animethemes.moe link")
+	ErrThemeNotFound     = errors.New("theme not found on animethemes.moe")
+)
+
+const DefaultBaseURL = "https://api.animethemes.moe"
+
+// Everything we can fill into an entry from a theme link
+type ThemeInfo struct {
+	Name     string                `json:"name"`
+	VideoURL string                `json:"video_url"`
+	Metadata bracket.EntryMetadata `json:"metadata"`
+}
+
+// Resolver turns a theme link into entry details. Client talks to the API, CachedResolver sits in front of it
+type Resolver interface {
+	Resolve(ctx context.Context, themeURL string) (*ThemeInfo, error)
+}
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type Client struct {
+	baseURL string
+	client  HTTPClient
+}
+
+func NewClient(baseURL string, client HTTPClient) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), client: client}
+}
+
+// Links we understand:
+// https://animethemes.moe/anime/bocchi_the_rock/OP1 (optionally with a video tag, OP1-NCBD1080)
+// https://v.animethemes.moe/BocchiTheRock-OP1.webm
+type themeRef struct {
+	animeSlug string
+	themeSlug string
+	videoTag  string
+	basename  string
+}
+
+func parseThemeURL(themeURL string) (*themeRef, error) {
+	u, err := url.Parse(strings.TrimSpace(themeURL))
+	if err != nil {
+		return nil, ErrNotAnimeThemesURL
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+
+	switch host {
+	case "animethemes.moe":
+		if len(parts) != 3 || parts[0] != "anime" || parts[1] == "" || parts[2] == "" {
+			return nil, ErrNotAnimeThemesURL
+		}
+		themeSlug, videoTag, _ := strings.Cut(parts[2], "-")
+		return &themeRef{animeSlug: parts[1], themeSlug: themeSlug, videoTag: videoTag}, nil
+	case "v.animethemes.moe":
+		if len(parts) != 1 || !strings.HasSuffix(parts[0], ".webm") {
+			return nil, ErrNotAnimeThemesURL
+		}
+		return &themeRef{basename: parts[0]}, nil
+	}
+	return nil, ErrNotAnimeThemesURL
+}
+
+func IsAnimeThemesURL(link string) bool {
+	_, err := parseThemeURL(link)
+	return err == nil
+}
+
+func (c *Client) Resolve(ctx context.Context, themeURL string) (*ThemeInfo, error) {
+	ref, err := parseThemeURL(themeURL)
+	if err != nil {
+		return nil, err
+	}
+	if ref.basename != "" {
+		return c.resolveVideo(ctx, ref.basename)
+	}
+	return c.resolveAnimeTheme(ctx, ref)
+}
+
+func (c *Client) resolveAnimeTheme(ctx context.Context, ref *themeRef) (*ThemeInfo, error) {
+	var resp struct {
+		Anime apiAnime `json:"anime"`
+	}
+	query := url.Values{"include": {"animethemes.animethemeentries.videos,animethemes.song.artists,images"}}
+	if err := c.get(ctx, "/anime/"+url.PathEscape(ref.animeSlug), query, &resp); err != nil {
+		return nil, err
+	}
+
+	for _, theme := range resp.Anime.AnimeThemes {
+		if !strings.EqualFold(theme.Slug, ref.themeSlug) {
+			continue
+		}
+		info := buildThemeInfo(&resp.Anime, &theme)
+		info.VideoURL = pickVideo(&theme, ref.videoTag)
+		return info, nil
+	}
+	return nil, ErrThemeNotFound
+}
+
+func (c *Client) resolveVideo(ctx context.Context, basename string) (*ThemeInfo, error) {
+	var resp struct {
+		Video apiVideo `json:"video"`
+	}
+	query := url.Values{"include": {"animethemeentries.animetheme.anime.images,animethemeentries.animetheme.song.artists"}}
+	if err := c.get(ctx, "/video/"+url.PathEscape(basename), query, &resp); err != nil {
+		return nil, err
+	}
+
+	for _, entry := range resp.Video.AnimeThemeEntries {
+		if entry.AnimeTheme == nil || entry.AnimeTheme.Anime == nil {
+			continue
+		}
+		info := buildThemeInfo(entry.AnimeTheme.Anime, entry.AnimeTheme)
+		info.VideoURL = resp.Video.Link
+		return info, nil
+	}
+	return nil, ErrThemeNotFound
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("animethemes request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrThemeNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("animethemes returned status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Entry names are capped at 50 characters on the create form
+const maxNameLength = 50
+
+func buildThemeInfo(anime *apiAnime, theme *apiTheme) *ThemeInfo {
+	var m bracket.EntryMetadata
+	if anime.Name != "" {
+		m.SeriesTitle = &anime.Name
+	}
+
+	switch strings.ToUpper(theme.Type) {
+	case "OP":
+		t := bracket.ThemeOpening
+		m.ThemeType = &t
+	case "ED":
+		t := bracket.ThemeEnding
+		m.ThemeType = &t
+	case "IN":
+		t := bracket.ThemeInsert
+		m.ThemeType = &t
+	}
+	m.ThemeSequence = theme.Sequence
+
+	if theme.Song != nil {
+		if theme.Song.Title != "" {
+			title := theme.Song.Title
+			m.SongTitle = &title
+		}
+		var artists []string
+		for _, a := range theme.Song.Artists {
+			artists = append(artists, a.Name)
+		}
+		if len(artists) > 0 {
+			artist := strings.Join(artists, ", ")
+			m.Artist = &artist
+		}
+	}
+
+	if anime.Year > 0 {
+		year := anime.Year
+		m.Year = &year
+	}
+	season := bracket.Season(strings.ToLower(anime.Season))
+	for _, s := range bracket.Seasons {
+		if s == season {
+			m.Season = &season
+		}
+	}
+
+	for _, img := range anime.Images {
+		if img.Facet == "Large Cover" || m.ThumbnailURL == nil {
+			link := img.Link
+			m.ThumbnailURL = &link
+		}
+	}
+
+	name := strings.TrimSpace(anime.Name + " " + m.ThemeLabel())
+	if runes := []rune(name); len(runes) > maxNameLength {
+		name = string(runes[:maxNameLength])
+	}
+
+	return &ThemeInfo{Name: name, Metadata: m}
+}
+
+// Prefers the video matching the tag from the link (e.g. NCBD1080), otherwise whatever comes first
+func pickVideo(theme *apiTheme, tag string) string {
+	first := ""
+	for _, entry := range theme.AnimeThemeEntries {
+		for _, v := range entry.Videos {
+			if first == "" {
+				first = v.Link
+			}
+			if tag != "" && strings.HasSuffix(strings.TrimSuffix(v.Basename, ".webm"), "-"+tag) {
+				return v.Link
+			}
+		}
+	}
+	return first
+}
+
+type apiAnime struct {
+	Name        string     `json:"name"`
+	Slug        string     `json:"slug"`
+	Year        int        `json:"year"`
+	Season      string     `json:"season"`
+	Images      []apiImage `json:"images"`
+	AnimeThemes []apiTheme `json:"animethemes"`
+}
+
+type apiImage struct {
+	Facet string `json:"facet"`
+	Link  string `json:"link"`
+}
+
+type apiTheme struct {
+	Type              string          `json:"type"`
+	Sequence          *int            `json:"sequence"`
+	Slug              string          `json:"slug"`
+	Song              *apiSong        `json:"song"`
+	Anime             *apiAnime       `json:"anime"`
+	AnimeThemeEntries []apiThemeEntry `json:"animethemeentries"`
+}
+
+type apiSong struct {
+	Title   string      `json:"title"`
+	Artists []apiArtist `json:"artists"`
+}
+
+type apiArtist struct {
+	Name string `json:"name"`
+}
+
+type apiThemeEntry struct {
+	Videos     []apiVideo `json:"videos"`
+	AnimeTheme *apiTheme  `json:"animetheme"`
+}
+
+type apiVideo struct {
+	Basename          string          `json:"basename"`
+	Link              string          `json:"link"`
+	AnimeThemeEntries []apiThemeEntry `json:"animethemeentries"`
+}
@@ -0,0 +1,147 @@
+package animethemes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Serves the fixture JSON in testdata the same way the real API would
+func newFixtureServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	fixtures := map[string]string{
+		"/anime/bocchi_the_rock":        "testdata/anime_bocchi_the_rock.json",
+		"/video/BocchiTheRock-ED3.webm": "testdata/video_bocchi_the_rock_ed3.json",
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests != nil {
+			requests.Add(1)
+		}
+		assert.NotEmpty(t, r.URL.Query().Get("include"))
+
+		path, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+}
+
+func TestResolve_AnimeThemePage(t *testing.T) {
+	server := newFixtureServer(t, nil)
+	defer server.Close()
+
+	client := NewClient(server.URL, server.Client())
+
+	info, err := client.Resolve(context.Background(), "https://animethemes.moe/anime/bocchi_the_rock/OP1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Bocchi the Rock! OP", info.Name)
+	assert.Equal(t, "https://v.animethemes.moe/BocchiTheRock-OP1.webm", info.VideoURL)
+	assert.Equal(t, "Bocchi the Rock!", *info.Metadata.SeriesTitle)
+	assert.Equal(t, bracket.ThemeOpening, *info.Metadata.ThemeType)
+	assert.Equal(t, "Seishun Complex", *info.Metadata.SongTitle)
+	assert.Equal(t, "Kessoku Band", *info.Metadata.Artist)
+	assert.Equal(t, bracket.SeasonFall, *info.Metadata.Season)
+	assert.Equal(t, 2022, *info.Metadata.Year)
+	assert.Equal(t, "https://i.animethemes.moe/anime/bocchi_large.jpg", *info.Metadata.ThumbnailURL)
+}
+
+func TestResolve_VideoTagAndSequence(t *testing.T) {
+	server := newFixtureServer(t, nil)
+	defer server.Close()
+
+	client := NewClient(server.URL, server.Client())
+
+	info, err := client.Resolve(context.Background(), "https://animethemes.moe/anime/bocchi_the_rock/OP1-NCBD1080")
+	require.NoError(t, err)
+	assert.Equal(t, "https://v.animethemes.moe/BocchiTheRock-OP1-NCBD1080.webm", info.VideoURL)
+
+	info, err = client.Resolve(context.Background(), "https://animethemes.moe/anime/bocchi_the_rock/ED3")
+	require.NoError(t, err)
+	assert.Equal(t, "ED3", info.Metadata.ThemeLabel())
+	assert.Equal(t, "Kessoku Band, Ikumi Hasegawa", *info.Metadata.Artist)
+}
+
+func TestResolve_DirectVideoLink(t *testing.T) {
+	server := newFixtureServer(t, nil)
+	defer server.Close()
+
+	client := NewClient(server.URL, server.Client())
+
+	info, err := client.Resolve(context.Background(), "https://v.animethemes.moe/BocchiTheRock-ED3.webm")
+	require.NoError(t, err)
+	assert.Equal(t, "Bocchi the Rock! ED3", info.Name)
+	assert.Equal(t, "https://v.animethemes.moe/BocchiTheRock-ED3.webm", info.VideoURL)
+	assert.Equal(t, "Korogaru Iwa, Kimi ni Asa ga Furu", *info.Metadata.SongTitle)
+}
+
+func TestResolve_Errors(t *testing.T) {
+	server := newFixtureServer(t, nil)
+	defer server.Close()
+
+	client := NewClient(server.URL, server.Client())
+
+	_, err := client.Resolve(context.Background(), "https://www.youtube.com/watch?v=abc")
+	assert.ErrorIs(t, err, ErrNotAnimeThemesURL)
+
+	_, err = client.Resolve(context.Background(), "https://animethemes.moe/anime/bocchi_the_rock/OP9")
+	assert.ErrorIs(t, err, ErrThemeNotFound)
+
+	_, err = client.Resolve(context.Background(), "https://animethemes.moe/anime/made_up_show/OP1")
+	assert.ErrorIs(t, err, ErrThemeNotFound)
+}
+
+type memoryCache struct {
+	payloads  map[string][]byte
+	fetchedAt map[string]time.Time
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string) ([]byte, time.Time, bool, error) {
+	payload, ok := c.payloads[key]
+	return payload, c.fetchedAt[key], ok, nil
+}
+
+func (c *memoryCache) Put(ctx context.Context, key string, payload []byte) error {
+	c.payloads[key] = payload
+	c.fetchedAt[key] = time.Now()
+	return nil
+}
+
+func TestCachedResolver(t *testing.T) {
+	var requests atomic.Int32
+	server := newFixtureServer(t, &requests)
+	defer server.Close()
+
+	cache := &memoryCache{payloads: map[string][]byte{}, fetchedAt: map[string]time.Time{}}
+	resolver := NewCachedResolver(NewClient(server.URL, server.Client()), cache, time.Hour)
+
+	link := "https://animethemes.moe/anime/bocchi_the_rock/OP1"
+	first, err := resolver.Resolve(context.Background(), link)
+	require.NoError(t, err)
+	second, err := resolver.Resolve(context.Background(), link)
+	require.NoError(t, err)
+
+	assert.Equal(t, int32(1), requests.Load())
+	assert.Equal(t, first, second)
+
+	// Expired entries get fetched again
+	cache.fetchedAt["animethemes:"+link] = time.Now().Add(-2 * time.Hour)
+	_, err = resolver.Resolve(context.Background(), link)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), requests.Load())
+}
@@ -0,0 +1,86 @@
+package bracket
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditAction string
+
+const (
+	AuditTournamentCreated AuditAction = "tournament_created"
+	AuditEntryUpdated      AuditAction = "entry_updated"
+	AuditMatchDecided      AuditAction = "match_decided"
+	AuditTournamentDeleted AuditAction = "tournament_deleted"
+	AuditTournamentStarted AuditAction = "tournament_started"
+	// Archiving and taking it back out of the archive
+	AuditTournamentArchived   AuditAction = "tournament_archived"
+	AuditTournamentUnarchived AuditAction = "tournament_unarchived"
+	// Settings page saves, Matches are only set when the format change rebuilt the bracket
+	AuditTournamentUpdated AuditAction = "tournament_updated"
+	// Owner overrides, both end in forfeits
+	AuditMatchForced       AuditAction = "match_forced"
+	AuditEntryDisqualified AuditAction = "entry_disqualified"
+	// Setting, moving or clearing the time and stream link of a match
+	AuditMatchScheduled AuditAction = "match_scheduled"
+	// The playoff bracket being generated once the last group match is decided
+	AuditPlayoffStarted AuditAction = "playoff_started"
+)
+
+// One change to a tournament and who made it. Rows are only ever added, they outlive the tournament itself
+type AuditEvent struct {
+	ID           uuid.UUID  `db:"id"`
+	TournamentID uuid.UUID  `db:"tournament_id"`
+	MatchID      *uuid.UUID `db:"match_id"`
+	// Nil for changes made from the command line
+	ActorID *uuid.UUID `db:"actor_id"`
+	// Name at the time, so the history still reads right after a rename or the user being deleted
+	ActorName string        `db:"actor_name"`
+	Action    AuditAction   `db:"action"`
+	Before    AuditSnapshot `db:"before_state"`
+	After     AuditSnapshot `db:"after_state"`
+	CreatedAt time.Time     `db:"created_at"`
+}
+
+// The rows a change touched, stored as JSON. Which fields are set depends on the action
+type AuditSnapshot struct {
+	Matches    []Match     `json:"matches,omitempty"`
+	Entry      *Entry      `json:"entry,omitempty"`
+	Tournament *Tournament `json:"tournament,omitempty"`
+}
+
+func (s AuditSnapshot) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (s *AuditSnapshot) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*s = AuditSnapshot{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case []byte:
+		return json.Unmarshal(v, s)
+	default:
+		return fmt.Errorf("cannot scan %T into AuditSnapshot", src)
+	}
+}
+
+// The match as it was after the change, if the snapshot has it
+func (s *AuditSnapshot) Match(id uuid.UUID) *Match {
+	for i := range s.Matches {
+		if s.Matches[i].ID == id {
+			return &s.Matches[i]
+		}
+	}
+	return nil
+}
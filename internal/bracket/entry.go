@@ -1,6 +1,19 @@
 package bracket
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LinkStatus string
+
+const (
+	LinkOK     LinkStatus = "ok"
+	LinkBroken LinkStatus = "broken"
+	// Couldn't tell either way, e.g. timeouts or 5xx from the host
+	LinkUnreachable LinkStatus = "unreachable"
+)
 
 type Entry struct {
 	ID           uuid.UUID `db:"id"`
@@ -8,4 +21,27 @@ type Entry struct {
 	Name         string    `db:"name"`
 	Seed         int       `db:"seed"`
 	EmbedLink    *string   `db:"embed_link"`
+	// Locally uploaded video, used instead of EmbedLink when set
+	UploadID *uuid.UUID `db:"upload_id"`
+
+	// Optional clip range in seconds, so we can play just the OP out of a full episode
+	StartSeconds *int `db:"start_seconds"`
+	EndSeconds   *int `db:"end_seconds"`
+
+	EntryMetadata
+
+	// Result of the last dead link check, nil if it was never checked
+	LinkStatus    *LinkStatus `db:"link_status"`
+	LinkCheckedAt *time.Time  `db:"link_checked_at"`
+
+	// Removed mid-tournament, forfeits every match it's still in or gets into
+	DisqualifiedAt *time.Time `db:"disqualified_at"`
+}
+
+func (e *Entry) IsDisqualified() bool {
+	return e.DisqualifiedAt != nil
+}
+
+func (e *Entry) IsLinkBroken() bool {
+	return e.UploadID == nil && e.LinkStatus != nil && *e.LinkStatus == LinkBroken
 }
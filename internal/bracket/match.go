@@ -20,6 +20,8 @@ const (
 	WinnersSide BracketSide = "winners"
 	LosersSide  BracketSide = "losers"
 	FinalsSide  BracketSide = "finals"
+	// Round robin matches of the group stage, they don't lead anywhere on their own
+	GroupSide BracketSide = "groups"
 )
 
 type Match struct {
@@ -48,6 +50,22 @@ type Match struct {
 	IsBye      bool `db:"is_bye"`
 
 	CreatedAt time.Time `db:"created_at"`
+	// When someone picked the winner. Nil for byes settled at creation and for matches decided before this was recorded
+	DecidedAt *time.Time `db:"decided_at"`
+	// Won by walkover, either the loser was disqualified or the owner forced the result
+	Forfeit bool `db:"forfeit"`
+
+	// Set by the owner for matches played out at a fixed time, like a weekly stream. Scheduled matches have the MatchScheduled status
+	ScheduledAt *time.Time `db:"scheduled_at"`
+	StreamURL   *string    `db:"stream_url"`
+
+	// 1-based, only set on group stage matches
+	GroupNumber *int `db:"group_number"`
+}
+
+// Scheduled and still waiting for a winner
+func (m *Match) IsUpcoming() bool {
+	return m.ScheduledAt != nil && m.Status != MatchFinished
 }
 
 func (m *Match) IsWinner(slot int) bool {
@@ -0,0 +1,65 @@
+package bracket
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ThemeType string
+
+const (
+	ThemeOpening ThemeType = "OP"
+	ThemeEnding  ThemeType = "ED"
+	ThemeInsert  ThemeType = "IN"
+)
+
+type Season string
+
+const (
+	SeasonWinter Season = "winter"
+	SeasonSpring Season = "spring"
+	SeasonSummer Season = "summer"
+	SeasonFall   Season = "fall"
+)
+
+var Seasons = []Season{SeasonWinter, SeasonSpring, SeasonSummer, SeasonFall}
+
+// Everything we know about the song behind an entry. All of it is optional since people paste random links
+type EntryMetadata struct {
+	SeriesTitle   *string    `db:"series_title"`
+	ThemeType     *ThemeType `db:"theme_type"`
+	ThemeSequence *int       `db:"theme_sequence"`
+	SongTitle     *string    `db:"song_title"`
+	Artist        *string    `db:"artist"`
+	Season        *Season    `db:"season"`
+	Year          *int       `db:"year"`
+	ThumbnailURL  *string    `db:"thumbnail_url"`
+}
+
+func (m *EntryMetadata) HasAny() bool {
+	return m.SeriesTitle != nil || m.ThemeType != nil || m.SongTitle != nil || m.Artist != nil || m.Season != nil || m.Year != nil || m.ThumbnailURL != nil
+}
+
+// OP, OP2, ED3 and so on
+func (m *EntryMetadata) ThemeLabel() string {
+	if m.ThemeType == nil {
+		return ""
+	}
+	if m.ThemeSequence != nil && *m.ThemeSequence > 0 {
+		return fmt.Sprintf("%s%d", *m.ThemeType, *m.ThemeSequence)
+	}
+	return string(*m.ThemeType)
+}
+
+// Spring 2024, or just one half if that's all we have
+func (m *EntryMetadata) SeasonLabel() string {
+	var parts []string
+	if m.Season != nil {
+		s := string(*m.Season)
+		parts = append(parts, strings.ToUpper(s[:1])+s[1:])
+	}
+	if m.Year != nil {
+		parts = append(parts, fmt.Sprint(*m.Year))
+	}
+	return strings.Join(parts, " ")
+}
@@ -21,6 +21,27 @@ const (
 	DoubleElimination TournamentType = "double"
 )
 
+// Private tournaments are only visible to their owner and admins, public ones to anyone with the link
+type Visibility string
+
+const (
+	VisibilityPublic  Visibility = "public"
+	VisibilityPrivate Visibility = "private"
+)
+
+// Which matches can be voted on while earlier ones are still open
+type Ordering string
+
+const (
+	// Round by round, in match order, separately for each bracket side
+	OrderingStrict Ordering = "strict"
+	// Anything with both entries in it
+	OrderingFree Ordering = "free"
+	// Winners and losers rounds take turns the way double elimination events are run,
+	// matches of the same round can be played in any order
+	OrderingInterleaved Ordering = "interleaved"
+)
+
 type Tournament struct {
 	ID               uuid.UUID        `db:"id"`
 	OwnerID          uuid.UUID        `db:"owner_id"`
@@ -29,4 +50,27 @@ type Tournament struct {
 	Type             TournamentType   `db:"tournament_type"`
 	ScoreRequirement int              `db:"score_requirement"`
 	CreatedAt        time.Time        `db:"created_at"`
+
+	Description   *string    `db:"description"`
+	CoverImageURL *string    `db:"cover_image_url"`
+	Visibility    Visibility `db:"visibility"`
+	// Third place match between the semifinal losers, single elimination only
+	BronzeMatch bool     `db:"bronze_match"`
+	Ordering    Ordering `db:"ordering"`
+
+	// Round robin groups played before the bracket, 0 for none. Type is then the format of the playoff
+	GroupCount int `db:"group_count"`
+	// How many of each group make the playoff
+	GroupAdvance int `db:"group_advance"`
+
+	// Archived tournaments are left off the home page but still work as normal
+	ArchivedAt *time.Time `db:"archived_at"`
+}
+
+func (t *Tournament) IsArchived() bool {
+	return t.ArchivedAt != nil
+}
+
+func (t *Tournament) HasGroupStage() bool {
+	return t.GroupCount > 0
 }
@@ -0,0 +1,506 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Everything the server reads at startup. Precedence is defaults < config file < .env < real environment
+type Config struct {
+	ListenAddr        string        `toml:"listen_addr" yaml:"listen_addr"`
+	BaseURL           string        `toml:"base_url" yaml:"base_url"`
+	SessionLifetime   time.Duration `toml:"session_lifetime" yaml:"session_lifetime"`
+	LinkCheckInterval time.Duration `toml:"link_check_interval" yaml:"link_check_interval"`
+	AnimeThemesURL    string        `toml:"animethemes_url" yaml:"animethemes_url"`
+	// json for log collectors, text is easier to read in a terminal
+	LogFormat string `toml:"log_format" yaml:"log_format"`
+	// Serve static files from this folder instead of the ones built into the binary, handy with the tailwind watcher
+	StaticDir string `toml:"static_dir" yaml:"static_dir"`
+	// Where local mode keeps the database and uploads, defaults to the OS user data folder
+	DataDir string `toml:"data_dir" yaml:"data_dir"`
+
+	Server    Server    `toml:"server" yaml:"server"`
+	Database  Database  `toml:"database" yaml:"database"`
+	Uploads   Uploads   `toml:"uploads" yaml:"uploads"`
+	Limits    Limits    `toml:"limits" yaml:"limits"`
+	RateLimit RateLimit `toml:"rate_limit" yaml:"rate_limit"`
+	OAuth     OAuth     `toml:"oauth" yaml:"oauth"`
+	Features  Features  `toml:"features" yaml:"features"`
+}
+
+// HTTP timeouts. Read and write have to fit the largest upload, idle is for keep-alive connections
+type Server struct {
+	ReadTimeout  time.Duration `toml:"read_timeout" yaml:"read_timeout"`
+	WriteTimeout time.Duration `toml:"write_timeout" yaml:"write_timeout"`
+	IdleTimeout  time.Duration `toml:"idle_timeout" yaml:"idle_timeout"`
+	// How long in-flight requests get to finish after SIGTERM before the server gives up on them
+	ShutdownGracePeriod time.Duration `toml:"shutdown_grace_period" yaml:"shutdown_grace_period"`
+	// How long /readyz fails before the server stops accepting, so the proxy has time to notice
+	DrainDelay time.Duration `toml:"drain_delay" yaml:"drain_delay"`
+}
+
+type Database struct {
+	Driver string `toml:"driver" yaml:"driver"` // sqlite3 or postgres
+	Path   string `toml:"path" yaml:"path"`     // SQLite only
+	URL    string `toml:"url" yaml:"url"`       // Postgres only
+}
+
+// What gets handed to sql.Open for the chosen driver
+func (d Database) DSN() string {
+	if d.Driver == "postgres" {
+		return d.URL
+	}
+	return d.Path
+}
+
+// The SQLite file on disk, without the file: prefix and query options
+func (d Database) FilePath() string {
+	path := strings.TrimPrefix(d.Path, "file:")
+	if i := strings.IndexByte(path, '?'); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
+
+type Uploads struct {
+	Dir   string `toml:"dir" yaml:"dir"`
+	MaxMB int64  `toml:"max_mb" yaml:"max_mb"`
+}
+
+func (u Uploads) MaxBytes() int64 {
+	return u.MaxMB << 20
+}
+
+// Hard caps checked when a tournament is created, 0 means no limit
+type Limits struct {
+	MaxEntries            int64 `toml:"max_entries" yaml:"max_entries"`
+	MaxTournamentsPerUser int64 `toml:"max_tournaments_per_user" yaml:"max_tournaments_per_user"`
+}
+
+// Per route group. Logged in users get their own bucket, guests and anonymous visitors share one per IP
+type RateLimit struct {
+	Enabled bool `toml:"enabled" yaml:"enabled"`
+	// Header the proxy puts the real client IP in, Fly-Client-IP on fly.io. Empty uses the connection's address,
+	// don't set it when nothing in front strips it or anyone can pick their own IP
+	ClientIPHeader string `toml:"client_ip_header" yaml:"client_ip_header"`
+
+	Auth   Rate `toml:"auth" yaml:"auth"`     // OAuth and guest login
+	Create Rate `toml:"create" yaml:"create"` // new tournaments
+	Edit   Rate `toml:"edit" yaml:"edit"`     // uploads, theme lookups and entry edits
+	Vote   Rate `toml:"vote" yaml:"vote"`     // picking match winners
+}
+
+type OAuth struct {
+	Discord OAuthProvider `toml:"discord" yaml:"discord"`
+	Google  OAuthProvider `toml:"google" yaml:"google"`
+}
+
+type OAuthProvider struct {
+	Key         string `toml:"key" yaml:"key"`
+	Secret      string `toml:"secret" yaml:"secret"`
+	CallbackURL string `toml:"callback_url" yaml:"callback_url"`
+}
+
+// Providers without credentials are skipped instead of registered half-broken
+func (p OAuthProvider) Enabled() bool {
+	return p.Key != "" && p.Secret != ""
+}
+
+type Features struct {
+	Demo bool `toml:"demo" yaml:"demo"`
+	// Single organizer on their own machine: random local port, data in DataDir, no login
+	Local      bool `toml:"local" yaml:"local"`
+	Uploads    bool `toml:"uploads" yaml:"uploads"`
+	GuestLogin bool `toml:"guest_login" yaml:"guest_login"`
+}
+
+func Default() Config {
+	return Config{
+		ListenAddr:      ":8080",
+		SessionLifetime: 24 * time.Hour,
+		LogFormat:       "json",
+		Server: Server{
+			ReadTimeout:         5 * time.Minute,
+			WriteTimeout:        5 * time.Minute,
+			IdleTimeout:         2 * time.Minute,
+			ShutdownGracePeriod: 20 * time.Second,
+			DrainDelay:          5 * time.Second,
+		},
+		Database: Database{
+			Driver: "sqlite3",
+			Path:   "op_rating.db?_journal_mode=WAL",
+		},
+		Uploads: Uploads{
+			Dir:   "uploads",
+			MaxMB: 200,
+		},
+		Limits: Limits{
+			MaxEntries:            256,
+			MaxTournamentsPerUser: 100,
+		},
+		RateLimit: RateLimit{
+			Enabled: true,
+			Auth:    Rate{Count: 10, Per: time.Minute},
+			Create:  Rate{Count: 10, Per: time.Minute},
+			Edit:    Rate{Count: 120, Per: time.Minute},
+			Vote:    Rate{Count: 60, Per: time.Minute},
+		},
+		Features: Features{
+			Uploads:    true,
+			GuestLogin: true,
+		},
+	}
+}
+
+// Loads .env (if there is one), then the config file from path or CONFIG_FILE, then the environment on top
+func Load(path string) (Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Config{}, fmt.Errorf("failed to read .env: %w", err)
+	}
+	return load(path, os.LookupEnv)
+}
+
+func load(path string, lookupEnv func(string) (string, bool)) (Config, error) {
+	cfg := Default()
+
+	if path == "" {
+		path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := readFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	if err := applyEnv(&cfg, lookupEnv); err != nil {
+		return cfg, err
+	}
+	if cfg.Features.Local {
+		if err := cfg.UseLocalMode(); err != nil {
+			return cfg, err
+		}
+	}
+	cfg.fillDerived()
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func readFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		// Typos would otherwise be silently ignored
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("invalid config file %s: unknown key %q", path, undecoded[0].String())
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(strings.NewReader(string(data)))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config file %s must be .toml, .yaml or .yml", path)
+	}
+	return nil
+}
+
+// Collects every bad value instead of stopping at the first one
+type envReader struct {
+	lookup func(string) (string, bool)
+	errs   []error
+}
+
+func (e *envReader) string(name string, dst *string) {
+	if v, ok := e.lookup(name); ok && v != "" {
+		*dst = v
+	}
+}
+
+func (e *envReader) duration(name string, dst *time.Duration) {
+	if v, ok := e.lookup(name); ok && v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: %q is not a duration like 30m or 6h", name, v))
+			return
+		}
+		*dst = d
+	}
+}
+
+func (e *envReader) int(name string, dst *int64) {
+	if v, ok := e.lookup(name); ok && v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: %q is not a number", name, v))
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *envReader) rate(name string, dst *Rate) {
+	if v, ok := e.lookup(name); ok && v != "" {
+		r, err := ParseRate(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: %w", name, err))
+			return
+		}
+		*dst = r
+	}
+}
+
+func (e *envReader) bool(name string, dst *bool) {
+	if v, ok := e.lookup(name); ok && v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			e.errs = append(e.errs, fmt.Errorf("%s: %q is not true or false", name, v))
+			return
+		}
+		*dst = b
+	}
+}
+
+func applyEnv(cfg *Config, lookupEnv func(string) (string, bool)) error {
+	env := &envReader{lookup: lookupEnv}
+
+	// PORT is what most hosts set, LISTEN_ADDR wins if both are there
+	if port, ok := lookupEnv("PORT"); ok && port != "" {
+		cfg.ListenAddr = ":" + port
+	}
+	env.string("LISTEN_ADDR", &cfg.ListenAddr)
+	env.string("BASE_URL", &cfg.BaseURL)
+	env.duration("SESSION_LIFETIME", &cfg.SessionLifetime)
+	env.duration("LINK_CHECK_INTERVAL", &cfg.LinkCheckInterval)
+	env.string("ANIMETHEMES_API_URL", &cfg.AnimeThemesURL)
+	env.string("STATIC_DIR", &cfg.StaticDir)
+	env.string("LOG_FORMAT", &cfg.LogFormat)
+
+	env.duration("HTTP_READ_TIMEOUT", &cfg.Server.ReadTimeout)
+	env.duration("HTTP_WRITE_TIMEOUT", &cfg.Server.WriteTimeout)
+	env.duration("HTTP_IDLE_TIMEOUT", &cfg.Server.IdleTimeout)
+	env.duration("SHUTDOWN_GRACE_PERIOD", &cfg.Server.ShutdownGracePeriod)
+	env.duration("SHUTDOWN_DRAIN_DELAY", &cfg.Server.DrainDelay)
+
+	env.string("DB_DRIVER", &cfg.Database.Driver)
+	env.string("DB_PATH", &cfg.Database.Path)
+	env.string("DATABASE_URL", &cfg.Database.URL)
+
+	env.string("UPLOAD_DIR", &cfg.Uploads.Dir)
+	env.int("UPLOAD_MAX_MB", &cfg.Uploads.MaxMB)
+
+	env.int("MAX_ENTRIES", &cfg.Limits.MaxEntries)
+	env.int("MAX_TOURNAMENTS_PER_USER", &cfg.Limits.MaxTournamentsPerUser)
+
+	env.bool("RATE_LIMIT", &cfg.RateLimit.Enabled)
+	env.string("CLIENT_IP_HEADER", &cfg.RateLimit.ClientIPHeader)
+	env.rate("RATE_LIMIT_AUTH", &cfg.RateLimit.Auth)
+	env.rate("RATE_LIMIT_CREATE", &cfg.RateLimit.Create)
+	env.rate("RATE_LIMIT_EDIT", &cfg.RateLimit.Edit)
+	env.rate("RATE_LIMIT_VOTE", &cfg.RateLimit.Vote)
+
+	env.string("DISCORD_KEY", &cfg.OAuth.Discord.Key)
+	env.string("DISCORD_SECRET", &cfg.OAuth.Discord.Secret)
+	env.string("DISCORD_CALLBACK_URL", &cfg.OAuth.Discord.CallbackURL)
+	env.string("GOOGLE_KEY", &cfg.OAuth.Google.Key)
+	env.string("GOOGLE_SECRET", &cfg.OAuth.Google.Secret)
+	env.string("GOOGLE_CALLBACK_URL", &cfg.OAuth.Google.CallbackURL)
+
+	env.string("DATA_DIR", &cfg.DataDir)
+	env.bool("DEMO", &cfg.Features.Demo)
+	env.bool("LOCAL_MODE", &cfg.Features.Local)
+	env.bool("FEATURE_UPLOADS", &cfg.Features.Uploads)
+	env.bool("FEATURE_GUEST_LOGIN", &cfg.Features.GuestLogin)
+
+	return errors.Join(env.errs...)
+}
+
+// Local mode ignores the server settings: it only listens on loopback, keeps everything under DataDir
+// and doesn't need OAuth since there's nobody else to log in
+func (c *Config) UseLocalMode() error {
+	c.Features.Local = true
+	if c.DataDir == "" {
+		dir, err := userDataDir()
+		if err != nil {
+			return fmt.Errorf("can't find a folder for local data, set DATA_DIR: %w", err)
+		}
+		c.DataDir = dir
+	}
+
+	c.ListenAddr = "127.0.0.1:0"
+	c.BaseURL = ""
+	c.Database = Database{
+		Driver: "sqlite3",
+		Path:   filepath.Join(c.DataDir, "op_rating.db") + "?_journal_mode=WAL",
+	}
+	c.Uploads.Dir = filepath.Join(c.DataDir, "uploads")
+	c.OAuth = OAuth{}
+	// Nobody else can reach it
+	c.RateLimit.Enabled = false
+	// No proxy in front to tell, Ctrl+C should quit right away
+	c.Server.DrainDelay = 0
+	c.LogFormat = "text"
+	return nil
+}
+
+// The usual per-user app data folder: AppData on Windows, Application Support on macOS, XDG data home elsewhere
+func userDataDir() (string, error) {
+	const name = "op-rating-app"
+	switch runtime.GOOS {
+	case "windows", "darwin", "ios":
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dir, name), nil
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, name), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", name), nil
+}
+
+// Fills in whatever can be worked out from the rest, e.g. the callback URLs from the base URL
+func (c *Config) fillDerived() {
+	if c.BaseURL == "" {
+		host, port, err := net.SplitHostPort(c.ListenAddr)
+		if err == nil {
+			if host == "" {
+				host = "localhost"
+			}
+			c.BaseURL = "http://" + net.JoinHostPort(host, port)
+		}
+	}
+	c.BaseURL = strings.TrimRight(c.BaseURL, "/")
+
+	if c.OAuth.Discord.CallbackURL == "" {
+		c.OAuth.Discord.CallbackURL = c.BaseURL + "/auth/discord/callback"
+	}
+	if c.OAuth.Google.CallbackURL == "" {
+		c.OAuth.Google.CallbackURL = c.BaseURL + "/auth/google/callback"
+	}
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		add("listen_addr (LISTEN_ADDR): %q is not a host:port address", c.ListenAddr)
+	}
+	if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		add("base_url (BASE_URL): %q must be an absolute http(s) URL", c.BaseURL)
+	}
+	if c.SessionLifetime <= 0 {
+		add("session_lifetime (SESSION_LIFETIME) must be positive")
+	}
+	if c.LinkCheckInterval < 0 {
+		add("link_check_interval (LINK_CHECK_INTERVAL) can't be negative, use 0 to turn it off")
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 {
+		add("server timeouts (HTTP_READ_TIMEOUT, HTTP_WRITE_TIMEOUT, HTTP_IDLE_TIMEOUT) must be positive")
+	}
+	if c.Server.ShutdownGracePeriod <= 0 {
+		add("server.shutdown_grace_period (SHUTDOWN_GRACE_PERIOD) must be positive")
+	}
+	if c.Server.DrainDelay < 0 {
+		add("server.drain_delay (SHUTDOWN_DRAIN_DELAY) can't be negative, use 0 to skip it")
+	}
+	if c.Limits.MaxEntries < 0 || c.Limits.MaxTournamentsPerUser < 0 {
+		add("limits (MAX_ENTRIES, MAX_TOURNAMENTS_PER_USER) can't be negative, use 0 for no limit")
+	}
+	if c.RateLimit.Enabled {
+		groups := []struct {
+			name string
+			rate Rate
+		}{{"auth", c.RateLimit.Auth}, {"create", c.RateLimit.Create}, {"edit", c.RateLimit.Edit}, {"vote", c.RateLimit.Vote}}
+		for _, g := range groups {
+			if g.rate.Count <= 0 || g.rate.Per <= 0 {
+				add("rate_limit.%s (RATE_LIMIT_%s) must be set when rate limiting is on", g.name, strings.ToUpper(g.name))
+			}
+		}
+	}
+	if c.LogFormat != "json" && c.LogFormat != "text" {
+		add("log_format (LOG_FORMAT): %q must be json or text", c.LogFormat)
+	}
+
+	switch c.Database.Driver {
+	case "sqlite3":
+		if c.Database.Path == "" {
+			add("database.path (DB_PATH) is required for sqlite3")
+		}
+	case "postgres":
+		if c.Database.URL == "" {
+			add("database.url (DATABASE_URL) is required when the driver is postgres")
+		}
+	default:
+		add("database.driver (DB_DRIVER): %q is not supported, use sqlite3 or postgres", c.Database.Driver)
+	}
+
+	if c.Features.Uploads {
+		if c.Uploads.Dir == "" {
+			add("uploads.dir (UPLOAD_DIR) is required when uploads are enabled")
+		}
+		if c.Uploads.MaxMB <= 0 {
+			add("uploads.max_mb (UPLOAD_MAX_MB) must be positive")
+		}
+	}
+
+	if (c.OAuth.Discord.Key == "") != (c.OAuth.Discord.Secret == "") {
+		add("oauth.discord: DISCORD_KEY and DISCORD_SECRET have to be set together")
+	}
+	if (c.OAuth.Google.Key == "") != (c.OAuth.Google.Secret == "") {
+		add("oauth.google: GOOGLE_KEY and GOOGLE_SECRET have to be set together")
+	}
+	if !c.Features.Local && !c.Features.GuestLogin && len(c.EnabledProviders()) == 0 {
+		add("guest login is disabled and no OAuth provider is configured, nobody would be able to log in")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Names match the goth provider names, in the order they're shown on the login page
+func (c Config) EnabledProviders() []string {
+	var providers []string
+	if c.OAuth.Google.Enabled() {
+		providers = append(providers, "google")
+	}
+	if c.OAuth.Discord.Enabled() {
+		providers = append(providers, "discord")
+	}
+	return providers
+}
+
+// Session cookies are only marked Secure when the site is actually served over https
+func (c Config) SecureCookies() bool {
+	return strings.HasPrefix(c.BaseURL, "https://")
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envMap(m map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := m[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load("", envMap(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8080", cfg.ListenAddr)
+	assert.Equal(t, "http://localhost:8080", cfg.BaseURL)
+	assert.Equal(t, 24*time.Hour, cfg.SessionLifetime)
+	assert.Equal(t, 20*time.Second, cfg.Server.ShutdownGracePeriod)
+	assert.Equal(t, 5*time.Second, cfg.Server.DrainDelay)
+	assert.Equal(t, "sqlite3", cfg.Database.Driver)
+	assert.Equal(t, int64(200<<20), cfg.Uploads.MaxBytes())
+	assert.Equal(t, "http://localhost:8080/auth/discord/callback", cfg.OAuth.Discord.CallbackURL)
+	assert.Empty(t, cfg.EnabledProviders())
+	assert.False(t, cfg.SecureCookies())
+}
+
+func TestLoad_EnvOverridesFile(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+listen_addr = ":9000"
+base_url = "https://ops.example.com/"
+session_lifetime = "72h"
+
+[server]
+shutdown_grace_period = "45s"
+
+[uploads]
+max_mb = 50
+
+[oauth.google]
+key = "file-key"
+secret = "file-secret"
+`)
+	cfg, err := load(path, envMap(map[string]string{
+		"UPLOAD_MAX_MB":       "10",
+		"LINK_CHECK_INTERVAL": "6h",
+		"HTTP_IDLE_TIMEOUT":   "30s",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":9000", cfg.ListenAddr)
+	assert.Equal(t, "https://ops.example.com", cfg.BaseURL)
+	assert.Equal(t, 72*time.Hour, cfg.SessionLifetime)
+	assert.Equal(t, 6*time.Hour, cfg.LinkCheckInterval)
+	assert.Equal(t, 45*time.Second, cfg.Server.ShutdownGracePeriod)
+	assert.Equal(t, 30*time.Second, cfg.Server.IdleTimeout)
+	assert.Equal(t, int64(10), cfg.Uploads.MaxMB)
+	assert.Equal(t, []string{"google"}, cfg.EnabledProviders())
+	assert.Equal(t, "https://ops.example.com/auth/google/callback", cfg.OAuth.Google.CallbackURL)
+	assert.True(t, cfg.SecureCookies())
+}
+
+func TestLoad_YAML(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+session_lifetime: 1h
+database:
+  driver: postgres
+  url: postgres://localhost/op_rating
+features:
+  uploads: false
+`)
+	cfg, err := load("", envMap(map[string]string{"CONFIG_FILE": path, "PORT": "3000"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":3000", cfg.ListenAddr)
+	assert.Equal(t, time.Hour, cfg.SessionLifetime)
+	assert.Equal(t, "postgres://localhost/op_rating", cfg.Database.DSN())
+	assert.False(t, cfg.Features.Uploads)
+}
+
+func TestLoad_UnknownFileKey(t *testing.T) {
+	path := writeFile(t, "config.toml", `sesion_lifetime = "1h"`)
+	_, err := load(path, envMap(nil))
+	assert.ErrorContains(t, err, "sesion_lifetime")
+
+	path = writeFile(t, "config.yml", "sesion_lifetime: 1h\n")
+	_, err = load(path, envMap(nil))
+	assert.ErrorContains(t, err, "sesion_lifetime")
+}
+
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	_, err := load("", envMap(map[string]string{
+		"SESSION_LIFETIME": "forever",
+		"UPLOAD_MAX_MB":    "lots",
+	}))
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "SESSION_LIFETIME")
+	assert.ErrorContains(t, err, "UPLOAD_MAX_MB")
+
+	_, err = load("", envMap(map[string]string{"SHUTDOWN_GRACE_PERIOD": "0s", "LOG_FORMAT": "xml"}))
+	assert.ErrorContains(t, err, "SHUTDOWN_GRACE_PERIOD")
+	assert.ErrorContains(t, err, "LOG_FORMAT")
+
+	_, err = load("", envMap(map[string]string{
+		"DB_DRIVER":           "mysql",
+		"DISCORD_KEY":         "key",
+		"BASE_URL":            "localhost",
+		"FEATURE_GUEST_LOGIN": "false",
+	}))
+	require.Error(t, err)
+	assert.ErrorContains(t, err, `"mysql" is not supported`)
+	assert.ErrorContains(t, err, "DISCORD_KEY and DISCORD_SECRET")
+	assert.ErrorContains(t, err, "BASE_URL")
+	assert.ErrorContains(t, err, "nobody would be able to log in")
+}
+
+func TestLoad_PostgresNeedsURL(t *testing.T) {
+	_, err := load("", envMap(map[string]string{"DB_DRIVER": "postgres"}))
+	assert.ErrorContains(t, err, "DATABASE_URL")
+}
+
+func TestLoad_LocalMode(t *testing.T) {
+	dir := t.TempDir()
+	cfg, err := load("", envMap(map[string]string{
+		"LOCAL_MODE":          "true",
+		"DATA_DIR":            dir,
+		"DB_DRIVER":           "postgres",
+		"DISCORD_KEY":         "key",
+		"DISCORD_SECRET":      "secret",
+		"FEATURE_GUEST_LOGIN": "false",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "127.0.0.1:0", cfg.ListenAddr)
+	assert.Equal(t, "sqlite3", cfg.Database.Driver)
+	assert.Equal(t, filepath.Join(dir, "op_rating.db"), cfg.Database.FilePath())
+	assert.Equal(t, filepath.Join(dir, "uploads"), cfg.Uploads.Dir)
+	assert.Equal(t, "text", cfg.LogFormat)
+	assert.Empty(t, cfg.EnabledProviders())
+}
+
+func TestLoad_RateLimits(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[rate_limit]
+vote = "5/s"
+
+[limits]
+max_entries = 64
+`)
+	cfg, err := load(path, envMap(map[string]string{"RATE_LIMIT_AUTH": "3/h"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, Rate{Count: 5, Per: time.Second}, cfg.RateLimit.Vote)
+	assert.Equal(t, Rate{Count: 3, Per: time.Hour}, cfg.RateLimit.Auth)
+	assert.Equal(t, "10/m", cfg.RateLimit.Create.String())
+	assert.Equal(t, int64(64), cfg.Limits.MaxEntries)
+
+	_, err = load("", envMap(map[string]string{"RATE_LIMIT_VOTE": "lots"}))
+	assert.ErrorContains(t, err, "RATE_LIMIT_VOTE")
+	_, err = load(writeFile(t, "config.yaml", "rate_limit:\n  edit: 10/fortnight\n"), envMap(nil))
+	assert.ErrorContains(t, err, "10/fortnight")
+}
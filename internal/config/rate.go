@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A token bucket size and refill period written as "10/m": 10 requests, refilled over a minute.
+// Bursts up to Count are fine as long as the average stays under it
+type Rate struct {
+	Count int
+	Per   time.Duration
+}
+
+var rateUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+func ParseRate(s string) (Rate, error) {
+	count, unit, ok := strings.Cut(strings.TrimSpace(s), "/")
+	n, err := strconv.Atoi(count)
+	per, known := rateUnits[unit]
+	if !ok || err != nil || n <= 0 || !known {
+		return Rate{}, fmt.Errorf("%q is not a rate like 10/m, 5/s or 100/h", s)
+	}
+	return Rate{Count: n, Per: per}, nil
+}
+
+// Lets the TOML and YAML decoders read rates as strings
+func (r *Rate) UnmarshalText(text []byte) error {
+	parsed, err := ParseRate(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+func (r Rate) String() string {
+	for unit, d := range rateUnits {
+		if d == r.Per {
+			return fmt.Sprintf("%d/%s", r.Count, unit)
+		}
+	}
+	return fmt.Sprintf("%d/%s", r.Count, r.Per)
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrBackupUnsupported = errors.New("backup and restore only work with SQLite, use pg_dump and pg_restore for Postgres")
+
+// SQLite files start with this, good enough to stop us restoring a random file over the database
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// Writes a consistent copy of the database to path while the server keeps running
+func Backup(db *sqlx.DB, path string) error {
+	if db.DriverName() != DriverSQLite {
+		return ErrBackupUnsupported
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	_, err := db.Exec("VACUUM INTO ?", path)
+	return err
+}
+
+// Replaces the database file at dbPath with the backup. The server must not be running,
+// SQLite keeps the old file open and would carry on writing to it
+func Restore(backupPath, dbPath string) error {
+	src, err := os.Open(backupPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	header := make([]byte, len(sqliteHeader))
+	if _, err := io.ReadFull(src, header); err != nil || !bytes.Equal(header, sqliteHeader) {
+		return fmt.Errorf("%s is not a SQLite database", backupPath)
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	// Copy next to the target first so a failed copy never leaves a half written database behind
+	tmpPath := dbPath + ".restore"
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// Leftover WAL files belong to the old database and would be replayed on top of the backup
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(tmpPath, dbPath)
+}
@@ -1,57 +1,86 @@
 package db
 
 import (
-	"database/sql"
 	"errors"
+	"fmt"
 	"log"
-	"os"
+	"strings"
 
+	"github.com/AdamBeresnev/op-rating-app/migrations"
+	"github.com/alexedwards/scs/sqlite3store"
+	"github.com/alexedwards/scs/v2"
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
-func InitDB() *sqlx.DB {
-	dbPath := os.Getenv("DB_PATH")
-	if dbPath == "" {
-		dbPath = "op_rating.db?_journal_mode=WAL"
-	}
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
 
-	var err error
-	db, err := sqlx.Connect("sqlite3", dbPath)
-	if err != nil {
-		log.Fatalln("Failed to connect to DB:", err)
+// dsn is DB_PATH for SQLite and DATABASE_URL for Postgres, see config.Database
+func InitDB(driver, dsn string) (*sqlx.DB, error) {
+	if driver == DriverSQLite {
+		dsn = sqliteDSN(dsn)
 	}
-
-	_, err = db.Exec("PRAGMA foreign_keys = ON;")
+	db, err := sqlx.Connect(driver, dsn)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("failed to connect to DB: %w", err)
 	}
 
-	log.Println("Database connected.")
-	dbInstance = db
-	return db
+	log.Printf("Database connected (%s).", driver)
+	return db, nil
 }
 
-var dbInstance *sqlx.DB
+// Foreign keys are a per-connection setting in SQLite, a PRAGMA would only reach whichever pooled connection ran it.
+// In the DSN every connection gets them, deleting a tournament relies on ON DELETE CASCADE for its entries and matches
+func sqliteDSN(dsn string) string {
+	if strings.Contains(dsn, "_foreign_keys=") || strings.Contains(dsn, "_fk=") {
+		return dsn
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&_foreign_keys=on"
+	}
+	return dsn + "?_foreign_keys=on"
+}
 
-func GetDB() *sqlx.DB {
-	return dbInstance
+// Each backend keeps its own migration history, Postgres ones live in migrations/postgres
+func migrationSource(driver string) (source.Driver, error) {
+	dir := "."
+	if driver == DriverPostgres {
+		dir = "postgres"
+	}
+	return iofs.New(migrations.FS, dir)
 }
 
-func RunMigrations(db *sql.DB) error {
-	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+func newMigrate(db *sqlx.DB) (*migrate.Migrate, error) {
+	src, err := migrationSource(db.DriverName())
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var driver database.Driver
+	if db.DriverName() == DriverPostgres {
+		driver, err = postgres.WithInstance(db.DB, &postgres.Config{})
+	} else {
+		driver, err = sqlite3.WithInstance(db.DB, &sqlite3.Config{})
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://migrations",
-		"sqlite3",
-		driver,
-	)
+	return migrate.NewWithInstance("iofs", src, db.DriverName(), driver)
+}
+
+func RunMigrations(db *sqlx.DB) error {
+	m, err := newMigrate(db)
 	if err != nil {
 		return err
 	}
@@ -63,3 +92,36 @@ func RunMigrations(db *sql.DB) error {
 	log.Println("Migrations ran successfully")
 	return nil
 }
+
+// Rolls back the last steps migrations
+func RollbackMigrations(db *sqlx.DB, steps int) error {
+	m, err := newMigrate(db)
+	if err != nil {
+		return err
+	}
+	if err := m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Version 0 means nothing has been applied yet. Dirty means a migration failed halfway and needs fixing by hand
+func MigrationVersion(db *sqlx.DB) (version uint, dirty bool, err error) {
+	m, err := newMigrate(db)
+	if err != nil {
+		return 0, false, err
+	}
+	version, dirty, err = m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Sessions live in the same database as everything else so multiple instances can share them
+func NewSessionStore(db *sqlx.DB) scs.Store {
+	if db.DriverName() == DriverPostgres {
+		return NewPostgresSessionStore(db.DB)
+	}
+	return sqlite3store.New(db.DB)
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitDB_ForeignKeysOnEveryConnection(t *testing.T) {
+	// The config default and the Dockerfile's DB_PATH
+	for _, suffix := range []string{"?_journal_mode=WAL", ""} {
+		t.Run("suffix "+suffix, func(t *testing.T) {
+			database, err := InitDB(DriverSQLite, filepath.Join(t.TempDir(), "op_rating.db")+suffix)
+			require.NoError(t, err)
+			defer database.Close()
+			require.NoError(t, RunMigrations(database))
+			ctx := context.Background()
+
+			// Holding one connection makes everything below run on another one from the pool
+			held, err := database.Conn(ctx)
+			require.NoError(t, err)
+			defer held.Close()
+			var enabled int
+			require.NoError(t, held.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enabled))
+			assert.Equal(t, 1, enabled)
+			require.NoError(t, database.GetContext(ctx, &enabled, "PRAGMA foreign_keys"))
+			assert.Equal(t, 1, enabled)
+
+			tournaments := store.NewTournamentStore(database)
+			// Owned by the superuser the migrations create
+			tournament := &bracket.Tournament{ID: uuid.New(), OwnerID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Name: "Doomed", Status: bracket.TournamentStarted, Type: bracket.SingleElimination}
+			entries := []bracket.Entry{
+				{ID: uuid.New(), TournamentID: tournament.ID, Name: "Entry 1", Seed: 1},
+				{ID: uuid.New(), TournamentID: tournament.ID, Name: "Entry 2", Seed: 2},
+			}
+			match := bracket.Match{ID: uuid.New(), TournamentID: tournament.ID, BracketSide: bracket.WinnersSide, RoundNumber: 1, MatchOrder: 1,
+				Entry1ID: &entries[0].ID, Entry2ID: &entries[1].ID, Status: bracket.MatchPending}
+
+			tx, err := tournaments.BeginTx(ctx)
+			require.NoError(t, err)
+			require.NoError(t, tournaments.CreateTournament(ctx, tx, tournament))
+			require.NoError(t, tournaments.CreateEntries(ctx, tx, entries))
+			require.NoError(t, tournaments.CreateMatches(ctx, tx, []bracket.Match{match}))
+			require.NoError(t, tx.Commit())
+
+			tx, err = tournaments.BeginTx(ctx)
+			require.NoError(t, err)
+			require.NoError(t, tournaments.DeleteTournament(ctx, tx, tournament.ID.String()))
+			require.NoError(t, tx.Commit())
+
+			var left int
+			require.NoError(t, database.GetContext(ctx, &left, "SELECT COUNT(*) FROM entries"))
+			assert.Zero(t, left, "entries")
+			require.NoError(t, database.GetContext(ctx, &left, "SELECT COUNT(*) FROM matches"))
+			assert.Zero(t, left, "matches")
+		})
+	}
+}
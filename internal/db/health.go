@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Highest migration built into the binary for this driver, what the database should be at after startup
+func LatestMigration(driver string) (uint, error) {
+	src, err := migrationSource(driver)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
+
+// Used by /readyz. Reads schema_migrations directly instead of going through migrate,
+// which would try to create the table and take its lock on every probe
+func CheckReady(ctx context.Context, db *sqlx.DB, want uint) error {
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("database unreachable: %w", err)
+	}
+
+	var current struct {
+		Version uint `db:"version"`
+		Dirty   bool `db:"dirty"`
+	}
+	err := db.GetContext(ctx, &current, "SELECT version, dirty FROM schema_migrations LIMIT 1")
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no migrations applied, want %d", want)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read migration version: %w", err)
+	}
+	if current.Dirty {
+		return fmt.Errorf("migration %d is dirty", current.Version)
+	}
+	if current.Version != want {
+		return fmt.Errorf("database is at migration %d, want %d", current.Version, want)
+	}
+	return nil
+}
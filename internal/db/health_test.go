@@ -0,0 +1,29 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckReady(t *testing.T) {
+	database, err := InitDB(DriverSQLite, filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer database.Close()
+	ctx := context.Background()
+
+	latest, err := LatestMigration(DriverSQLite)
+	require.NoError(t, err)
+	assert.NotZero(t, latest)
+
+	assert.ErrorContains(t, CheckReady(ctx, database, latest), "failed to read migration version")
+
+	require.NoError(t, RunMigrations(database))
+	assert.NoError(t, CheckReady(ctx, database, latest))
+
+	require.NoError(t, RollbackMigrations(database, 1))
+	assert.ErrorContains(t, CheckReady(ctx, database, latest), "want")
+}
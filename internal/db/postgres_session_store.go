@@ -0,0 +1,80 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+)
+
+// scs session store backed by the Postgres sessions table, same idea as sqlite3store
+type PostgresSessionStore struct {
+	db          *sql.DB
+	stopCleanup chan bool
+}
+
+const (
+	findSessionQuery   = "SELECT data FROM sessions WHERE token = $1 AND current_timestamp < expiry"
+	commitSessionQuery = `INSERT INTO sessions (token, data, expiry) VALUES ($1, $2, $3)
+		ON CONFLICT (token) DO UPDATE SET data = EXCLUDED.data, expiry = EXCLUDED.expiry`
+	deleteSessionQuery         = "DELETE FROM sessions WHERE token = $1"
+	deleteExpiredSessionsQuery = "DELETE FROM sessions WHERE expiry < current_timestamp"
+)
+
+// Expired sessions get cleaned up every 5 minutes in the background
+func NewPostgresSessionStore(db *sql.DB) *PostgresSessionStore {
+	return NewPostgresSessionStoreWithCleanupInterval(db, 5*time.Minute)
+}
+
+// A zero interval turns the cleanup off
+func NewPostgresSessionStoreWithCleanupInterval(db *sql.DB, interval time.Duration) *PostgresSessionStore {
+	s := &PostgresSessionStore{db: db}
+	if interval > 0 {
+		s.stopCleanup = make(chan bool)
+		go s.startCleanup(interval)
+	}
+	return s
+}
+
+func (s *PostgresSessionStore) Find(token string) ([]byte, bool, error) {
+	var b []byte
+	err := s.db.QueryRow(findSessionQuery, token).Scan(&b)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+func (s *PostgresSessionStore) Commit(token string, b []byte, expiry time.Time) error {
+	_, err := s.db.Exec(commitSessionQuery, token, b, expiry.UTC())
+	return err
+}
+
+func (s *PostgresSessionStore) Delete(token string) error {
+	_, err := s.db.Exec(deleteSessionQuery, token)
+	return err
+}
+
+func (s *PostgresSessionStore) startCleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.db.Exec(deleteExpiredSessionsQuery); err != nil {
+				log.Println("Failed to clean up expired sessions:", err)
+			}
+		case <-s.stopCleanup:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func (s *PostgresSessionStore) StopCleanup() {
+	if s.stopCleanup != nil {
+		s.stopCleanup <- true
+	}
+}
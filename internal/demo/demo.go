@@ -0,0 +1,114 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/AdamBeresnev/op-rating-app/internal/utils"
+	"github.com/google/uuid"
+)
+
+// Sample data for --demo mode. Everything belongs to the guest user so "Continue as guest" shows it straight away
+
+type sampleTheme struct {
+	name   string
+	link   string
+	series string
+	kind   bracket.ThemeType
+	seq    int
+	song   string
+	artist string
+	season bracket.Season
+	year   int
+}
+
+var fall2022 = []sampleTheme{
+	{"Chainsaw Man OP", "https://v.animethemes.moe/ChainsawMan-OP1.webm", "Chainsaw Man", bracket.ThemeOpening, 1, "KICK BACK", "Kenshi Yonezu", bracket.SeasonFall, 2022},
+	{"Bocchi the Rock! OP", "https://v.animethemes.moe/BocchiTheRock-OP1.webm", "Bocchi the Rock!", bracket.ThemeOpening, 1, "Seishun Complex", "Kessoku Band", bracket.SeasonFall, 2022},
+	{"Spy x Family OP2", "https://v.animethemes.moe/SpyXFamilyPart2-OP1.webm", "Spy x Family Part 2", bracket.ThemeOpening, 1, "SOUVENIR", "BUMP OF CHICKEN", bracket.SeasonFall, 2022},
+	{"Mob Psycho 100 III OP", "https://v.animethemes.moe/MobPsycho100S3-OP1.webm", "Mob Psycho 100 III", bracket.ThemeOpening, 1, "1", "MOB CHOIR feat. sajou no hana", bracket.SeasonFall, 2022},
+	{"Bleach TYBW OP", "https://v.animethemes.moe/BleachSennenKessenHen-OP1.webm", "Bleach: Thousand-Year Blood War", bracket.ThemeOpening, 1, "Scar", "Kitani Tatsuya", bracket.SeasonFall, 2022},
+	{"Cyberpunk: Edgerunners OP", "https://v.animethemes.moe/CyberpunkEdgerunners-OP1.webm", "Cyberpunk: Edgerunners", bracket.ThemeOpening, 1, "This Fffire", "Franz Ferdinand", bracket.SeasonFall, 2022},
+	{"Bocchi the Rock! ED3", "https://v.animethemes.moe/BocchiTheRock-ED3.webm", "Bocchi the Rock!", bracket.ThemeEnding, 3, "Korogaru Iwa, Kimi ni Asa ga Furu", "Kessoku Band", bracket.SeasonFall, 2022},
+	{"Chainsaw Man ED1", "https://v.animethemes.moe/ChainsawMan-ED1.webm", "Chainsaw Man", bracket.ThemeEnding, 1, "CHAINSAW BLOOD", "Vaundy", bracket.SeasonFall, 2022},
+}
+
+var classics = []sampleTheme{
+	{"Cowboy Bebop OP", "https://v.animethemes.moe/CowboyBebop-OP1.webm", "Cowboy Bebop", bracket.ThemeOpening, 1, "Tank!", "The Seatbelts", bracket.SeasonSpring, 1998},
+	{"Evangelion OP", "https://v.animethemes.moe/NeonGenesisEvangelion-OP1.webm", "Neon Genesis Evangelion", bracket.ThemeOpening, 1, "A Cruel Angel's Thesis", "Yoko Takahashi", bracket.SeasonFall, 1995},
+	{"FMA Brotherhood OP1", "https://v.animethemes.moe/FullmetalAlchemistBrotherhood-OP1.webm", "Fullmetal Alchemist: Brotherhood", bracket.ThemeOpening, 1, "again", "YUI", bracket.SeasonSpring, 2009},
+	{"Steins;Gate OP", "https://v.animethemes.moe/SteinsGate-OP1.webm", "Steins;Gate", bracket.ThemeOpening, 1, "Hacking to the Gate", "Kanako Itou", bracket.SeasonSpring, 2011},
+	{"Attack on Titan OP1", "https://v.animethemes.moe/ShingekiNoKyojin-OP1.webm", "Attack on Titan", bracket.ThemeOpening, 1, "Guren no Yumiya", "Linked Horizon", bracket.SeasonSpring, 2013},
+}
+
+func (t sampleTheme) entryInput() service.EntryInput {
+	return service.EntryInput{
+		Name:      t.name,
+		EmbedLink: t.link,
+		Metadata: bracket.EntryMetadata{
+			SeriesTitle:   utils.Ptr(t.series),
+			ThemeType:     utils.Ptr(t.kind),
+			ThemeSequence: utils.Ptr(t.seq),
+			SongTitle:     utils.Ptr(t.song),
+			Artist:        utils.Ptr(t.artist),
+			Season:        utils.Ptr(t.season),
+			Year:          utils.Ptr(t.year),
+		},
+	}
+}
+
+func entryInputs(themes []sampleTheme) []service.EntryInput {
+	inputs := make([]service.EntryInput, len(themes))
+	for i, t := range themes {
+		inputs[i] = t.entryInput()
+	}
+	return inputs
+}
+
+// Creates the guest user and a couple of tournaments, one of them already halfway through round 1
+func Seed(ctx context.Context, users *service.UserService, tournaments *service.TournamentService, matches *service.MatchService) error {
+	guest, err := users.EnsureGuestUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create guest user: %w", err)
+	}
+	ctx = context.WithValue(ctx, middleware.UserIDKey, guest.ID)
+
+	if _, err := tournaments.CreateTournament(ctx, "Demo: Classics", bracket.DoubleElimination, entryInputs(classics)); err != nil {
+		return fmt.Errorf("failed to create demo tournament: %w", err)
+	}
+
+	fallID, err := tournaments.CreateTournament(ctx, "Demo: Fall 2022", bracket.SingleElimination, entryInputs(fall2022))
+	if err != nil {
+		return fmt.Errorf("failed to create demo tournament: %w", err)
+	}
+	return playRound(ctx, tournaments, matches, fallID, 2)
+}
+
+// Decides the first n playable matches, the higher seed always wins
+func playRound(ctx context.Context, tournaments *service.TournamentService, matches *service.MatchService, tournamentID uuid.UUID, n int) error {
+	for i := 0; i < n; i++ {
+		data, err := tournaments.GetTournamentData(ctx, tournamentID.String())
+		if err != nil {
+			return err
+		}
+		if data.NextMatchID == nil {
+			return nil
+		}
+
+		match, err := matches.GetMatchViewData(ctx, data.NextMatchID.String())
+		if err != nil {
+			return err
+		}
+		winner := match.Entry1
+		if match.Entry2.Seed < winner.Seed {
+			winner = match.Entry2
+		}
+		if _, err := matches.AdvanceWinner(ctx, match.Match.ID, winner.ID); err != nil {
+			return fmt.Errorf("failed to play demo match: %w", err)
+		}
+	}
+	return nil
+}
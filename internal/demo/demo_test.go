@@ -0,0 +1,43 @@
+package demo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeed(t *testing.T) {
+	tournamentStore := store.NewMemoryTournamentStore()
+	tournamentService := service.NewTournamentService(tournamentStore)
+
+	err := Seed(context.Background(),
+		service.NewUserService(store.NewMemoryUserStore()),
+		tournamentService,
+		service.NewMatchService(tournamentStore),
+	)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, uuid.MustParse(middleware.SuperUserID))
+	tournaments, err := tournamentService.GetTournamentsForUser(ctx)
+	require.NoError(t, err)
+	require.Len(t, tournaments, 2)
+
+	var finished int
+	for _, tournament := range tournaments {
+		matches, err := tournamentStore.GetMatches(ctx, tournament.ID.String())
+		require.NoError(t, err)
+		for _, m := range matches {
+			if m.Status == bracket.MatchFinished && !m.IsBye {
+				finished++
+			}
+		}
+	}
+	assert.Equal(t, 2, finished)
+}
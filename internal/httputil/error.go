@@ -1,29 +1,134 @@
 package httputil
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/AdamBeresnev/op-rating-app/views"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 )
 
-func InternalServerError(w http.ResponseWriter, msg string, err error) {
-	slog.Error(msg, "error", err)
-	http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+// Picks the status code for a service error and renders it. Anything the services don't have a type for is a 500,
+// the details of those only go to the log
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	status, msg := classify(err)
+	if status >= http.StatusInternalServerError {
+		InternalServerError(w, r, "request failed", err)
+		return
+	}
+	slog.Warn("request rejected", "status", status, "error", err, "request_id", chimiddleware.GetReqID(r.Context()))
+	render(w, r, status, msg)
+}
+
+func classify(err error) (int, string) {
+	var notFound *service.NotFoundError
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &notFound):
+		return http.StatusNotFound, capitalize(notFound.Error())
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound, "Not found"
+	case errors.As(err, &invalid):
+		return http.StatusBadRequest, invalid.Message
+	case errors.Is(err, service.ErrWinnerNotInMatch):
+		return http.StatusBadRequest, capitalize(service.ErrWinnerNotInMatch.Error())
+	case errors.Is(err, service.ErrUnauthenticated):
+		return http.StatusUnauthorized, capitalize(service.ErrUnauthenticated.Error())
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden, capitalize(service.ErrForbidden.Error())
+	}
+	// The request was fine, the tournament just isn't in a state where it can happen
+	for _, conflict := range []error{service.ErrMatchOutOfOrder, service.ErrTournamentNotStarted, service.ErrTournamentFinished, service.ErrTournamentStarted, service.ErrFormatLocked, service.ErrMatchDecided} {
+		if errors.Is(err, conflict) {
+			return http.StatusConflict, capitalize(conflict.Error())
+		}
+	}
+	return http.StatusInternalServerError, "Something went wrong on our side"
+}
+
+// The request ID ties an error in the logs to the request line middleware.Observe writes for it
+func InternalServerError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	slog.Error(msg, "error", err, "request_id", chimiddleware.GetReqID(r.Context()))
+	render(w, r, http.StatusInternalServerError, "Something went wrong on our side")
+}
+
+func BadRequest(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	if err != nil {
+		slog.Warn("bad request", "message", msg, "error", err, "request_id", chimiddleware.GetReqID(r.Context()))
+	} else {
+		slog.Warn("bad request", "message", msg, "request_id", chimiddleware.GetReqID(r.Context()))
+	}
+	render(w, r, http.StatusBadRequest, msg)
 }
 
-func BadRequest(w http.ResponseWriter, msg string, err error) {
+func NotFound(w http.ResponseWriter, r *http.Request, msg string, err error) {
 	if err != nil {
-		slog.Warn("bad request", "message", msg, "error", err)
+		slog.Warn("not found", "message", msg, "error", err, "request_id", chimiddleware.GetReqID(r.Context()))
 	} else {
-		slog.Warn("bad request", "message", msg)
+		slog.Warn("not found", "message", msg, "request_id", chimiddleware.GetReqID(r.Context()))
 	}
-	http.Error(w, msg, http.StatusBadRequest)
+	render(w, r, http.StatusNotFound, msg)
 }
 
-func NotFound(w http.ResponseWriter, msg string, err error) {
+func Forbidden(w http.ResponseWriter, r *http.Request, msg string, err error) {
 	if err != nil {
-		slog.Warn("not found", "message", msg, "error", err)
+		slog.Warn("forbidden", "message", msg, "error", err, "request_id", chimiddleware.GetReqID(r.Context()))
 	} else {
-		slog.Warn("not found", "message", msg)
+		slog.Warn("forbidden", "message", msg, "request_id", chimiddleware.GetReqID(r.Context()))
 	}
-	http.Error(w, msg, http.StatusNotFound)
+	render(w, r, http.StatusForbidden, msg)
+}
+
+func TooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	slog.Warn("rate limited", "path", r.URL.Path, "retry_after", seconds, "request_id", chimiddleware.GetReqID(r.Context()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	render(w, r, http.StatusTooManyRequests, fmt.Sprintf("Slow down a little, try again in %d seconds", seconds))
+}
+
+type errorResponse struct {
+	Error     string `json:"error"`
+	Status    int    `json:"status"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// htmx requests get a toast, API clients JSON and everything else a full page
+func render(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	requestID := chimiddleware.GetReqID(r.Context())
+
+	switch {
+	case r.Header.Get("HX-Request") == "true":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("HX-Retarget", "#toasts")
+		w.Header().Set("HX-Reswap", "beforeend")
+		w.WriteHeader(status)
+		views.ErrorToast(msg, requestID).Render(r.Context(), w)
+
+	case strings.Contains(r.Header.Get("Accept"), "application/json"):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(errorResponse{Error: msg, Status: status, RequestID: requestID})
+
+	default:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		views.ErrorPage(status, http.StatusText(status), msg, requestID).Render(r.Context(), w)
+	}
+}
+
+// Service errors are lower case like every Go error, toasts read better with a capital
+func capitalize(s string) string {
+	first, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(first)) + s[size:]
 }
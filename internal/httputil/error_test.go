@@ -0,0 +1,59 @@
+package httputil
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		msg    string
+	}{
+		{&service.NotFoundError{What: "tournament", Err: sql.ErrNoRows}, http.StatusNotFound, "Tournament not found"},
+		{sql.ErrNoRows, http.StatusNotFound, "Not found"},
+		{fmt.Errorf("failed to get match: %w", service.ErrMatchOutOfOrder), http.StatusConflict, "Matches must be decided in order"},
+		{service.ErrWinnerNotInMatch, http.StatusBadRequest, "Winner is not part of this match"},
+		{&service.ValidationError{Message: "Too many entries"}, http.StatusBadRequest, "Too many entries"},
+		{service.ErrForbidden, http.StatusForbidden, "You don't have access to this"},
+		{errors.New("disk on fire"), http.StatusInternalServerError, "Something went wrong on our side"},
+	}
+	for _, tt := range tests {
+		status, msg := classify(tt.err)
+		assert.Equal(t, tt.status, status, tt.err.Error())
+		assert.Equal(t, tt.msg, msg, tt.err.Error())
+	}
+}
+
+func TestErrorRendering(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/thing", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	Error(rec, req, service.ErrTournamentFinished)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "Tournament is already finished", body.Error)
+
+	// The internals of a 500 stay in the log
+	req = httptest.NewRequest(http.MethodPost, "/matches/1/advance", nil)
+	req.Header.Set("HX-Request", "true")
+	rec = httptest.NewRecorder()
+	Error(rec, req, errors.New("pq: connection refused"))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "#toasts", rec.Header().Get("HX-Retarget"))
+	assert.NotContains(t, rec.Body.String(), "connection refused")
+}
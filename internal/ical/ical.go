@@ -0,0 +1,130 @@
+// Package ical writes just enough of RFC 5545 for a subscribable calendar of timed events
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	prodID     = "-//op-rating-app//Match schedule//EN"
+	timeLayout = "20060102T150405Z"
+	// Content lines longer than this many bytes get folded
+	maxLineLength = 75
+)
+
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+type Event struct {
+	// Has to stay the same across refreshes, calendar apps use it to update the event instead of adding another
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	// When the event last changed, falls back to the time of writing
+	Stamp time.Time
+}
+
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	cw := &contentWriter{w: bufio.NewWriter(w)}
+	now := time.Now()
+
+	cw.line("BEGIN:VCALENDAR")
+	cw.line("VERSION:2.0")
+	cw.line("PRODID:" + prodID)
+	cw.line("CALSCALE:GREGORIAN")
+	cw.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		cw.line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	for _, e := range c.Events {
+		stamp := e.Stamp
+		if stamp.IsZero() {
+			stamp = now
+		}
+		cw.line("BEGIN:VEVENT")
+		cw.line("UID:" + escape(e.UID))
+		cw.line("DTSTAMP:" + formatTime(stamp))
+		cw.line("DTSTART:" + formatTime(e.Start))
+		if !e.End.IsZero() {
+			cw.line("DTEND:" + formatTime(e.End))
+		}
+		cw.line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			cw.line("DESCRIPTION:" + escape(e.Description))
+		}
+		if e.Location != "" {
+			cw.line("LOCATION:" + escape(e.Location))
+		}
+		if e.URL != "" {
+			// URI values aren't escaped like text
+			cw.line("URL:" + e.URL)
+		}
+		cw.line("END:VEVENT")
+	}
+	cw.line("END:VCALENDAR")
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// Keeps the first error around so WriteTo doesn't have to check every line
+type contentWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+// Ends lines with CRLF and folds them at 75 bytes without splitting a UTF-8 sequence.
+// Continuation lines start with a space, which counts towards their length
+func (cw *contentWriter) line(s string) {
+	limit := maxLineLength
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		cw.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = maxLineLength - 1
+	}
+	cw.write(s + "\r\n")
+}
+
+func (cw *contentWriter) write(s string) {
+	if cw.err != nil {
+		return
+	}
+	n, err := cw.w.WriteString(s)
+	cw.n += int64(n)
+	cw.err = err
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,63 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendar_WriteTo(t *testing.T) {
+	start := time.Date(2026, 11, 2, 19, 30, 0, 0, time.FixedZone("CET", 3600))
+	calendar := &Calendar{
+		Name: "Openings, 2026",
+		Events: []Event{{
+			UID:         "match-1@example.com",
+			Start:       start,
+			End:         start.Add(time.Hour),
+			Summary:     "A vs B; Winners R1 #1",
+			Description: "First line\nsecond line",
+			URL:         "https://example.com/matches/1",
+			Stamp:       start,
+		}},
+	}
+
+	var sb strings.Builder
+	n, err := calendar.WriteTo(&sb)
+	require.NoError(t, err)
+	out := sb.String()
+	assert.Equal(t, int64(len(out)), n)
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.Contains(t, out, "X-WR-CALNAME:Openings\\, 2026\r\n")
+	assert.Contains(t, out, "DTSTART:20261102T183000Z\r\n")
+	assert.Contains(t, out, "DTEND:20261102T193000Z\r\n")
+	assert.Contains(t, out, "SUMMARY:A vs B\\; Winners R1 #1\r\n")
+	assert.Contains(t, out, "DESCRIPTION:First line\\nsecond line\r\n")
+	assert.Contains(t, out, "URL:https://example.com/matches/1\r\n")
+}
+
+func TestCalendar_Folding(t *testing.T) {
+	summary := strings.Repeat("ö", 100)
+	calendar := &Calendar{Events: []Event{{UID: "1", Start: time.Now(), Summary: summary}}}
+
+	var sb strings.Builder
+	_, err := calendar.WriteTo(&sb)
+	require.NoError(t, err)
+
+	var unfolded strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(sb.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+		assert.True(t, utf8.ValidString(line), line)
+		if strings.HasPrefix(line, " ") {
+			unfolded.WriteString(line[1:])
+		} else {
+			unfolded.WriteString("\n" + line)
+		}
+	}
+	assert.Contains(t, unfolded.String(), "\nSUMMARY:"+summary+"\n")
+}
@@ -0,0 +1,156 @@
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+)
+
+// Anything that can send a request, *http.Client satisfies this. Tests swap it out for httptest stand-ins
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+const defaultYouTubeOEmbedURL = "https://www.youtube.com/oembed"
+
+type Checker struct {
+	client HTTPClient
+	// YouTube answers 200 for deleted videos on the watch page, so we ask oEmbed instead
+	youTubeOEmbedURL string
+}
+
+// With a nil client the checker only ever connects to public addresses
+func NewChecker(client HTTPClient) *Checker {
+	if client == nil {
+		client = publicOnlyClient()
+	}
+	return &Checker{client: client, youTubeOEmbedURL: defaultYouTubeOEmbedURL}
+}
+
+var errPrivateAddress = errors.New("linkcheck: refusing to connect to a private address")
+
+// Links are pasted in by users, so they could point at localhost or something on the internal network.
+// The address is checked right before connecting, that covers redirects and hostnames resolving to private IPs too
+func publicOnlyClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil || !isPublic(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would do the connecting for us and skip the check above
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// Carrier-grade NAT, netip doesn't count it as private
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// Points YouTube lookups at a different oEmbed endpoint, mostly for tests
+func (c *Checker) WithYouTubeOEmbedURL(endpoint string) *Checker {
+	c.youTubeOEmbedURL = endpoint
+	return c
+}
+
+func (c *Checker) Check(ctx context.Context, link string) bracket.LinkStatus {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return bracket.LinkBroken
+	}
+
+	if isYouTube(u) {
+		return c.checkYouTube(ctx, u)
+	}
+	return c.checkGeneric(ctx, u)
+}
+
+func isYouTube(u *url.URL) bool {
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	return host == "youtube.com" || host == "m.youtube.com" || host == "youtu.be"
+}
+
+func (c *Checker) checkYouTube(ctx context.Context, u *url.URL) bracket.LinkStatus {
+	endpoint, err := url.Parse(c.youTubeOEmbedURL)
+	if err != nil {
+		return bracket.LinkUnreachable
+	}
+	q := endpoint.Query()
+	q.Set("url", u.String())
+	q.Set("format", "json")
+	endpoint.RawQuery = q.Encode()
+
+	status, err := c.do(ctx, http.MethodGet, endpoint.String(), false)
+	if err != nil {
+		return bracket.LinkUnreachable
+	}
+
+	switch {
+	case status == http.StatusOK:
+		return bracket.LinkOK
+	// oEmbed says 401 for private videos, 400/404 for deleted or made up ones
+	case status == http.StatusBadRequest || status == http.StatusUnauthorized || status == http.StatusForbidden || status == http.StatusNotFound:
+		return bracket.LinkBroken
+	default:
+		return bracket.LinkUnreachable
+	}
+}
+
+func (c *Checker) checkGeneric(ctx context.Context, u *url.URL) bracket.LinkStatus {
+	status, err := c.do(ctx, http.MethodHead, u.String(), false)
+	// Plenty of hosts don't bother implementing HEAD, try a regular GET before giving up
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.do(ctx, http.MethodGet, u.String(), true)
+	}
+	if err != nil {
+		return bracket.LinkUnreachable
+	}
+
+	switch {
+	case status < 400:
+		return bracket.LinkOK
+	case status == http.StatusNotFound || status == http.StatusGone || status == http.StatusForbidden || status == http.StatusUnauthorized:
+		return bracket.LinkBroken
+	default:
+		return bracket.LinkUnreachable
+	}
+}
+
+func (c *Checker) do(ctx context.Context, method string, target string, firstByteOnly bool) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	if firstByteOnly {
+		// We only care about the status code, don't download the whole video
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,105 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck_Generic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok.webm":
+			w.WriteHeader(http.StatusOK)
+		case "/gone.webm":
+			w.WriteHeader(http.StatusGone)
+		case "/no-head.webm":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			assert.Equal(t, "bytes=0-0", r.Header.Get("Range"))
+			w.WriteHeader(http.StatusPartialContent)
+		case "/flaky.webm":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	checker := NewChecker(server.Client())
+
+	testCases := []struct {
+		name     string
+		link     string
+		expected bracket.LinkStatus
+	}{
+		{name: "OK", link: server.URL + "/ok.webm", expected: bracket.LinkOK},
+		{name: "Not found", link: server.URL + "/missing.webm", expected: bracket.LinkBroken},
+		{name: "Gone", link: server.URL + "/gone.webm", expected: bracket.LinkBroken},
+		{name: "HEAD not allowed falls back to GET", link: server.URL + "/no-head.webm", expected: bracket.LinkOK},
+		{name: "Server error is not broken", link: server.URL + "/flaky.webm", expected: bracket.LinkUnreachable},
+		{name: "Not a URL", link: "definitely not a link", expected: bracket.LinkBroken},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, checker.Check(context.Background(), tc.link))
+		})
+	}
+}
+
+func TestCheck_YouTubeOEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "json", r.URL.Query().Get("format"))
+		switch r.URL.Query().Get("url") {
+		case "https://www.youtube.com/watch?v=alive":
+			w.Write([]byte(`{"title": "OP"}`))
+		case "https://youtu.be/private":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	checker := NewChecker(server.Client()).WithYouTubeOEmbedURL(server.URL + "/oembed")
+
+	assert.Equal(t, bracket.LinkOK, checker.Check(context.Background(), "https://www.youtube.com/watch?v=alive"))
+	assert.Equal(t, bracket.LinkBroken, checker.Check(context.Background(), "https://youtu.be/private"))
+	assert.Equal(t, bracket.LinkBroken, checker.Check(context.Background(), "https://www.youtube.com/watch?v=deleted"))
+}
+
+func TestCheck_PrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// The test server listens on loopback, the default client must not reach it
+	assert.Equal(t, bracket.LinkUnreachable, NewChecker(nil).Check(context.Background(), server.URL+"/ok.webm"))
+
+	for addr, public := range map[string]bool{
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"192.168.0.10":     false,
+		"172.16.5.4":       false,
+		"169.254.169.254":  false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"fd00::1":          false,
+		"fe80::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		assert.Equal(t, public, isPublic(netip.MustParseAddr(addr)), addr)
+	}
+}
@@ -0,0 +1,124 @@
+package media
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnsupportedType = errors.New("only WebM and MP4 videos can be uploaded")
+	ErrTooLarge        = errors.New("upload exceeds the maximum file size")
+)
+
+// Sniffed content type -> file extension on disk
+var allowedTypes = map[string]string{
+	"video/webm": ".webm",
+	"video/mp4":  ".mp4",
+}
+
+type Upload struct {
+	ID           uuid.UUID `db:"id"`
+	OwnerID      uuid.UUID `db:"owner_id"`
+	OriginalName string    `db:"original_name"`
+	ContentType  string    `db:"content_type"`
+	Size         int64     `db:"size"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// Link the player uses to stream the file
+func (u *Upload) URL() string {
+	return URLFor(u.ID)
+}
+
+const URLPrefix = "/media/"
+
+func URLFor(id uuid.UUID) string {
+	return URLPrefix + id.String()
+}
+
+// Library keeps uploaded videos in a directory on local disk, named by upload ID
+type Library struct {
+	dir     string
+	maxSize int64
+}
+
+func NewLibrary(dir string, maxSize int64) (*Library, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	return &Library{dir: dir, maxSize: maxSize}, nil
+}
+
+func (l *Library) MaxSize() int64 {
+	return l.maxSize
+}
+
+// Save sniffs the content type from the first bytes and writes the file to disk.
+// Nothing is left behind on disk if the file is rejected
+func (l *Library) Save(id uuid.UUID, r io.Reader) (contentType string, size int64, err error) {
+	br := bufio.NewReaderSize(r, 512)
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", 0, err
+	}
+
+	contentType = http.DetectContentType(head)
+	ext, ok := allowedTypes[contentType]
+	if !ok {
+		return "", 0, ErrUnsupportedType
+	}
+
+	path := filepath.Join(l.dir, id.String()+ext)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", 0, err
+	}
+
+	// Read one byte past the limit so we can tell when the file is too big
+	size, err = io.Copy(f, io.LimitReader(br, l.maxSize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && size > l.maxSize {
+		err = ErrTooLarge
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", 0, err
+	}
+
+	return contentType, size, nil
+}
+
+func (l *Library) path(upload *Upload) string {
+	return filepath.Join(l.dir, upload.ID.String()+allowedTypes[upload.ContentType])
+}
+
+func (l *Library) Remove(upload *Upload) error {
+	err := os.Remove(l.path(upload))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Serve streams the file, ServeContent takes care of Range requests so seeking in the player works
+func (l *Library) Serve(w http.ResponseWriter, r *http.Request, upload *Upload) error {
+	f, err := os.Open(l.path(upload))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", upload.ContentType)
+	http.ServeContent(w, r, upload.OriginalName, upload.CreatedAt, f)
+	return nil
+}
@@ -0,0 +1,73 @@
+package media
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Smallest thing http.DetectContentType recognizes as WebM (EBML header + doctype)
+var webmHeader = []byte{0x1A, 0x45, 0xDF, 0xA3, 0x9F, 0x42, 0x86, 0x81, 0x01, 0x42, 0x82, 0x84, 'w', 'e', 'b', 'm'}
+
+func fakeWebM(size int) []byte {
+	data := make([]byte, size)
+	copy(data, webmHeader)
+	return data
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	library, err := NewLibrary(dir, 1024)
+	require.NoError(t, err)
+
+	t.Run("Accepts WebM", func(t *testing.T) {
+		id := uuid.New()
+		contentType, size, err := library.Save(id, bytes.NewReader(fakeWebM(100)))
+		require.NoError(t, err)
+		assert.Equal(t, "video/webm", contentType)
+		assert.Equal(t, int64(100), size)
+		assert.FileExists(t, filepath.Join(dir, id.String()+".webm"))
+	})
+
+	t.Run("Rejects other types", func(t *testing.T) {
+		_, _, err := library.Save(uuid.New(), bytes.NewReader([]byte("<html><body>not a video</body></html>")))
+		assert.ErrorIs(t, err, ErrUnsupportedType)
+	})
+
+	t.Run("Rejects files over the limit and cleans up", func(t *testing.T) {
+		id := uuid.New()
+		_, _, err := library.Save(id, bytes.NewReader(fakeWebM(2048)))
+		assert.ErrorIs(t, err, ErrTooLarge)
+		_, statErr := os.Stat(filepath.Join(dir, id.String()+".webm"))
+		assert.True(t, os.IsNotExist(statErr))
+	})
+}
+
+func TestServe_Range(t *testing.T) {
+	library, err := NewLibrary(t.TempDir(), 1024)
+	require.NoError(t, err)
+
+	data := fakeWebM(500)
+	data[100] = 'x'
+	upload := &Upload{ID: uuid.New(), OriginalName: "op.webm"}
+	upload.ContentType, upload.Size, err = library.Save(upload.ID, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, upload.URL(), nil)
+	req.Header.Set("Range", "bytes=100-199")
+	rec := httptest.NewRecorder()
+
+	require.NoError(t, library.Serve(rec, req, upload))
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "video/webm", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "bytes 100-199/500", rec.Header().Get("Content-Range"))
+	assert.Equal(t, 100, rec.Body.Len())
+	assert.Equal(t, byte('x'), rec.Body.Bytes()[0])
+}
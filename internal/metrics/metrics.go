@@ -0,0 +1,87 @@
+// Package metrics holds the Prometheus collectors the app exposes on /metrics.
+// They live on their own registry so tests can build as many servers as they like without duplicate registrations.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "op_rating"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	// route is the chi pattern like /tournaments/{id}, never the raw path, so IDs don't blow up the series count
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// Includes slow uploads and media streams, the long running requests worth watching during deploys
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Requests currently being served.",
+	})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time spent in each store method.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"store", "method"})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests turned away by the rate limiter, by route group.",
+	}, []string{"group"})
+
+	TournamentsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tournaments_created_total",
+		Help:      "Tournaments created through the web UI.",
+	})
+
+	MatchesDecided = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "matches_decided_total",
+		Help:      "Matches with a winner picked.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		HTTPInFlight,
+		DBQueryDuration,
+		RateLimited,
+		TournamentsCreated,
+		MatchesDecided,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Meant to be deferred: defer metrics.ObserveQuery("tournament", "GetMatch", time.Now())
+func ObserveQuery(store, method string, start time.Time) {
+	DBQueryDuration.WithLabelValues(store, method).Observe(time.Since(start).Seconds())
+}
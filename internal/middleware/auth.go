@@ -3,8 +3,8 @@ package middleware
 import (
 	"context"
 	"net/http"
-	"os"
 
+	"github.com/AdamBeresnev/op-rating-app/internal/config"
 	"github.com/AdamBeresnev/op-rating-app/internal/store"
 	users "github.com/AdamBeresnev/op-rating-app/internal/user"
 	"github.com/alexedwards/scs/v2"
@@ -19,22 +19,20 @@ type ContextKey string
 const UserIDKey ContextKey = "userID"
 const SuperUserID = "00000000-0000-0000-0000-000000000001"
 
-func InitAuth() {
-	discordKey := os.Getenv("DISCORD_KEY")
-	discordSecret := os.Getenv("DISCORD_SECRET")
-	discordCallbackURL := os.Getenv("DISCORD_CALLBACK_URL")
-
-	googleKey := os.Getenv("GOOGLE_KEY")
-	googleSecret := os.Getenv("GOOGLE_SECRET")
-	googleCallbackURL := os.Getenv("GOOGLE_CALLBACK_URL")
-
-	goth.UseProviders(
-		discord.New(discordKey, discordSecret, discordCallbackURL, discord.ScopeIdentify, discord.ScopeEmail),
-		google.New(googleKey, googleSecret, googleCallbackURL, "email", "profile"),
-	)
+// Only providers with both a key and a secret get registered, the rest are left off the login page
+func InitAuth(cfg config.OAuth) {
+	var providers []goth.Provider
+	if cfg.Discord.Enabled() {
+		providers = append(providers, discord.New(cfg.Discord.Key, cfg.Discord.Secret, cfg.Discord.CallbackURL, discord.ScopeIdentify, discord.ScopeEmail))
+	}
+	if cfg.Google.Enabled() {
+		providers = append(providers, google.New(cfg.Google.Key, cfg.Google.Secret, cfg.Google.CallbackURL, "email", "profile"))
+	}
+	goth.ClearProviders()
+	goth.UseProviders(providers...)
 }
 
-func LoadAuthenticatedUser(sessionManager *scs.SessionManager, userStore *store.UserStore) func(http.Handler) http.Handler {
+func LoadAuthenticatedUser(sessionManager *scs.SessionManager, userStore store.UserRepository) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			userIDStr := sessionManager.GetString(r.Context(), "userID")
@@ -63,6 +61,18 @@ func LoadAuthenticatedUser(sessionManager *scs.SessionManager, userStore *store.
 	}
 }
 
+// Puts userID into every session that doesn't have a user yet. Only for local mode, where there's nobody else
+func AutoLogin(sessionManager *scs.SessionManager, userID uuid.UUID) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if sessionManager.GetString(r.Context(), "userID") == "" {
+				sessionManager.Put(r.Context(), "userID", userID.String())
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if GetAuthenticatedUser(r.Context()) == nil {
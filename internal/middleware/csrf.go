@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+const (
+	csrfSessionKey = "csrfToken"
+	// htmx sends the header thanks to hx-headers on <body>, plain forms use the field
+	CSRFHeader    = "X-CSRF-Token"
+	CSRFFormField = "csrf_token"
+
+	csrfTokenKey ContextKey = "csrfToken"
+)
+
+// One token per session, checked on every request that isn't GET/HEAD/OPTIONS/TRACE.
+// The token has to be in the session before the handler starts writing, scs commits on the first write,
+// so it's created up front instead of when a template asks for it. Goes after LoadAndSave
+func CSRF(sessionManager *scs.SessionManager, onFailure http.HandlerFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := sessionManager.GetString(r.Context(), csrfSessionKey)
+			if token == "" {
+				token = newCSRFToken()
+				sessionManager.Put(r.Context(), csrfSessionKey, token)
+			}
+
+			if !isSafeMethod(r.Method) && !hasBearerToken(r) && !validCSRFToken(r, token) {
+				onFailure(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), csrfTokenKey, token)))
+		})
+	}
+}
+
+// The token for the current session, for templates to put into forms and headers
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfTokenKey).(string)
+	return token
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// API clients authenticate with a header a cross-site form can't set, so there's nothing to forge.
+// Setting it from another origin's script needs a CORS preflight we never answer
+func hasBearerToken(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func validCSRFToken(r *http.Request, want string) bool {
+	got := r.Header.Get(CSRFHeader)
+	if got == "" {
+		// FormValue would read the whole body of a multipart upload just to find out the header is missing,
+		// only urlencoded forms carry the field
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			got = r.PostFormValue(CSRFFormField)
+		}
+	}
+	return got != "" && subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+func newCSRFToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRF(t *testing.T) {
+	sessionManager := scs.New()
+	var seen string
+	handler := sessionManager.LoadAndSave(CSRF(sessionManager, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = CSRFToken(r.Context())
+	})))
+
+	do := func(req *http.Request, cookie *http.Cookie) *httptest.ResponseRecorder {
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The first page view starts a session with a token in it
+	rec := do(httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	session := cookies[0]
+	token := seen
+	require.NotEmpty(t, token)
+
+	assert.Equal(t, http.StatusForbidden, do(httptest.NewRequest(http.MethodPost, "/", nil), session).Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(CSRFHeader, token+"x")
+	assert.Equal(t, http.StatusForbidden, do(req, session).Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(CSRFHeader, token)
+	assert.Equal(t, http.StatusOK, do(req, session).Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{CSRFFormField: {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusOK, do(req, session).Code)
+
+	// A token from another session is no good
+	other := do(httptest.NewRequest(http.MethodGet, "/", nil), nil).Result().Cookies()[0]
+	req = httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(CSRFHeader, token)
+	assert.Equal(t, http.StatusForbidden, do(req, other).Code)
+
+	req = httptest.NewRequest(http.MethodDelete, "/", nil)
+	req.Header.Set("Authorization", "Bearer api-token")
+	assert.Equal(t, http.StatusOK, do(req, nil).Code)
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/metrics"
+	"github.com/AdamBeresnev/op-rating-app/internal/ratelimit"
+	"github.com/google/uuid"
+)
+
+var guestUserID = uuid.MustParse(SuperUserID)
+
+// Limits a route group per logged in user. Guests all share one account, so they and anonymous visitors are
+// limited per client IP instead. Goes after LoadAuthenticatedUser
+func RateLimit(limiter ratelimit.Limiter, group string, clientIP func(*http.Request) string, onLimited func(w http.ResponseWriter, r *http.Request, retryAfter time.Duration)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := "ip:" + clientIP(r)
+			if userID, ok := GetUserIDFromContext(r.Context()); ok && userID != guestUserID {
+				key = "user:" + userID.String()
+			}
+
+			ok, retryAfter, err := limiter.Allow(r.Context(), group+":"+key)
+			if err != nil {
+				// A broken limiter shouldn't take the site down with it
+				slog.Error("rate limiter failed", "group", group, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !ok {
+				metrics.RateLimited.WithLabelValues(group).Inc()
+				onLimited(w, r, retryAfter)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Reads the client IP from header when a trusted proxy sets it, the connection's address otherwise
+func ClientIP(header string) func(*http.Request) string {
+	return func(r *http.Request) string {
+		if header != "" {
+			// X-Forwarded-For style lists have the client first
+			if v, _, _ := strings.Cut(r.Header.Get(header), ","); strings.TrimSpace(v) != "" {
+				return strings.TrimSpace(v)
+			}
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+}
@@ -0,0 +1,82 @@
+// Package ratelimit keeps token buckets per key, one key per user or client IP.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// What the middleware talks to. Memory is enough for one machine, something backed by Redis or Postgres
+// would implement the same thing once there are several
+type Limiter interface {
+	// Takes a token from key's bucket. When it's empty, retryAfter says how long until the next one
+	Allow(ctx context.Context, key string) (ok bool, retryAfter time.Duration, err error)
+}
+
+// In-process buckets. Idle ones are dropped once they'd be full again anyway, so the map doesn't grow forever
+type Memory struct {
+	limit rate.Limit
+	burst int
+	idle  time.Duration
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+var _ Limiter = (*Memory)(nil)
+
+// count requests per period, all of them can be used at once
+func NewMemory(count int, per time.Duration) *Memory {
+	return &Memory{
+		limit:   rate.Every(per / time.Duration(count)),
+		burst:   count,
+		idle:    per,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (m *Memory) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(m.limit, m.burst)}
+		m.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// Don't keep the token we'd have to wait for, the request is turned away
+		reservation.CancelAt(now)
+		return false, delay, nil
+	}
+	return true, 0, nil
+}
+
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.idle {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if now.Sub(b.lastSeen) >= m.idle {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewMemory(3, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	for range 3 {
+		ok, _, err := limiter.Allow(ctx, "ip:1.2.3.4")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, retryAfter, _ := limiter.Allow(ctx, "ip:1.2.3.4")
+	assert.False(t, ok)
+	assert.Equal(t, 20*time.Second, retryAfter)
+
+	// Someone else has their own bucket
+	ok, _, _ = limiter.Allow(ctx, "ip:5.6.7.8")
+	assert.True(t, ok)
+
+	// One token comes back every 20 seconds
+	now = now.Add(20 * time.Second)
+	ok, _, _ = limiter.Allow(ctx, "ip:1.2.3.4")
+	assert.True(t, ok)
+	ok, _, _ = limiter.Allow(ctx, "ip:1.2.3.4")
+	assert.False(t, ok)
+
+	// Buckets that would be full again are forgotten
+	now = now.Add(2 * time.Minute)
+	limiter.Allow(ctx, "ip:9.9.9.9")
+	assert.Len(t, limiter.buckets, 1)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	"github.com/google/uuid"
+)
+
+// Fills in who and when and writes the event in the caller's transaction, so it only exists if the change does.
+// Without a user in the context (the admin CLI) the actor stays empty
+func recordAudit(ctx context.Context, repo store.TournamentRepository, tx store.Tx, event bracket.AuditEvent) error {
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now().UTC()
+	if user := middleware.GetAuthenticatedUser(ctx); user != nil {
+		event.ActorID = &user.ID
+		event.ActorName = user.Username
+	} else if userID, ok := middleware.GetUserIDFromContext(ctx); ok {
+		event.ActorID = &userID
+	}
+	return repo.CreateAuditEvent(ctx, tx, &event)
+}
+
+// Every match one advance touched, as first read and as last written, in the order they were reached
+type matchChanges struct {
+	order  []uuid.UUID
+	before map[uuid.UUID]bracket.Match
+	after  map[uuid.UUID]bracket.Match
+}
+
+func newMatchChanges() *matchChanges {
+	return &matchChanges{before: make(map[uuid.UUID]bracket.Match), after: make(map[uuid.UUID]bracket.Match)}
+}
+
+func (c *matchChanges) read(m *bracket.Match) {
+	if _, ok := c.before[m.ID]; !ok {
+		c.before[m.ID] = *m
+		c.order = append(c.order, m.ID)
+	}
+}
+
+func (c *matchChanges) wrote(m *bracket.Match) {
+	c.after[m.ID] = *m
+}
+
+func (c *matchChanges) snapshots() (before, after bracket.AuditSnapshot) {
+	for _, id := range c.order {
+		if m, ok := c.after[id]; ok {
+			before.Matches = append(before.Matches, c.before[id])
+			after.Matches = append(after.Matches, m)
+		}
+	}
+	return before, after
+}
+
+type HistoryData struct {
+	Tournament *bracket.Tournament
+	// Newest first
+	Events  []bracket.AuditEvent
+	Entries map[uuid.UUID]bracket.Entry
+}
+
+func (s *TournamentService) GetHistory(ctx context.Context, id string) (*HistoryData, error) {
+	tournament, err := s.getVisibleTournament(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.GetAuditLog(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.store.GetEntries(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &HistoryData{
+		Tournament: tournament,
+		Events:     make([]bracket.AuditEvent, 0, len(events)),
+		Entries:    make(map[uuid.UUID]bracket.Entry, len(entries)),
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		data.Events = append(data.Events, events[i])
+	}
+	for _, e := range entries {
+		data.Entries[e.ID] = e
+	}
+	return data, nil
+}
+
+// Oldest first. Unlike GetHistory this works for deleted tournaments too
+func (s *TournamentService) GetAuditLog(ctx context.Context, id string) ([]bracket.AuditEvent, error) {
+	return s.store.GetAuditEvents(ctx, id)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	tournaments := NewTournamentService(tournamentStore)
+	matches := NewMatchService(tournamentStore)
+
+	user := &users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "Mockinator"}
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, user.ID)
+	ctx = context.WithValue(ctx, users.UserKey, user)
+
+	// Three entries, so seed 1 gets a bye into the final
+	tournamentID, err := tournaments.CreateTournament(ctx, "Audited", bracket.SingleElimination, []EntryInput{{Name: "A"}, {Name: "B"}, {Name: "C"}})
+	require.NoError(t, err)
+	data, err := tournaments.GetTournamentData(ctx, tournamentID.String())
+	require.NoError(t, err)
+	playable := data.Matches[1]
+	require.NotNil(t, playable.Entry1ID)
+	require.NotNil(t, playable.Entry2ID)
+
+	_, err = matches.AdvanceWinner(ctx, playable.ID, *playable.Entry2ID)
+	require.NoError(t, err)
+	_, err = tournaments.UpdateEntryMetadata(ctx, playable.Entry2ID.String(), "C (renamed)", bracket.EntryMetadata{})
+	require.NoError(t, err)
+
+	// A rejected advance leaves nothing behind
+	_, err = matches.AdvanceWinner(ctx, playable.ID, uuid.New())
+	require.Error(t, err)
+
+	history, err := tournaments.GetHistory(ctx, tournamentID.String())
+	require.NoError(t, err)
+	require.Len(t, history.Events, 3)
+	assert.Equal(t, bracket.AuditEntryUpdated, history.Events[0].Action)
+	assert.Equal(t, bracket.AuditMatchDecided, history.Events[1].Action)
+	assert.Equal(t, bracket.AuditTournamentCreated, history.Events[2].Action)
+
+	decided := history.Events[1]
+	assert.Equal(t, &user.ID, decided.ActorID)
+	assert.Equal(t, "Mockinator", decided.ActorName)
+	assert.Equal(t, &playable.ID, decided.MatchID)
+	// The decided match and the final it fed into
+	require.Len(t, decided.Before.Matches, 2)
+	require.Len(t, decided.After.Matches, 2)
+	assert.Equal(t, bracket.MatchPending, decided.Before.Matches[0].Status)
+	assert.True(t, decided.After.Match(playable.ID).IsWinner(2))
+	final := decided.After.Matches[1]
+	assert.Equal(t, playable.WinnerNextMatchID, &final.ID)
+	assert.Equal(t, playable.Entry2ID, final.Entry2ID)
+
+	renamed := history.Events[0]
+	assert.Equal(t, "C", renamed.Before.Entry.Name)
+	assert.Equal(t, "C (renamed)", renamed.After.Entry.Name)
+
+	require.NoError(t, tournaments.DeleteTournament(ctx, tournamentID.String()))
+	_, err = tournaments.GetHistory(ctx, tournamentID.String())
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	events, err := tournaments.GetAuditLog(ctx, tournamentID.String())
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	deleted := events[3]
+	assert.Equal(t, bracket.AuditTournamentDeleted, deleted.Action)
+	assert.Len(t, deleted.Before.Matches, len(data.Matches))
+}
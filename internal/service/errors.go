@@ -0,0 +1,57 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Errors handlers can show to the user. httputil.Error maps each one to a status code,
+// anything else coming out of a service is treated as a 500
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrForbidden       = errors.New("you don't have access to this")
+	ErrUnauthenticated = errors.New("you need to log in first")
+
+	ErrMatchOutOfOrder      = errors.New("matches must be decided in order")
+	ErrWinnerNotInMatch     = errors.New("winner is not part of this match")
+	ErrTournamentNotStarted = errors.New("tournament hasn't started yet")
+	ErrTournamentFinished   = errors.New("tournament is already finished")
+	ErrTournamentStarted    = errors.New("tournament has already started")
+	ErrMatchDecided         = errors.New("match has already been decided")
+	ErrFormatLocked         = errors.New("the format can't change once a match has been decided")
+)
+
+// Says what couldn't be found. Matches ErrNotFound with errors.Is and still unwraps to the store's sql.ErrNoRows
+type NotFoundError struct {
+	What string
+	Err  error
+}
+
+func (e *NotFoundError) Error() string {
+	return e.What + " not found"
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// Bad input that got past the handler, Message is shown as is
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Turns the store's "no rows" into a NotFoundError, everything else passes through
+func notFound(err error, what string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return &NotFoundError{What: what, Err: err}
+	}
+	return err
+}
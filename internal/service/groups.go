@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	"github.com/AdamBeresnev/op-rating-app/internal/utils"
+	"github.com/google/uuid"
+)
+
+// Round robin groups played before the bracket. Groups is 0 for a plain bracket,
+// otherwise the top Advance entries of every group make it into the playoff
+type GroupStage struct {
+	Groups  int
+	Advance int
+}
+
+// Same as CreateTournament with a group stage in front, tournamentType is the format of the playoff.
+// The playoff bracket is only generated once every group match is decided
+func (s *TournamentService) CreateGroupTournament(ctx context.Context, name string, tournamentType bracket.TournamentType, stage GroupStage, entryInputs []EntryInput) (uuid.UUID, error) {
+	stage, err := validateGroupStage(stage, len(entryInputs))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return s.createTournament(ctx, bracket.Tournament{
+		Name:         name,
+		Status:       bracket.TournamentStarted,
+		Type:         tournamentType,
+		GroupCount:   stage.Groups,
+		GroupAdvance: stage.Advance,
+	}, entryInputs)
+}
+
+func validateGroupStage(stage GroupStage, entryCount int) (GroupStage, error) {
+	if stage.Groups == 0 {
+		return GroupStage{}, nil
+	}
+	// Snake seeding makes the groups differ by one at most
+	smallest := 0
+	if stage.Groups > 0 {
+		smallest = entryCount / stage.Groups
+	}
+	switch {
+	case stage.Groups < 0:
+		return stage, &ValidationError{Message: "The number of groups can't be negative"}
+	case smallest < 2:
+		return stage, &ValidationError{Message: fmt.Sprintf("%d groups need at least %d entries, every group needs two", stage.Groups, stage.Groups*2)}
+	case stage.Advance < 1 || stage.Advance >= smallest:
+		return stage, &ValidationError{Message: fmt.Sprintf("Between 1 and %d entries per group can advance", smallest-1)}
+	case stage.Groups*stage.Advance < 2:
+		return stage, &ValidationError{Message: "At least two entries have to advance to the playoff"}
+	}
+	return stage, nil
+}
+
+// Splits entries into groups by snake seeding, 1-2-3-4 across and then 8-7-6-5 back, so every group gets a similar spread of seeds.
+// Entries have to be in seed order
+func snakeGroups(entries []bracket.Entry, groupCount int) [][]bracket.Entry {
+	groups := make([][]bracket.Entry, groupCount)
+	for i, e := range entries {
+		g := i % groupCount
+		if (i/groupCount)%2 == 1 {
+			g = groupCount - 1 - g
+		}
+		groups[g] = append(groups[g], e)
+	}
+	return groups
+}
+
+// Every pairing of n entries exactly once, spread over rounds with the circle method so nobody plays twice in a round.
+// With an odd count one entry sits out each round
+func roundRobin(n int) [][][2]int {
+	size := n
+	if size%2 == 1 {
+		size++
+	}
+	circle := make([]int, size)
+	for i := range circle {
+		circle[i] = i
+	}
+
+	var rounds [][][2]int
+	for r := 0; r < size-1; r++ {
+		var pairs [][2]int
+		for i := 0; i < size/2; i++ {
+			a, b := circle[i], circle[size-1-i]
+			if a >= n || b >= n {
+				continue
+			}
+			pairs = append(pairs, [2]int{min(a, b), max(a, b)})
+		}
+		rounds = append(rounds, pairs)
+		// The first one stays put, the rest turn one step
+		circle = append([]int{circle[0], circle[size-1]}, circle[1:size-1]...)
+	}
+	return rounds
+}
+
+// Group matches for the whole stage. Match order runs across groups within a round, so the strict ordering
+// plays round 1 of every group before anyone's round 2
+func generateGroupStage(tournamentID uuid.UUID, entries []bracket.Entry, groupCount int) []bracket.Match {
+	var matches []bracket.Match
+	order := make(map[int]int)
+	for g, group := range snakeGroups(entries, groupCount) {
+		for r, pairs := range roundRobin(len(group)) {
+			for _, pair := range pairs {
+				order[r+1]++
+				matches = append(matches, bracket.Match{
+					ID:           uuid.New(),
+					TournamentID: tournamentID,
+					BracketSide:  bracket.GroupSide,
+					GroupNumber:  utils.Ptr(g + 1),
+					RoundNumber:  r + 1,
+					MatchOrder:   order[r+1],
+					Entry1ID:     &group[pair[0]].ID,
+					Entry2ID:     &group[pair[1]].ID,
+					Status:       bracket.MatchPending,
+				})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].RoundNumber != matches[j].RoundNumber {
+			return matches[i].RoundNumber < matches[j].RoundNumber
+		}
+		return matches[i].MatchOrder < matches[j].MatchOrder
+	})
+	return matches
+}
+
+type Group struct {
+	Number int
+	// Best first, same order as the results page
+	Standings []EntryResult
+	Matches   []bracket.Match
+}
+
+// Who plays in which group comes from the group matches, every entry in a group plays everyone else there
+func groupTables(entries []bracket.Entry, matches []bracket.Match) []Group {
+	byID := make(map[uuid.UUID]bracket.Entry, len(entries))
+	for _, e := range entries {
+		byID[e.ID] = e
+	}
+
+	var groups []Group
+	index := make(map[int]int)
+	members := make(map[int][]bracket.Entry)
+	seen := make(map[uuid.UUID]bool)
+	for _, m := range matches {
+		if m.BracketSide != bracket.GroupSide || m.GroupNumber == nil {
+			continue
+		}
+		n := *m.GroupNumber
+		if _, ok := index[n]; !ok {
+			index[n] = len(groups)
+			groups = append(groups, Group{Number: n})
+		}
+		groups[index[n]].Matches = append(groups[index[n]].Matches, m)
+		for _, id := range []*uuid.UUID{m.Entry1ID, m.Entry2ID} {
+			if e, ok := byID[utils.OrZero(id)]; ok && !seen[e.ID] {
+				seen[e.ID] = true
+				members[n] = append(members[n], e)
+			}
+		}
+	}
+
+	for i := range groups {
+		groups[i].Standings = calcEntryResults(members[groups[i].Number], groups[i].Matches)
+	}
+	sort.Slice(groups, func(a, b int) bool { return groups[a].Number < groups[b].Number })
+	return groups
+}
+
+// Playoff seeds from the final standings: every group winner first, then the runners-up and so on, group A first within a place.
+// Entries from the same group are then kept apart in round 1 where another entry of the same place can take the spot
+func playoffSeeding(groups []Group, advance int) []bracket.Entry {
+	type qualifier struct {
+		entry bracket.Entry
+		group int
+		place int
+	}
+	var seeded []qualifier
+	for place := 0; place < advance; place++ {
+		for _, g := range groups {
+			if place < len(g.Standings) && !g.Standings[place].Entry.IsDisqualified() {
+				seeded = append(seeded, qualifier{g.Standings[place].Entry, g.Number, place})
+			}
+		}
+	}
+
+	partner := make(map[int]int)
+	for _, pair := range generateRound1Pairs(calcBracketSize(len(seeded))) {
+		if pair[0] < len(seeded) && pair[1] < len(seeded) {
+			partner[pair[0]] = pair[1]
+			partner[pair[1]] = pair[0]
+		}
+	}
+	clash := func(i int) bool {
+		p, ok := partner[i]
+		return ok && seeded[i].group == seeded[p].group
+	}
+	for i := range seeded {
+		if !clash(i) {
+			continue
+		}
+		// The lower seed of the pair moves, the better one keeps its spot
+		low := max(i, partner[i])
+		for j := range seeded {
+			if j == low || j == partner[low] || seeded[j].place != seeded[low].place {
+				continue
+			}
+			seeded[low], seeded[j] = seeded[j], seeded[low]
+			if !clash(low) && !clash(j) {
+				break
+			}
+			seeded[low], seeded[j] = seeded[j], seeded[low]
+		}
+	}
+
+	entries := make([]bracket.Entry, len(seeded))
+	for i, q := range seeded {
+		entries[i] = q.entry
+	}
+	return entries
+}
+
+// The playoff is only generated after the last group match, rewound group stages leave it out
+func withoutEarlyPlayoff(matches []bracket.Match) []bracket.Match {
+	for _, m := range matches {
+		if m.BracketSide == bracket.GroupSide && m.Status != bracket.MatchFinished {
+			var groups []bracket.Match
+			for _, m := range matches {
+				if m.BracketSide == bracket.GroupSide {
+					groups = append(groups, m)
+				}
+			}
+			return groups
+		}
+	}
+	return matches
+}
+
+// Runs after the last group match is decided and builds the playoff from the standings inside the same transaction.
+// If too few entries are left to play one (disqualifications) the tournament just ends
+func (s *MatchService) startPlayoff(ctx context.Context, tx store.Tx, run *advance) error {
+	matches, err := s.store.GetMatchesTx(ctx, tx, run.tournament.ID.String())
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if m.BracketSide != bracket.GroupSide || m.Status != bracket.MatchFinished {
+			return nil
+		}
+	}
+
+	qualifiers := playoffSeeding(groupTables(run.entries, matches), run.tournament.GroupAdvance)
+	playoff := s.brackets.buildElimination(run.tournament, qualifiers)
+	if len(playoff) == 0 {
+		return s.store.UpdateTournamentStatusTx(ctx, tx, run.tournament.ID.String(), bracket.TournamentCompleted)
+	}
+	if err := s.store.CreateMatches(ctx, tx, playoff); err != nil {
+		return err
+	}
+	run.playoff = playoff
+	return nil
+}
+
+// Its own audit event after the one for the decision that triggered it, the history reads in the order things happened
+func (s *MatchService) recordPlayoff(ctx context.Context, tx store.Tx, run *advance) error {
+	if run.playoff == nil {
+		return nil
+	}
+	if err := recordAudit(ctx, s.store, tx, bracket.AuditEvent{
+		TournamentID: run.tournament.ID,
+		Action:       bracket.AuditPlayoffStarted,
+		After:        bracket.AuditSnapshot{Matches: run.playoff},
+	}); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundRobin(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 5} {
+		rounds := roundRobin(n)
+		seen := make(map[[2]int]bool)
+		for _, pairs := range rounds {
+			busy := make(map[int]bool)
+			for _, p := range pairs {
+				assert.False(t, busy[p[0]] || busy[p[1]], "someone plays twice in a round")
+				busy[p[0]], busy[p[1]] = true, true
+				assert.False(t, seen[p], "pairing repeated")
+				seen[p] = true
+			}
+		}
+		assert.Len(t, seen, n*(n-1)/2, "n=%d", n)
+	}
+}
+
+func TestSnakeGroups(t *testing.T) {
+	var entries []bracket.Entry
+	for i := 1; i <= 7; i++ {
+		entries = append(entries, bracket.Entry{ID: uuid.New(), Seed: i})
+	}
+	seeds := func(group []bracket.Entry) []int {
+		var s []int
+		for _, e := range group {
+			s = append(s, e.Seed)
+		}
+		return s
+	}
+
+	groups := snakeGroups(entries, 3)
+	assert.Equal(t, []int{1, 6, 7}, seeds(groups[0]))
+	assert.Equal(t, []int{2, 5}, seeds(groups[1]))
+	assert.Equal(t, []int{3, 4}, seeds(groups[2]))
+}
+
+func TestPlayoffSeeding_KeepsGroupsApart(t *testing.T) {
+	// Three groups of two qualifiers, plain tiers would pair C's winner with C's runner-up
+	var groups []Group
+	for g := 1; g <= 3; g++ {
+		groups = append(groups, Group{Number: g, Standings: []EntryResult{
+			{Entry: bracket.Entry{ID: uuid.New(), Name: string(rune('A'+g-1)) + "1"}},
+			{Entry: bracket.Entry{ID: uuid.New(), Name: string(rune('A'+g-1)) + "2"}},
+		}})
+	}
+
+	seeded := playoffSeeding(groups, 2)
+	require.Len(t, seeded, 6)
+	var names []string
+	for _, e := range seeded {
+		names = append(names, e.Name)
+	}
+	assert.Equal(t, []string{"A1", "B1", "C1"}, names[:3])
+	for _, pair := range generateRound1Pairs(8) {
+		if pair[1] < len(names) {
+			assert.NotEqual(t, names[pair[0]][0], names[pair[1]][0], "%v", pair)
+		}
+	}
+}
+
+func TestGroupStage(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	tournaments := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
+	owner := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+
+	var inputs []EntryInput
+	for _, name := range []string{"S1", "S2", "S3", "S4", "S5", "S6", "S7", "S8"} {
+		inputs = append(inputs, EntryInput{Name: name})
+	}
+
+	var invalid *ValidationError
+	_, err := tournaments.CreateGroupTournament(owner, "Too many groups", bracket.SingleElimination, GroupStage{Groups: 5, Advance: 1}, inputs)
+	assert.ErrorAs(t, err, &invalid)
+	_, err = tournaments.CreateGroupTournament(owner, "Everyone advances", bracket.SingleElimination, GroupStage{Groups: 2, Advance: 4}, inputs)
+	assert.ErrorAs(t, err, &invalid)
+
+	id, err := tournaments.CreateGroupTournament(owner, "Groups", bracket.SingleElimination, GroupStage{Groups: 2, Advance: 2}, inputs)
+	require.NoError(t, err)
+	data, err := tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	require.Len(t, data.Matches, 12)
+	require.Len(t, data.Groups, 2)
+
+	seeds := make(map[uuid.UUID]int)
+	for _, e := range data.Entries {
+		seeds[e.ID] = e.Seed
+	}
+	members := func(g Group) []int {
+		var s []int
+		for _, r := range g.Standings {
+			s = append(s, r.Entry.Seed)
+		}
+		return s
+	}
+	assert.ElementsMatch(t, []int{1, 4, 5, 8}, members(data.Groups[0]))
+	assert.ElementsMatch(t, []int{2, 3, 6, 7}, members(data.Groups[1]))
+
+	// The better seed wins every group match
+	for _, m := range data.Matches {
+		winner := *m.Entry1ID
+		if seeds[*m.Entry2ID] < seeds[winner] {
+			winner = *m.Entry2ID
+		}
+		_, err := matchService.AdvanceWinner(owner, m.ID, winner)
+		require.NoError(t, err)
+	}
+
+	data, err = tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.TournamentStarted, data.Tournament.Status)
+	assert.Equal(t, []int{1, 4, 5, 8}, members(data.Groups[0]))
+	assert.Equal(t, 3, data.Groups[0].Standings[0].Wins)
+
+	var playoff []bracket.Match
+	for _, m := range data.Matches {
+		if m.BracketSide != bracket.GroupSide {
+			playoff = append(playoff, m)
+		}
+	}
+	require.Len(t, playoff, 3)
+	// Group winners meet the other group's runner-up
+	var firstRound [][2]int
+	for _, m := range playoff {
+		if m.RoundNumber == 1 {
+			firstRound = append(firstRound, [2]int{seeds[*m.Entry1ID], seeds[*m.Entry2ID]})
+		}
+	}
+	assert.ElementsMatch(t, [][2]int{{1, 3}, {2, 4}}, firstRound)
+
+	events, err := tournamentStore.GetAuditEvents(owner, id.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.AuditPlayoffStarted, events[len(events)-1].Action)
+}
+
+func TestGroupStage_Disqualification(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	tournaments := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
+	owner := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+
+	id, err := tournaments.CreateGroupTournament(owner, "Groups", bracket.SingleElimination, GroupStage{Groups: 1, Advance: 2}, []EntryInput{
+		{Name: "A"}, {Name: "B"}, {Name: "C"},
+	})
+	require.NoError(t, err)
+	data, err := tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	require.Len(t, data.Matches, 3)
+
+	var c bracket.Entry
+	for _, e := range data.Entries {
+		if e.Name == "C" {
+			c = e
+		}
+	}
+	// C forfeits both of its group matches, the one left to play decides the group
+	_, err = matchService.DisqualifyEntry(owner, c.ID.String())
+	require.NoError(t, err)
+	data, err = tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	var open *bracket.Match
+	for i, m := range data.Matches {
+		if m.Status != bracket.MatchFinished {
+			require.Nil(t, open)
+			open = &data.Matches[i]
+		}
+	}
+	require.NotNil(t, open)
+
+	_, err = matchService.AdvanceWinner(owner, open.ID, *open.Entry1ID)
+	require.NoError(t, err)
+	data, err = tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	require.Len(t, data.Matches, 4)
+	var final bracket.Match
+	for _, m := range data.Matches {
+		if m.BracketSide == bracket.WinnersSide {
+			final = m
+		}
+	}
+	require.NotNil(t, final.Entry1ID)
+	require.NotNil(t, final.Entry2ID)
+	assert.NotEqual(t, c.ID, *final.Entry1ID)
+	assert.NotEqual(t, c.ID, *final.Entry2ID)
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/linkcheck"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	"github.com/google/uuid"
+)
+
+type LinkCheckService struct {
+	store   store.TournamentRepository
+	checker *linkcheck.Checker
+
+	// Checks started from the tournament page outlive the request, Close cancels them and the periodic job and waits
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+	mu      sync.Mutex
+	// Tournaments with a check already going, clicking the button twice doesn't start a second one
+	checking map[uuid.UUID]bool
+}
+
+func NewLinkCheckService(store store.TournamentRepository, checker *linkcheck.Checker) *LinkCheckService {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LinkCheckService{store: store, checker: checker, ctx: ctx, cancel: cancel, checking: make(map[uuid.UUID]bool)}
+}
+
+// Owner only. Starts checking every pasted link in the tournament and returns right away,
+// a slow host can take the full timeout per entry
+func (s *LinkCheckService) CheckTournament(ctx context.Context, tournamentID string) error {
+	tournament, err := getManagedTournament(ctx, s.store, tournamentID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil || s.checking[tournament.ID] {
+		return nil
+	}
+	s.checking[tournament.ID] = true
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		broken, err := s.checkTournament(s.ctx, tournament.ID.String())
+		if err != nil {
+			slog.Error("link check failed", "tournament", tournament.ID, "error", err)
+		} else {
+			slog.Info("link check finished", "tournament", tournament.ID, "broken", broken)
+		}
+		s.mu.Lock()
+		delete(s.checking, tournament.ID)
+		s.mu.Unlock()
+	}()
+	return nil
+}
+
+// Runs RunPeriodically in the background until Close
+func (s *LinkCheckService) StartPeriodic(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil {
+		return
+	}
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		s.RunPeriodically(s.ctx, interval)
+	}()
+}
+
+// Stops the checks still running and waits for them, so nothing writes to the database after it's closed
+func (s *LinkCheckService) Close() {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+	s.running.Wait()
+}
+
+// Returns how many links turned out to be broken
+func (s *LinkCheckService) checkTournament(ctx context.Context, tournamentID string) (int, error) {
+	entries, err := s.store.GetEntries(ctx, tournamentID)
+	if err != nil {
+		return 0, err
+	}
+	return s.checkEntries(ctx, entries)
+}
+
+// Checks all tournaments that are still going, this is what the background job runs
+func (s *LinkCheckService) CheckActiveTournaments(ctx context.Context) (int, error) {
+	entries, err := s.store.GetActiveLinkedEntries(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return s.checkEntries(ctx, entries)
+}
+
+func (s *LinkCheckService) checkEntries(ctx context.Context, entries []bracket.Entry) (int, error) {
+	broken := 0
+	for _, e := range entries {
+		// Uploads live on our own disk, nothing to check there
+		if e.UploadID != nil || e.EmbedLink == nil {
+			continue
+		}
+
+		status := s.checker.Check(ctx, *e.EmbedLink)
+		if status == bracket.LinkBroken {
+			broken++
+		}
+		if err := s.store.UpdateEntryLinkStatus(ctx, e.ID, status, time.Now().UTC()); err != nil {
+			return broken, err
+		}
+	}
+	return broken, nil
+}
+
+// Blocks and re-checks active tournaments every interval until the context is cancelled
+func (s *LinkCheckService) RunPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			broken, err := s.CheckActiveTournaments(ctx)
+			if err != nil {
+				slog.Error("periodic link check failed", "error", err)
+				continue
+			}
+			slog.Info("periodic link check finished", "broken", broken)
+		}
+	}
+}
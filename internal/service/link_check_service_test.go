@@ -0,0 +1,98 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/linkcheck"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTournamentLinks(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dead.webm" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	bracketService := NewTournamentService(tournamentStore)
+	linkCheckService := NewLinkCheckService(tournamentStore, linkcheck.NewChecker(server.Client()))
+
+	ctx := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+
+	entryInputs := []EntryInput{
+		{Name: "Alive", EmbedLink: server.URL + "/alive.webm"},
+		{Name: "Dead", EmbedLink: server.URL + "/dead.webm"},
+		{Name: "No link"},
+	}
+	tournamentID, err := bracketService.CreateTournament(ctx, "Link Check", bracket.SingleElimination, entryInputs)
+	require.NoError(t, err)
+
+	require.NoError(t, linkCheckService.CheckTournament(ctx, tournamentID.String()))
+	// The check runs in the background, wait for it without cancelling
+	linkCheckService.running.Wait()
+
+	entries, err := tournamentStore.GetEntries(ctx, tournamentID.String())
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	require.NotNil(t, entries[0].LinkStatus)
+	assert.Equal(t, bracket.LinkOK, *entries[0].LinkStatus)
+	assert.NotNil(t, entries[0].LinkCheckedAt)
+
+	assert.True(t, entries[1].IsLinkBroken())
+	assert.Nil(t, entries[2].LinkStatus)
+
+	// The background job should pick up the same tournament since it's still running
+	activeEntries, err := tournamentStore.GetActiveLinkedEntries(ctx)
+	require.NoError(t, err)
+	assert.Len(t, activeEntries, 2)
+}
+
+func TestCheckTournamentLinks_NotOwner(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	bracketService := NewTournamentService(tournamentStore)
+	linkCheckService := NewLinkCheckService(tournamentStore, linkcheck.NewChecker(nil))
+	defer linkCheckService.Close()
+
+	owner := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+	tournamentID, err := bracketService.CreateTournament(owner, "Link Check", bracket.SingleElimination, []EntryInput{
+		{Name: "A", EmbedLink: "http://127.0.0.1/a.webm"},
+		{Name: "B"},
+	})
+	require.NoError(t, err)
+
+	stranger := asUser(&users.User{ID: uuid.New(), Username: "stranger"})
+	assert.ErrorIs(t, linkCheckService.CheckTournament(stranger, tournamentID.String()), ErrForbidden)
+
+	var notFound *NotFoundError
+	assert.ErrorAs(t, linkCheckService.CheckTournament(owner, uuid.NewString()), &notFound)
+}
+
+func TestLinkCheckService_Close(t *testing.T) {
+	linkCheckService := NewLinkCheckService(store.NewMemoryTournamentStore(), linkcheck.NewChecker(nil))
+	linkCheckService.StartPeriodic(time.Hour)
+
+	// Close has to wait for the periodic job to stop, and nothing starts after it
+	linkCheckService.Close()
+	linkCheckService.StartPeriodic(time.Hour)
+	linkCheckService.running.Wait()
+}
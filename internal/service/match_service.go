@@ -3,23 +3,26 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/metrics"
 	"github.com/AdamBeresnev/op-rating-app/internal/store"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 )
 
 type MatchService struct {
-	db    *sqlx.DB
-	store *store.TournamentStore
+	store store.TournamentRepository
+	// Builds the playoff once a group stage is done
+	brackets *TournamentService
 }
 
-func NewMatchService(db *sqlx.DB, store *store.TournamentStore) *MatchService {
-	return &MatchService{db: db, store: store}
+func NewMatchService(store store.TournamentRepository) *MatchService {
+	return &MatchService{store: store, brackets: NewTournamentService(store)}
 }
 
 type MatchData struct {
+	Tournament  *bracket.Tournament
 	Match       *bracket.Match
 	Entry1      *bracket.Entry
 	Entry2      *bracket.Entry
@@ -29,7 +32,14 @@ type MatchData struct {
 func (s *MatchService) GetMatchViewData(ctx context.Context, matchIDStr string) (*MatchData, error) {
 	match, err := s.store.GetMatch(ctx, matchIDStr)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "match")
+	}
+	tournament, err := s.store.GetTournament(ctx, match.TournamentID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tournament: %w", err)
+	}
+	if !canView(ctx, tournament) {
+		return nil, &NotFoundError{What: "match"}
 	}
 
 	var entry1, entry2 *bracket.Entry
@@ -48,57 +58,273 @@ func (s *MatchService) GetMatchViewData(ctx context.Context, matchIDStr string)
 		entry2 = e
 	}
 
-	nextMatch, err := s.store.GetNextPendingMatch(ctx, match.TournamentID.String())
+	matches, err := s.store.GetMatches(ctx, match.TournamentID.String())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get next match: %w", err)
-	}
-
-	var nextMatchID *uuid.UUID
-	if nextMatch != nil {
-		id := nextMatch.ID
-		nextMatchID = &id
+		return nil, fmt.Errorf("failed to get matches: %w", err)
 	}
 
 	return &MatchData{
+		Tournament:  tournament,
 		Match:       match,
 		Entry1:      entry1,
 		Entry2:      entry2,
-		NextMatchID: nextMatchID,
+		NextMatchID: nextMatchID(tournament.Ordering, matches),
 	}, nil
 }
 
 func (s *MatchService) AdvanceWinner(ctx context.Context, matchID uuid.UUID, winnerEntryID uuid.UUID) (uuid.UUID, error) {
-	tx, err := s.db.BeginTxx(ctx, nil)
+	match, err := s.store.GetMatch(ctx, matchID.String())
+	if err != nil {
+		return uuid.Nil, notFound(err, "match")
+	}
+	tournament, err := s.store.GetTournament(ctx, match.TournamentID.String())
 	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get tournament: %w", err)
+	}
+	if !canView(ctx, tournament) {
+		return uuid.Nil, &NotFoundError{What: "match"}
+	}
+	if err := checkOpenForVoting(tournament); err != nil {
 		return uuid.Nil, err
 	}
-	defer tx.Rollback()
+	if match.Status == bracket.MatchFinished {
+		return uuid.Nil, ErrMatchDecided
+	}
+	return s.decide(ctx, tournament, match, winnerEntryID, false)
+}
 
-	tournamentID, err := s.advanceWinnerRecursive(ctx, tx, matchID, winnerEntryID)
+// Owner override for when the normal order gets in the way. Skips the order check and counts as a forfeit,
+// both entries have to be in the match already so nothing feeding into it gets lost
+func (s *MatchService) ForceResult(ctx context.Context, matchID uuid.UUID, winnerEntryID uuid.UUID) (uuid.UUID, error) {
+	match, err := s.store.GetMatch(ctx, matchID.String())
+	if err != nil {
+		return uuid.Nil, notFound(err, "match")
+	}
+	tournament, err := getManagedTournament(ctx, s.store, match.TournamentID.String())
 	if err != nil {
 		return uuid.Nil, err
 	}
+	if err := checkOpenForVoting(tournament); err != nil {
+		return uuid.Nil, err
+	}
+	if match.Status == bracket.MatchFinished {
+		return uuid.Nil, ErrMatchDecided
+	}
+	if match.Entry1ID == nil || match.Entry2ID == nil {
+		return uuid.Nil, &ValidationError{Message: "Both entries have to be in the match before it can be decided"}
+	}
+	return s.decide(ctx, tournament, match, winnerEntryID, true)
+}
 
-	return tournamentID, tx.Commit()
+func checkOpenForVoting(tournament *bracket.Tournament) error {
+	switch tournament.Status {
+	case bracket.TournamentDraft:
+		return ErrTournamentNotStarted
+	case bracket.TournamentCompleted:
+		return ErrTournamentFinished
+	}
+	return nil
 }
 
-func (s *MatchService) advanceWinnerRecursive(ctx context.Context, tx *sqlx.Tx, matchID uuid.UUID, winnerEntryID uuid.UUID) (uuid.UUID, error) {
-	match, err := s.store.GetMatchTx(ctx, tx, matchID.String())
+func (s *MatchService) decide(ctx context.Context, tournament *bracket.Tournament, match *bracket.Match, winnerEntryID uuid.UUID, forced bool) (uuid.UUID, error) {
+	tx, err := s.store.BeginTx(ctx)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to get match: %w", err)
+		return uuid.Nil, err
 	}
+	defer tx.Rollback()
 
-	// Check for byes
-	if !match.IsBye {
-		hasPending, err := s.store.HasPreviousPendingMatchesTx(ctx, tx, match.TournamentID.String(), match.BracketSide, match.RoundNumber, match.MatchOrder)
+	// Byes the pick pushes an entry into share the timestamp, the replay treats them as one step
+	run, err := s.newAdvance(ctx, tx, tournament)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	// Checked again inside the transaction, two votes on the same match can arrive at once
+	current, err := s.store.GetMatchTx(ctx, tx, match.ID.String())
+	if err != nil {
+		return uuid.Nil, notFound(err, "match")
+	}
+	if current.Status == bracket.MatchFinished {
+		return uuid.Nil, ErrMatchDecided
+	}
+	// The order is checked against the matches inside the transaction, a vote landing in between can't slip past it
+	if !forced && !match.IsBye {
+		matches, err := s.store.GetMatchesTx(ctx, tx, tournament.ID.String())
 		if err != nil {
-			return uuid.Nil, fmt.Errorf("failed to check match order: %w", err)
+			return uuid.Nil, err
 		}
-		if hasPending {
-			return uuid.Nil, fmt.Errorf("matches must be decided in order")
+		if isBlocked(tournament.Ordering, matches, match) {
+			return uuid.Nil, ErrMatchOutOfOrder
 		}
 	}
 
+	tournamentID, err := s.advanceWinnerRecursive(ctx, tx, run, match.ID, winnerEntryID, forced)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	action := bracket.AuditMatchDecided
+	if forced {
+		action = bracket.AuditMatchForced
+	}
+	before, after := run.changes.snapshots()
+	if err := recordAudit(ctx, s.store, tx, bracket.AuditEvent{
+		TournamentID: tournamentID,
+		MatchID:      &match.ID,
+		Action:       action,
+		Before:       before,
+		After:        after,
+	}); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to record audit event: %w", err)
+	}
+	if err := s.recordPlayoff(ctx, tx, run); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	// Byes advanced along the way aren't counted, only the pick someone made
+	metrics.MatchesDecided.Inc()
+	return tournamentID, nil
+}
+
+// Takes an entry out of the tournament. Its current match goes to the opponent right away,
+// any match it gets into later is handed over as soon as the other entry arrives
+func (s *MatchService) DisqualifyEntry(ctx context.Context, entryID string) (uuid.UUID, error) {
+	entry, err := s.store.GetEntry(ctx, entryID)
+	if err != nil {
+		return uuid.Nil, notFound(err, "entry")
+	}
+	tournament, err := getManagedTournament(ctx, s.store, entry.TournamentID.String())
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err := checkOpenForVoting(tournament); err != nil {
+		return uuid.Nil, err
+	}
+	if entry.IsDisqualified() {
+		return tournament.ID, nil
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	run, err := s.newAdvance(ctx, tx, tournament)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	run.disqualify(entry.ID)
+	matches, err := s.store.GetMatchesTx(ctx, tx, tournament.ID.String())
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := s.store.DisqualifyEntryTx(ctx, tx, entryID, run.decidedAt); err != nil {
+		return uuid.Nil, notFound(err, "entry")
+	}
+	// In a bracket an entry is only ever waiting in one match at a time, in a group it forfeits everything left to play
+	for i := range matches {
+		if m := &matches[i]; !m.IsBye {
+			if opponent := run.walkover(m); opponent != nil {
+				if _, err := s.advanceWinnerRecursive(ctx, tx, run, m.ID, *opponent, true); err != nil {
+					return uuid.Nil, err
+				}
+				if m.BracketSide != bracket.GroupSide {
+					break
+				}
+			}
+		}
+	}
+
+	after := *entry
+	after.DisqualifiedAt = &run.decidedAt
+	before, afterMatches := run.changes.snapshots()
+	before.Entry = entry
+	afterMatches.Entry = &after
+	if err := recordAudit(ctx, s.store, tx, bracket.AuditEvent{
+		TournamentID: tournament.ID,
+		Action:       bracket.AuditEntryDisqualified,
+		Before:       before,
+		After:        afterMatches,
+	}); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to record audit event: %w", err)
+	}
+	if err := s.recordPlayoff(ctx, tx, run); err != nil {
+		return uuid.Nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	return tournament.ID, nil
+}
+
+func (s *MatchService) newAdvance(ctx context.Context, tx store.Tx, tournament *bracket.Tournament) (*advance, error) {
+	entries, err := s.store.GetEntriesTx(ctx, tx, tournament.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	run := &advance{
+		tournament:   tournament,
+		entries:      entries,
+		changes:      newMatchChanges(),
+		decidedAt:    time.Now().UTC(),
+		disqualified: make(map[uuid.UUID]bool),
+	}
+	for _, e := range entries {
+		if e.IsDisqualified() {
+			run.disqualified[e.ID] = true
+		}
+	}
+	return run, nil
+}
+
+// Everything one decision touches, the pick itself and whatever it cascades into
+type advance struct {
+	tournament *bracket.Tournament
+	entries    []bracket.Entry
+	changes    *matchChanges
+	decidedAt  time.Time
+	// Entries that forfeit any match they're in
+	disqualified map[uuid.UUID]bool
+	// Set when the decision finished the group stage
+	playoff []bracket.Match
+}
+
+func (a *advance) disqualify(entryID uuid.UUID) {
+	a.disqualified[entryID] = true
+	for i := range a.entries {
+		if a.entries[i].ID == entryID {
+			a.entries[i].DisqualifiedAt = &a.decidedAt
+		}
+	}
+}
+
+// Who wins a match by walkover, nil until both entries are in or if neither is disqualified.
+// Two disqualified entries still need someone to move on, they'll forfeit the next one anyway
+func (a *advance) walkover(m *bracket.Match) *uuid.UUID {
+	if m.Status == bracket.MatchFinished || m.Entry1ID == nil || m.Entry2ID == nil {
+		return nil
+	}
+	if a.disqualified[*m.Entry1ID] {
+		return m.Entry2ID
+	}
+	if a.disqualified[*m.Entry2ID] {
+		return m.Entry1ID
+	}
+	return nil
+}
+
+// The order is checked by the caller, byes and forfeits cascading from a pick don't wait for anything
+func (s *MatchService) advanceWinnerRecursive(ctx context.Context, tx store.Tx, run *advance, matchID uuid.UUID, winnerEntryID uuid.UUID, forfeit bool) (uuid.UUID, error) {
+	match, err := s.store.GetMatchTx(ctx, tx, matchID.String())
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get match: %w", err)
+	}
+	run.changes.read(match)
+
 	// Sanity check so we avoid deadlocks
 	if match.Entry1ID != nil && *match.Entry1ID == winnerEntryID {
 		slot := 1
@@ -107,14 +333,17 @@ func (s *MatchService) advanceWinnerRecursive(ctx context.Context, tx *sqlx.Tx,
 		slot := 2
 		match.WinnerSlot = &slot
 	} else {
-		return uuid.Nil, fmt.Errorf("winner is not part of this match")
+		return uuid.Nil, ErrWinnerNotInMatch
 	}
 
 	match.Status = bracket.MatchFinished
+	match.DecidedAt = &run.decidedAt
+	match.Forfeit = forfeit
 
 	if err := s.store.UpdateMatch(ctx, tx, match); err != nil {
 		return uuid.Nil, fmt.Errorf("failed to update match: %w", err)
 	}
+	run.changes.wrote(match)
 
 	// Propagate Winner
 	if match.WinnerNextMatchID != nil && match.WinnerNextSlot != nil {
@@ -122,6 +351,7 @@ func (s *MatchService) advanceWinnerRecursive(ctx context.Context, tx *sqlx.Tx,
 		if err != nil {
 			return uuid.Nil, fmt.Errorf("failed to get next match: %w", err)
 		}
+		run.changes.read(nextMatch)
 
 		switch *match.WinnerNextSlot {
 		case 1:
@@ -133,17 +363,32 @@ func (s *MatchService) advanceWinnerRecursive(ctx context.Context, tx *sqlx.Tx,
 		if err := s.store.UpdateMatch(ctx, tx, nextMatch); err != nil {
 			return uuid.Nil, fmt.Errorf("failed to update next match: %w", err)
 		}
+		run.changes.wrote(nextMatch)
 
 		// Recursive Auto-Advance if next match is a BYE
 		if nextMatch.IsBye {
-			if _, err := s.advanceWinnerRecursive(ctx, tx, nextMatch.ID, winnerEntryID); err != nil {
+			if _, err := s.advanceWinnerRecursive(ctx, tx, run, nextMatch.ID, winnerEntryID, false); err != nil {
 				return uuid.Nil, fmt.Errorf("failed to auto-advance bye match (winner path): %w", err)
 			}
+		} else if opponent := run.walkover(nextMatch); opponent != nil {
+			if _, err := s.advanceWinnerRecursive(ctx, tx, run, nextMatch.ID, *opponent, true); err != nil {
+				return uuid.Nil, fmt.Errorf("failed to forfeit match (winner path): %w", err)
+			}
+		}
+	} else if match.BracketSide == bracket.GroupSide {
+		if err := s.startPlayoff(ctx, tx, run); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to start the playoff: %w", err)
 		}
 	} else {
-		// If there is no next match, update tournament status to finished
-		if err := s.store.UpdateTournamentStatusTx(ctx, tx, match.TournamentID.String(), bracket.TournamentCompleted); err != nil {
-			return uuid.Nil, fmt.Errorf("failed to update tournament status: %w", err)
+		// No next match means a final, the tournament is done once nothing else is left either (e.g. the bronze match)
+		hasPending, err := s.store.HasPendingMatchesTx(ctx, tx, match.TournamentID.String())
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to check for pending matches: %w", err)
+		}
+		if !hasPending {
+			if err := s.store.UpdateTournamentStatusTx(ctx, tx, match.TournamentID.String(), bracket.TournamentCompleted); err != nil {
+				return uuid.Nil, fmt.Errorf("failed to update tournament status: %w", err)
+			}
 		}
 	}
 
@@ -161,6 +406,7 @@ func (s *MatchService) advanceWinnerRecursive(ctx context.Context, tx *sqlx.Tx,
 			if err != nil {
 				return uuid.Nil, fmt.Errorf("failed to get loser next match: %w", err)
 			}
+			run.changes.read(loserMatch)
 
 			switch *match.LoserNextSlot {
 			case 1:
@@ -172,12 +418,17 @@ func (s *MatchService) advanceWinnerRecursive(ctx context.Context, tx *sqlx.Tx,
 			if err := s.store.UpdateMatch(ctx, tx, loserMatch); err != nil {
 				return uuid.Nil, fmt.Errorf("failed to update loser next match: %w", err)
 			}
+			run.changes.wrote(loserMatch)
 
 			// Recursive Auto-Advance if loser match is a BYE
 			if loserMatch.IsBye {
-				if _, err := s.advanceWinnerRecursive(ctx, tx, loserMatch.ID, *loserID); err != nil {
+				if _, err := s.advanceWinnerRecursive(ctx, tx, run, loserMatch.ID, *loserID, false); err != nil {
 					return uuid.Nil, fmt.Errorf("failed to auto-advance bye match (loser path): %w", err)
 				}
+			} else if opponent := run.walkover(loserMatch); opponent != nil {
+				if _, err := s.advanceWinnerRecursive(ctx, tx, run, loserMatch.ID, *opponent, true); err != nil {
+					return uuid.Nil, fmt.Errorf("failed to forfeit match (loser path): %w", err)
+				}
 			}
 		}
 	}
@@ -7,6 +7,7 @@ import (
 	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
 	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
 	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,8 +18,8 @@ func TestAdvanceWinner(t *testing.T) {
 	defer db.Close()
 
 	tournamentStore := store.NewTournamentStore(db)
-	bracketService := NewTournamentService(db, tournamentStore)
-	matchService := NewMatchService(db, tournamentStore)
+	bracketService := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
 
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, middleware.UserIDKey, uuid.MustParse(middleware.SuperUserID))
@@ -93,8 +94,8 @@ func TestAdvanceWinner_EnforceOrder(t *testing.T) {
 	defer db.Close()
 
 	tournamentStore := store.NewTournamentStore(db)
-	bracketService := NewTournamentService(db, tournamentStore)
-	matchService := NewMatchService(db, tournamentStore)
+	bracketService := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
 
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, middleware.UserIDKey, uuid.MustParse(middleware.SuperUserID))
@@ -136,6 +137,48 @@ func TestAdvanceWinner_EnforceOrder(t *testing.T) {
 
 	_, err = matchService.AdvanceWinner(ctx, match3.ID, entry1.ID)
 	require.NoError(t, err)
+
+	// The final decided the tournament, nothing can be changed after that
+	_, err = matchService.AdvanceWinner(ctx, match3.ID, entry3.ID)
+	assert.ErrorIs(t, err, ErrTournamentFinished)
+}
+
+func TestAdvanceWinner_Errors(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	bracketService := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, uuid.MustParse(middleware.SuperUserID))
+	tournamentID, err := bracketService.CreateTournament(ctx, "Errors", bracket.SingleElimination, []EntryInput{
+		{Name: "Entry 1"}, {Name: "Entry 2"}, {Name: "Entry 3"}, {Name: "Entry 4"},
+	})
+	require.NoError(t, err)
+	matches, err := tournamentStore.GetMatches(ctx, tournamentID.String())
+	require.NoError(t, err)
+	entries, err := tournamentStore.GetEntries(ctx, tournamentID.String())
+	require.NoError(t, err)
+
+	_, err = matchService.AdvanceWinner(ctx, matches[1].ID, *matches[1].Entry1ID)
+	assert.ErrorIs(t, err, ErrMatchOutOfOrder)
+
+	_, err = matchService.AdvanceWinner(ctx, matches[0].ID, *matches[1].Entry1ID)
+	assert.ErrorIs(t, err, ErrWinnerNotInMatch)
+
+	_, err = matchService.AdvanceWinner(ctx, uuid.New(), entries[0].ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.EqualError(t, err, "match not found")
+
+	// A second vote doesn't flip the result
+	_, err = matchService.AdvanceWinner(ctx, matches[0].ID, *matches[0].Entry1ID)
+	require.NoError(t, err)
+	_, err = matchService.AdvanceWinner(ctx, matches[0].ID, *matches[0].Entry2ID)
+	assert.ErrorIs(t, err, ErrMatchDecided)
+	match, err := tournamentStore.GetMatch(ctx, matches[0].ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, 1, *match.WinnerSlot)
 }
 
 func TestDoubleEliminationAdvancement(t *testing.T) {
@@ -143,8 +186,8 @@ func TestDoubleEliminationAdvancement(t *testing.T) {
 	defer db.Close()
 
 	tournamentStore := store.NewTournamentStore(db)
-	bracketService := NewTournamentService(db, tournamentStore)
-	matchService := NewMatchService(db, tournamentStore)
+	bracketService := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
 
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, middleware.UserIDKey, uuid.MustParse(middleware.SuperUserID))
@@ -198,8 +241,8 @@ func TestDoubleElimination_ByeHandling(t *testing.T) {
 	defer db.Close()
 
 	tournamentStore := store.NewTournamentStore(db)
-	bracketService := NewTournamentService(db, tournamentStore)
-	matchService := NewMatchService(db, tournamentStore)
+	bracketService := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
 
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, middleware.UserIDKey, uuid.MustParse(middleware.SuperUserID))
@@ -257,3 +300,174 @@ func TestDoubleElimination_ByeHandling(t *testing.T) {
 	hasP5 := (lbR2M1.Entry1ID != nil && *lbR2M1.Entry1ID == p5ID) || (lbR2M1.Entry2ID != nil && *lbR2M1.Entry2ID == p5ID)
 	assert.True(t, hasP5, "P5 should have auto-advanced to LB R2 M1")
 }
+
+// Same flow against the in-memory store, a rejected advance must not leave anything half written
+func TestAdvanceWinner_MemoryStore(t *testing.T) {
+	tournamentStore := store.NewMemoryTournamentStore()
+	bracketService := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, uuid.MustParse(middleware.SuperUserID))
+
+	entryInputs := []EntryInput{
+		{Name: "Entry 1"}, {Name: "Entry 2"}, {Name: "Entry 3"}, {Name: "Entry 4"},
+	}
+	tournamentID, err := bracketService.CreateTournament(ctx, "Memory Tournament", bracket.SingleElimination, entryInputs)
+	require.NoError(t, err)
+
+	matches, err := tournamentStore.GetMatches(ctx, tournamentID.String())
+	require.NoError(t, err)
+	require.Len(t, matches, 3)
+	match1, match2 := matches[0], matches[1]
+
+	// Round 1 match 2 can't go before match 1
+	_, err = matchService.AdvanceWinner(ctx, match2.ID, *match2.Entry1ID)
+	require.Error(t, err)
+	unchanged, err := tournamentStore.GetMatch(ctx, match2.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.MatchPending, unchanged.Status)
+
+	_, err = matchService.AdvanceWinner(ctx, match1.ID, *match1.Entry1ID)
+	require.NoError(t, err)
+	_, err = matchService.AdvanceWinner(ctx, match2.ID, *match2.Entry1ID)
+	require.NoError(t, err)
+
+	data, err := bracketService.GetTournamentData(ctx, tournamentID.String())
+	require.NoError(t, err)
+	require.NotNil(t, data.NextMatchID)
+	final, err := matchService.GetMatchViewData(ctx, data.NextMatchID.String())
+	require.NoError(t, err)
+
+	_, err = matchService.AdvanceWinner(ctx, final.Match.ID, final.Entry1.ID)
+	require.NoError(t, err)
+
+	data, err = bracketService.GetTournamentData(ctx, tournamentID.String())
+	require.NoError(t, err)
+	assert.Nil(t, data.NextMatchID)
+	assert.Equal(t, bracket.TournamentCompleted, data.Tournament.Status)
+}
+
+func TestDisqualifyEntry(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	tournaments := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
+	owner := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+	stranger := asUser(&users.User{ID: uuid.New(), Username: "stranger"})
+
+	id, err := tournaments.CreateTournament(owner, "DQ", bracket.DoubleElimination, []EntryInput{
+		{Name: "Entry 1"}, {Name: "Entry 2"}, {Name: "Entry 3"}, {Name: "Entry 4"},
+	})
+	require.NoError(t, err)
+	entries, err := tournamentStore.GetEntries(owner, id.String())
+	require.NoError(t, err)
+	dq := entries[3]
+
+	_, err = matchService.DisqualifyEntry(stranger, dq.ID.String())
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	tournamentID, err := matchService.DisqualifyEntry(owner, dq.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, id, tournamentID)
+
+	find := func(side bracket.BracketSide, round, order int) *bracket.Match {
+		t.Helper()
+		matches, err := tournamentStore.GetMatches(owner, id.String())
+		require.NoError(t, err)
+		for i := range matches {
+			if m := &matches[i]; m.BracketSide == side && m.RoundNumber == round && m.MatchOrder == order {
+				return m
+			}
+		}
+		t.Fatalf("no %s R%d #%d", side, round, order)
+		return nil
+	}
+
+	// Its current match goes to the opponent right away
+	current := find(bracket.WinnersSide, 1, 1)
+	require.Equal(t, bracket.MatchFinished, current.Status)
+	assert.True(t, current.Forfeit)
+	assert.Equal(t, entries[0].ID, *winnerOf(current))
+
+	// It dropped into the losers bracket and hands that match over once the opponent shows up
+	other := find(bracket.WinnersSide, 1, 2)
+	_, err = matchService.AdvanceWinner(owner, other.ID, *other.Entry1ID)
+	require.NoError(t, err)
+	assert.False(t, find(bracket.WinnersSide, 1, 2).Forfeit)
+
+	losers := find(bracket.LosersSide, 1, 1)
+	require.Equal(t, bracket.MatchFinished, losers.Status)
+	assert.True(t, losers.Forfeit)
+	assert.Equal(t, *other.Entry2ID, *winnerOf(losers))
+
+	// Doing it twice changes nothing
+	_, err = matchService.DisqualifyEntry(owner, dq.ID.String())
+	require.NoError(t, err)
+	events, err := tournamentStore.GetAuditEvents(owner, id.String())
+	require.NoError(t, err)
+	var disqualified int
+	for _, e := range events {
+		if e.Action == bracket.AuditEntryDisqualified {
+			disqualified++
+		}
+	}
+	assert.Equal(t, 1, disqualified)
+
+	results, err := tournaments.GetResults(owner, id.String(), ResultsFilter{})
+	require.NoError(t, err)
+	last := results.Results[len(results.Results)-1]
+	assert.Equal(t, dq.ID, last.Entry.ID)
+	assert.Equal(t, 0, last.Losses)
+	assert.Equal(t, 2, last.ForfeitLosses)
+}
+
+func TestForceResult(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	tournaments := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
+	owner := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+	stranger := asUser(&users.User{ID: uuid.New(), Username: "stranger"})
+
+	id, err := tournaments.CreateTournament(owner, "Force", bracket.SingleElimination, []EntryInput{
+		{Name: "Entry 1"}, {Name: "Entry 2"}, {Name: "Entry 3"}, {Name: "Entry 4"},
+	})
+	require.NoError(t, err)
+	matches, err := tournamentStore.GetMatches(owner, id.String())
+	require.NoError(t, err)
+	second, final := matches[1], matches[2]
+	require.Equal(t, 2, second.MatchOrder)
+
+	// A normal vote has to wait for match 1, the override doesn't
+	_, err = matchService.AdvanceWinner(owner, second.ID, *second.Entry2ID)
+	assert.ErrorIs(t, err, ErrMatchOutOfOrder)
+	_, err = matchService.ForceResult(stranger, second.ID, *second.Entry2ID)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = matchService.ForceResult(owner, final.ID, *second.Entry2ID)
+	var invalid *ValidationError
+	assert.ErrorAs(t, err, &invalid)
+
+	_, err = matchService.ForceResult(owner, second.ID, *second.Entry2ID)
+	require.NoError(t, err)
+	forced, err := tournamentStore.GetMatch(owner, second.ID.String())
+	require.NoError(t, err)
+	assert.True(t, forced.Forfeit)
+	assert.Equal(t, *second.Entry2ID, *winnerOf(forced))
+
+	_, err = matchService.ForceResult(owner, second.ID, *second.Entry1ID)
+	assert.ErrorIs(t, err, ErrMatchDecided)
+}
+
+func winnerOf(m *bracket.Match) *uuid.UUID {
+	if m.WinnerSlot == nil {
+		return nil
+	}
+	if *m.WinnerSlot == 1 {
+		return m.Entry1ID
+	}
+	return m.Entry2ID
+}
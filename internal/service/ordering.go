@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/google/uuid"
+)
+
+type QueueData struct {
+	Tournament *bracket.Tournament
+	Entries    map[uuid.UUID]bracket.Entry
+	// Playable right now, first one up first
+	Matches []bracket.Match
+	// Still waiting for an entry or for earlier matches to be decided
+	Waiting int
+}
+
+func (s *TournamentService) GetMatchQueue(ctx context.Context, id string) (*QueueData, error) {
+	tournament, err := s.getVisibleTournament(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.loadTournamentData(ctx, tournament)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := &QueueData{
+		Tournament: tournament,
+		Entries:    make(map[uuid.UUID]bracket.Entry, len(data.Entries)),
+		Matches:    matchQueue(tournament.Ordering, data.Matches),
+	}
+	for _, e := range data.Entries {
+		queue.Entries[e.ID] = e
+	}
+	for _, m := range data.Matches {
+		if m.Status != bracket.MatchFinished && !m.IsBye {
+			queue.Waiting++
+		}
+	}
+	queue.Waiting -= len(queue.Matches)
+	return queue, nil
+}
+
+// Everything that can be voted on right now under the tournament's ordering, in the order it should be played
+func matchQueue(ordering bracket.Ordering, matches []bracket.Match) []bracket.Match {
+	var queue []bracket.Match
+	for i := range matches {
+		if m := &matches[i]; isPlayable(m) && !isBlocked(ordering, matches, m) {
+			queue = append(queue, *m)
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		return playsBefore(ordering, &queue[i], &queue[j])
+	})
+	return queue
+}
+
+// The match the bracket highlights and "Next Match" links to, nil once nothing is left to vote on
+func nextMatchID(ordering bracket.Ordering, matches []bracket.Match) *uuid.UUID {
+	queue := matchQueue(ordering, matches)
+	if len(queue) == 0 {
+		return nil
+	}
+	return &queue[0].ID
+}
+
+// Both entries are in and nobody picked a winner yet. Byes settle themselves
+func isPlayable(m *bracket.Match) bool {
+	return m.Status != bracket.MatchFinished && !m.IsBye && m.Entry1ID != nil && m.Entry2ID != nil
+}
+
+// Whether an open match has to be decided before this one. Open includes matches still waiting for an entry
+func isBlocked(ordering bracket.Ordering, matches []bracket.Match, match *bracket.Match) bool {
+	if ordering == bracket.OrderingFree {
+		return false
+	}
+	for i := range matches {
+		m := &matches[i]
+		if m.ID == match.ID || m.Status == bracket.MatchFinished {
+			continue
+		}
+		if ordering == bracket.OrderingInterleaved {
+			if interleavedSlot(m) < interleavedSlot(match) {
+				return true
+			}
+		} else if m.BracketSide == match.BracketSide &&
+			(m.RoundNumber < match.RoundNumber || (m.RoundNumber == match.RoundNumber && m.MatchOrder < match.MatchOrder)) {
+			return true
+		}
+	}
+	return false
+}
+
+func playsBefore(ordering bracket.Ordering, a, b *bracket.Match) bool {
+	if ordering == bracket.OrderingInterleaved {
+		if sa, sb := interleavedSlot(a), interleavedSlot(b); sa != sb {
+			return sa < sb
+		}
+		return a.MatchOrder < b.MatchOrder
+	}
+	if a.RoundNumber != b.RoundNumber {
+		return a.RoundNumber < b.RoundNumber
+	}
+	if ra, rb := sideRank(a.BracketSide), sideRank(b.BracketSide); ra != rb {
+		return ra < rb
+	}
+	return a.MatchOrder < b.MatchOrder
+}
+
+// Position of a round in the interleaved schedule: WB1, LB1, WB2, LB2, LB3, WB3, LB4, LB5, WB4 and so on.
+// Losers of winners round r drop into losers round 2(r-1), so every round comes after the ones feeding it.
+// Group rounds go before all of it, the finals side (grand final, bronze match) goes last
+func interleavedSlot(m *bracket.Match) int {
+	r := m.RoundNumber
+	switch m.BracketSide {
+	case bracket.GroupSide:
+		return r - 1<<20
+	case bracket.WinnersSide:
+		return 3 * (r - 1)
+	case bracket.LosersSide:
+		if r%2 == 0 {
+			return 3*r/2 + 1
+		}
+		return 3*(r-1)/2 + 2
+	default:
+		return 1<<20 + r
+	}
+}
+
+func sideRank(side bracket.BracketSide) int {
+	switch side {
+	case bracket.GroupSide:
+		return -1
+	case bracket.WinnersSide:
+		return 0
+	case bracket.LosersSide:
+		return 1
+	default:
+		return 2
+	}
+}
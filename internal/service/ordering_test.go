@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchQueue_Interleaved(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	match := func(side bracket.BracketSide, round, order int, status bracket.MatchStatus) bracket.Match {
+		return bracket.Match{ID: uuid.New(), BracketSide: side, RoundNumber: round, MatchOrder: order, Status: status, Entry1ID: &a, Entry2ID: &b}
+	}
+	wb1 := match(bracket.WinnersSide, 1, 1, bracket.MatchFinished)
+	lb1 := match(bracket.LosersSide, 1, 1, bracket.MatchPending)
+	wb2a := match(bracket.WinnersSide, 2, 1, bracket.MatchPending)
+	wb2b := match(bracket.WinnersSide, 2, 2, bracket.MatchPending)
+	matches := []bracket.Match{wb2b, wb2a, lb1, wb1}
+
+	// Strict ordering looks at each side on its own, interleaved lets the losers round catch up first
+	assert.Equal(t, []uuid.UUID{lb1.ID, wb2a.ID}, ids(matchQueue(bracket.OrderingStrict, matches)))
+	assert.Equal(t, []uuid.UUID{lb1.ID}, ids(matchQueue(bracket.OrderingInterleaved, matches)))
+	assert.Equal(t, []uuid.UUID{lb1.ID, wb2a.ID, wb2b.ID}, ids(matchQueue(bracket.OrderingFree, matches)))
+
+	matches[2].Status = bracket.MatchFinished
+	assert.Equal(t, []uuid.UUID{wb2a.ID, wb2b.ID}, ids(matchQueue(bracket.OrderingInterleaved, matches)))
+}
+
+func TestAdvanceWinner_Ordering(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	tournaments := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
+	owner := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+
+	id, err := tournaments.CreateTournament(owner, "Ordering", bracket.SingleElimination, []EntryInput{
+		{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"},
+	})
+	require.NoError(t, err)
+	data, err := tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+
+	var second *bracket.Match
+	for i, m := range data.Matches {
+		if m.RoundNumber == 1 && m.MatchOrder == 2 {
+			second = &data.Matches[i]
+		}
+	}
+	require.NotNil(t, second)
+
+	_, err = matchService.AdvanceWinner(owner, second.ID, *second.Entry1ID)
+	assert.ErrorIs(t, err, ErrMatchOutOfOrder)
+
+	settings := SettingsOf(data.Tournament)
+	settings.Ordering = bracket.OrderingFree
+	require.NoError(t, tournaments.UpdateSettings(owner, id.String(), settings))
+
+	queue, err := tournaments.GetMatchQueue(owner, id.String())
+	require.NoError(t, err)
+	assert.Len(t, queue.Matches, 2)
+	assert.Equal(t, 1, queue.Waiting)
+
+	_, err = matchService.AdvanceWinner(owner, second.ID, *second.Entry1ID)
+	require.NoError(t, err)
+}
+
+func ids(matches []bracket.Match) []uuid.UUID {
+	var out []uuid.UUID
+	for _, m := range matches {
+		out = append(out, m.ID)
+	}
+	return out
+}
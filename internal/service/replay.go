@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/google/uuid"
+)
+
+// One pick. Byes it pushed an entry through were settled in the same step
+type ReplayStep struct {
+	Match     bracket.Match
+	DecidedAt time.Time
+}
+
+type ReplayData struct {
+	Tournament *bracket.Tournament
+	Entries    []bracket.Entry
+	Steps      []ReplayStep
+	// 0 is the seeding, len(Steps) the bracket as it is now
+	Step int
+	// The bracket right after Step
+	Matches []bracket.Match
+	// Group tables right after Step, only for tournaments with a group stage
+	Groups []Group
+}
+
+// Step is clamped, so out of range values show the seeding or the final state
+func (s *TournamentService) GetReplay(ctx context.Context, id string, step int) (*ReplayData, error) {
+	tournament, err := s.getVisibleTournament(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.store.GetEntries(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.store.GetMatches(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := replaySteps(matches)
+	step = max(0, min(step, len(steps)))
+	var until *time.Time
+	if step > 0 {
+		until = &steps[step-1].DecidedAt
+	}
+
+	data := &ReplayData{
+		Tournament: tournament,
+		Entries:    entries,
+		Steps:      steps,
+		Step:       step,
+		Matches:    bracketAt(matches, until),
+	}
+	if tournament.HasGroupStage() {
+		data.Matches = withoutEarlyPlayoff(data.Matches)
+		data.Groups = groupTables(entries, data.Matches)
+	}
+	return data, nil
+}
+
+// Groups decided matches by timestamp, every group is one pick plus the byes it cascaded into
+func replaySteps(matches []bracket.Match) []ReplayStep {
+	var decided []bracket.Match
+	for _, m := range matches {
+		if m.Status == bracket.MatchFinished && m.DecidedAt != nil {
+			decided = append(decided, m)
+		}
+	}
+	sort.SliceStable(decided, func(i, j int) bool {
+		return decided[i].DecidedAt.Before(*decided[j].DecidedAt)
+	})
+
+	var steps []ReplayStep
+	for _, m := range decided {
+		if len(steps) > 0 && steps[len(steps)-1].DecidedAt.Equal(*m.DecidedAt) {
+			// The bye is never the match someone actually picked
+			if steps[len(steps)-1].Match.IsBye && !m.IsBye {
+				steps[len(steps)-1].Match = m
+			}
+			continue
+		}
+		steps = append(steps, ReplayStep{Match: m, DecidedAt: *m.DecidedAt})
+	}
+	return steps
+}
+
+// Rewinds the bracket to how it looked once everything up to until was decided, nil meaning just the seeding.
+// An entry only sits in a slot once the match feeding that slot is decided, slots nothing feeds into were filled at creation.
+// Matches without a timestamp count as settled from the start, that's byes and anything decided before timestamps were recorded
+func bracketAt(matches []bracket.Match, until *time.Time) []bracket.Match {
+	settled := func(m *bracket.Match) bool {
+		if m.Status != bracket.MatchFinished {
+			return false
+		}
+		return m.DecidedAt == nil || (until != nil && !m.DecidedAt.After(*until))
+	}
+
+	type slotKey struct {
+		matchID uuid.UUID
+		slot    int
+	}
+	feeders := make(map[slotKey]*bracket.Match)
+	for i := range matches {
+		m := &matches[i]
+		if m.WinnerNextMatchID != nil && m.WinnerNextSlot != nil {
+			feeders[slotKey{*m.WinnerNextMatchID, *m.WinnerNextSlot}] = m
+		}
+		if m.LoserNextMatchID != nil && m.LoserNextSlot != nil {
+			feeders[slotKey{*m.LoserNextMatchID, *m.LoserNextSlot}] = m
+		}
+	}
+
+	rewound := make([]bracket.Match, len(matches))
+	for i, m := range matches {
+		if !settled(&m) {
+			m.Status = bracket.MatchPending
+			m.WinnerSlot = nil
+			m.DecidedAt = nil
+		}
+		if feeder, ok := feeders[slotKey{m.ID, 1}]; ok && !settled(feeder) {
+			m.Entry1ID = nil
+		}
+		if feeder, ok := feeders[slotKey{m.ID, 2}]; ok && !settled(feeder) {
+			m.Entry2ID = nil
+		}
+		rewound[i] = m
+	}
+	return rewound
+}
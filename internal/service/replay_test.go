@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetReplay(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	tournaments := NewTournamentService(tournamentStore)
+	matches := NewMatchService(tournamentStore)
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, uuid.MustParse(middleware.SuperUserID))
+
+	// Five entries in double elimination, so picks cascade through byes in the losers bracket
+	tournamentID, err := tournaments.CreateTournament(ctx, "Replay", bracket.DoubleElimination, []EntryInput{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}, {Name: "E"}})
+	require.NoError(t, err)
+	seeding, err := tournaments.GetTournamentData(ctx, tournamentID.String())
+	require.NoError(t, err)
+
+	// Entry 1 of whatever's next wins, until the tournament is over
+	picks := 0
+	for data := seeding; data.NextMatchID != nil; picks++ {
+		match, err := tournamentStore.GetMatch(ctx, data.NextMatchID.String())
+		require.NoError(t, err)
+		_, err = matches.AdvanceWinner(ctx, match.ID, *match.Entry1ID)
+		require.NoError(t, err)
+		data, err = tournaments.GetTournamentData(ctx, tournamentID.String())
+		require.NoError(t, err)
+	}
+	final, err := tournaments.GetTournamentData(ctx, tournamentID.String())
+	require.NoError(t, err)
+	require.Equal(t, bracket.TournamentCompleted, final.Tournament.Status)
+
+	replay, err := tournaments.GetReplay(ctx, tournamentID.String(), 0)
+	require.NoError(t, err)
+	require.Len(t, replay.Steps, picks)
+	assert.Equal(t, 0, replay.Step)
+	assert.Equal(t, seeding.Matches, replay.Matches)
+	for _, step := range replay.Steps {
+		assert.False(t, step.Match.IsBye)
+	}
+
+	// Every step adds exactly the one pick on top of the one before
+	for i := 1; i <= picks; i++ {
+		replay, err = tournaments.GetReplay(ctx, tournamentID.String(), i)
+		require.NoError(t, err)
+		picked := replay.Steps[i-1].Match
+		for _, m := range replay.Matches {
+			if m.ID == picked.ID {
+				assert.True(t, m.IsWinner(1), "step %d", i)
+			}
+		}
+	}
+	assert.Equal(t, final.Matches, replay.Matches)
+
+	// Past the end shows the final state
+	replay, err = tournaments.GetReplay(ctx, tournamentID.String(), picks+10)
+	require.NoError(t, err)
+	assert.Equal(t, picks, replay.Step)
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/utils"
+	"github.com/google/uuid"
+)
+
+type EntryResult struct {
+	Entry  bracket.Entry
+	Wins   int
+	Losses int
+	// Walkovers are kept apart from the votes, they still count towards the ranking
+	ForfeitWins   int
+	ForfeitLosses int
+}
+
+// Aggregated record for everything sharing an artist or a season
+type GroupStat struct {
+	Key     string
+	Entries int
+	Wins    int
+	Losses  int
+}
+
+type ResultsFilter struct {
+	Artist string
+	Season string
+}
+
+type ResultsData struct {
+	Tournament  *bracket.Tournament
+	Results     []EntryResult
+	ArtistStats []GroupStat
+	SeasonStats []GroupStat
+	Filter      ResultsFilter
+}
+
+func (s *TournamentService) GetResults(ctx context.Context, id string, filter ResultsFilter) (*ResultsData, error) {
+	tournament, err := s.getVisibleTournament(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.store.GetEntries(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := s.store.GetMatches(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	results := calcEntryResults(entries, matches)
+
+	// Stats always cover the whole tournament so the filter dropdowns don't lose their options
+	data := &ResultsData{
+		Tournament:  tournament,
+		ArtistStats: groupStats(results, func(e *bracket.Entry) string { return utils.OrZero(e.Artist) }),
+		SeasonStats: groupStats(results, func(e *bracket.Entry) string { return e.SeasonLabel() }),
+		Filter:      filter,
+	}
+
+	for _, r := range results {
+		if filter.Artist != "" && utils.OrZero(r.Entry.Artist) != filter.Artist {
+			continue
+		}
+		if filter.Season != "" && r.Entry.SeasonLabel() != filter.Season {
+			continue
+		}
+		data.Results = append(data.Results, r)
+	}
+
+	return data, nil
+}
+
+// Counts decided matches per entry, byes don't count as wins
+func calcEntryResults(entries []bracket.Entry, matches []bracket.Match) []EntryResult {
+	byID := make(map[uuid.UUID]*EntryResult, len(entries))
+	results := make([]EntryResult, len(entries))
+	for i, e := range entries {
+		results[i] = EntryResult{Entry: e}
+		byID[e.ID] = &results[i]
+	}
+
+	for _, m := range matches {
+		if m.Status != bracket.MatchFinished || m.IsBye || m.WinnerSlot == nil || m.Entry1ID == nil || m.Entry2ID == nil {
+			continue
+		}
+		winner, loser := m.Entry1ID, m.Entry2ID
+		if *m.WinnerSlot == 2 {
+			winner, loser = loser, winner
+		}
+		if r, ok := byID[*winner]; ok {
+			if m.Forfeit {
+				r.ForfeitWins++
+			} else {
+				r.Wins++
+			}
+		}
+		if r, ok := byID[*loser]; ok {
+			if m.Forfeit {
+				r.ForfeitLosses++
+			} else {
+				r.Losses++
+			}
+		}
+	}
+
+	// Disqualified entries go to the bottom no matter how far they got
+	sort.SliceStable(results, func(i, j int) bool {
+		if di, dj := results[i].Entry.IsDisqualified(), results[j].Entry.IsDisqualified(); di != dj {
+			return dj
+		}
+		if wi, wj := results[i].Wins+results[i].ForfeitWins, results[j].Wins+results[j].ForfeitWins; wi != wj {
+			return wi > wj
+		}
+		if li, lj := results[i].Losses+results[i].ForfeitLosses, results[j].Losses+results[j].ForfeitLosses; li != lj {
+			return li < lj
+		}
+		return results[i].Entry.Seed < results[j].Entry.Seed
+	})
+
+	return results
+}
+
+func groupStats(results []EntryResult, key func(*bracket.Entry) string) []GroupStat {
+	groups := make(map[string]*GroupStat)
+	var order []string
+	for i := range results {
+		k := key(&results[i].Entry)
+		if k == "" {
+			continue
+		}
+		g, ok := groups[k]
+		if !ok {
+			g = &GroupStat{Key: k}
+			groups[k] = g
+			order = append(order, k)
+		}
+		g.Entries++
+		g.Wins += results[i].Wins
+		g.Losses += results[i].Losses
+	}
+
+	stats := make([]GroupStat, 0, len(order))
+	for _, k := range order {
+		stats = append(stats, *groups[k])
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Wins != stats[j].Wins {
+			return stats[i].Wins > stats[j].Wins
+		}
+		return stats[i].Key < stats[j].Key
+	})
+	return stats
+}
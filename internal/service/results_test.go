@@ -0,0 +1,77 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/AdamBeresnev/op-rating-app/internal/utils"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetResults(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	bracketService := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
+
+	// Editing entries is owner only
+	ctx := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+
+	spring := bracket.SeasonSpring
+	op := bracket.ThemeOpening
+	entryInputs := []EntryInput{
+		{Name: "Entry 1", Metadata: bracket.EntryMetadata{Artist: utils.Ptr("LiSA"), Season: &spring, Year: utils.Ptr(2024), ThemeType: &op, ThemeSequence: utils.Ptr(2)}},
+		{Name: "Entry 2", Metadata: bracket.EntryMetadata{Artist: utils.Ptr("Aimer")}},
+		{Name: "Entry 3", Metadata: bracket.EntryMetadata{Artist: utils.Ptr("LiSA")}},
+		{Name: "Entry 4"},
+	}
+	tournamentID, err := bracketService.CreateTournament(ctx, "Results Test", bracket.SingleElimination, entryInputs)
+	require.NoError(t, err)
+
+	entries, err := tournamentStore.GetEntries(ctx, tournamentID.String())
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	assert.Equal(t, "OP2", entries[0].ThemeLabel())
+	assert.Equal(t, "Spring 2024", entries[0].SeasonLabel())
+
+	matches, err := tournamentStore.GetMatches(ctx, tournamentID.String())
+	require.NoError(t, err)
+
+	// Seed 1 beats seed 4
+	_, err = matchService.AdvanceWinner(ctx, matches[0].ID, entries[0].ID)
+	require.NoError(t, err)
+
+	data, err := bracketService.GetResults(ctx, tournamentID.String(), ResultsFilter{})
+	require.NoError(t, err)
+	require.Len(t, data.Results, 4)
+	assert.Equal(t, entries[0].ID, data.Results[0].Entry.ID)
+	assert.Equal(t, 1, data.Results[0].Wins)
+
+	require.NotEmpty(t, data.ArtistStats)
+	assert.Equal(t, GroupStat{Key: "LiSA", Entries: 2, Wins: 1, Losses: 0}, data.ArtistStats[0])
+	assert.Equal(t, []GroupStat{{Key: "Spring 2024", Entries: 1, Wins: 1}}, data.SeasonStats)
+
+	data, err = bracketService.GetResults(ctx, tournamentID.String(), ResultsFilter{Artist: "LiSA"})
+	require.NoError(t, err)
+	assert.Len(t, data.Results, 2)
+
+	data, err = bracketService.GetResults(ctx, tournamentID.String(), ResultsFilter{Season: "Spring 2024"})
+	require.NoError(t, err)
+	assert.Len(t, data.Results, 1)
+
+	updated, err := bracketService.UpdateEntryMetadata(ctx, entries[3].ID.String(), "Renamed", bracket.EntryMetadata{SongTitle: utils.Ptr("Gurenge")})
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", updated.Name)
+
+	fetched, err := tournamentStore.GetEntry(ctx, entries[3].ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", fetched.Name)
+	assert.Equal(t, "Gurenge", *fetched.SongTitle)
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/ical"
+	"github.com/AdamBeresnev/op-rating-app/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	maxUpcomingMatches = 10
+	// Matches don't have an end, calendar apps still want one
+	scheduledMatchLength = time.Hour
+)
+
+// A nil time takes the match off the schedule, the stream link goes with it
+type MatchSchedule struct {
+	At        *time.Time
+	StreamURL string
+}
+
+// Owner only. Picking a time marks the match as scheduled, clearing it puts it back to pending.
+// Voting doesn't wait for the time, it's only there so people know when to show up
+func (s *MatchService) ScheduleMatch(ctx context.Context, matchID string, schedule MatchSchedule) (uuid.UUID, error) {
+	match, err := s.store.GetMatch(ctx, matchID)
+	if err != nil {
+		return uuid.Nil, notFound(err, "match")
+	}
+	tournament, err := getManagedTournament(ctx, s.store, match.TournamentID.String())
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if tournament.Status == bracket.TournamentCompleted {
+		return uuid.Nil, ErrTournamentFinished
+	}
+	schedule.StreamURL = strings.TrimSpace(schedule.StreamURL)
+	if schedule.StreamURL != "" && !isWebURL(schedule.StreamURL) {
+		return uuid.Nil, &ValidationError{Message: "The stream has to be an http or https link"}
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	// Read again inside the transaction, a vote or a winner moving into one of the slots could have landed
+	// since and writing back the old row would undo it
+	match, err = s.store.GetMatchTx(ctx, tx, matchID)
+	if err != nil {
+		return uuid.Nil, notFound(err, "match")
+	}
+	if match.Status == bracket.MatchFinished {
+		return uuid.Nil, ErrMatchDecided
+	}
+	if match.IsBye {
+		return uuid.Nil, &ValidationError{Message: "Byes aren't played, there's nothing to schedule"}
+	}
+
+	before := *match
+	if schedule.At != nil {
+		at := schedule.At.UTC().Truncate(time.Minute)
+		match.ScheduledAt = &at
+		match.StreamURL = utils.StringOrNil(schedule.StreamURL)
+		match.Status = bracket.MatchScheduled
+	} else {
+		match.ScheduledAt = nil
+		match.StreamURL = nil
+		match.Status = bracket.MatchPending
+	}
+
+	if err := s.store.UpdateMatch(ctx, tx, match); err != nil {
+		return uuid.Nil, err
+	}
+	if err := recordAudit(ctx, s.store, tx, bracket.AuditEvent{
+		TournamentID: tournament.ID,
+		MatchID:      &match.ID,
+		Action:       bracket.AuditMatchScheduled,
+		Before:       bracket.AuditSnapshot{Matches: []bracket.Match{before}},
+		After:        bracket.AuditSnapshot{Matches: []bracket.Match{*match}},
+	}); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to record audit event: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	return tournament.ID, nil
+}
+
+// Scheduled matches nobody picked a winner for yet, soonest first. Overdue ones stay until they're decided
+func upcomingMatches(matches []bracket.Match) []bracket.Match {
+	var upcoming []bracket.Match
+	for _, m := range matches {
+		if m.IsUpcoming() {
+			upcoming = append(upcoming, m)
+		}
+	}
+	sortBySchedule(upcoming)
+	return upcoming
+}
+
+func sortBySchedule(matches []bracket.Match) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].ScheduledAt.Before(*matches[j].ScheduledAt)
+	})
+}
+
+type UpcomingMatch struct {
+	Tournament bracket.Tournament
+	Match      bracket.Match
+	Entry1     *bracket.Entry
+	Entry2     *bracket.Entry
+}
+
+// The next few scheduled matches across the user's own tournaments, for the home page
+func (s *TournamentService) GetUpcomingMatchesForUser(ctx context.Context) ([]UpcomingMatch, error) {
+	tournaments, err := s.GetTournamentsForUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var upcoming []UpcomingMatch
+	for _, t := range tournaments {
+		if t.Status == bracket.TournamentCompleted {
+			continue
+		}
+		matches, err := s.store.GetMatches(ctx, t.ID.String())
+		if err != nil {
+			return nil, err
+		}
+		scheduled := upcomingMatches(matches)
+		if len(scheduled) == 0 {
+			continue
+		}
+		entries, err := s.entriesByID(ctx, t.ID.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range scheduled {
+			upcoming = append(upcoming, UpcomingMatch{
+				Tournament: t,
+				Match:      m,
+				Entry1:     lookupEntry(entries, m.Entry1ID),
+				Entry2:     lookupEntry(entries, m.Entry2ID),
+			})
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].Match.ScheduledAt.Before(*upcoming[j].Match.ScheduledAt)
+	})
+	if len(upcoming) > maxUpcomingMatches {
+		upcoming = upcoming[:maxUpcomingMatches]
+	}
+	return upcoming, nil
+}
+
+// Every scheduled match of a tournament, decided ones included so they stay in people's calendars.
+// Calendar apps can't log in, so private tournaments only work for the owner's own browser
+func (s *TournamentService) GetCalendar(ctx context.Context, id string, baseURL string) (*ical.Calendar, error) {
+	tournament, err := s.getVisibleTournament(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.store.GetMatches(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.entriesByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduled []bracket.Match
+	for _, m := range matches {
+		if m.ScheduledAt != nil {
+			scheduled = append(scheduled, m)
+		}
+	}
+	sortBySchedule(scheduled)
+
+	calendar := &ical.Calendar{Name: tournament.Name}
+	for _, m := range scheduled {
+		matchURL := fmt.Sprintf("%s/matches/%s", baseURL, m.ID)
+		description := matchURL
+		if m.StreamURL != nil {
+			description = fmt.Sprintf("Stream: %s\n%s", *m.StreamURL, matchURL)
+		}
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:         m.ID.String() + "@op-rating-app",
+			Start:       *m.ScheduledAt,
+			End:         m.ScheduledAt.Add(scheduledMatchLength),
+			Summary:     fmt.Sprintf("%s: %s vs %s", tournament.Name, entryName(entries, m.Entry1ID), entryName(entries, m.Entry2ID)),
+			Description: description,
+			Location:    utils.OrZero(m.StreamURL),
+			URL:         matchURL,
+		})
+	}
+	return calendar, nil
+}
+
+func (s *TournamentService) entriesByID(ctx context.Context, tournamentID string) (map[uuid.UUID]bracket.Entry, error) {
+	entries, err := s.store.GetEntries(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]bracket.Entry, len(entries))
+	for _, e := range entries {
+		byID[e.ID] = e
+	}
+	return byID, nil
+}
+
+func lookupEntry(entries map[uuid.UUID]bracket.Entry, id *uuid.UUID) *bracket.Entry {
+	if id == nil {
+		return nil
+	}
+	if e, ok := entries[*id]; ok {
+		return &e
+	}
+	return nil
+}
+
+// Later rounds are scheduled before anyone knows who's in them
+func entryName(entries map[uuid.UUID]bracket.Entry, id *uuid.UUID) string {
+	if e := lookupEntry(entries, id); e != nil {
+		return e.Name
+	}
+	return "TBD"
+}
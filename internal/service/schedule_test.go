@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleMatch(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	tournaments := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
+	owner := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+	stranger := asUser(&users.User{ID: uuid.New(), Username: "stranger"})
+
+	id, err := tournaments.CreateTournament(owner, "League", bracket.SingleElimination, []EntryInput{
+		{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"},
+	})
+	require.NoError(t, err)
+	data, err := tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	first, second, final := data.Matches[0], data.Matches[1], data.Matches[2]
+
+	week1 := time.Date(2026, 11, 2, 19, 30, 0, 0, time.UTC)
+	week2 := week1.AddDate(0, 0, 7)
+
+	_, err = matchService.ScheduleMatch(stranger, first.ID.String(), MatchSchedule{At: &week1})
+	assert.ErrorIs(t, err, ErrForbidden)
+	var invalid *ValidationError
+	_, err = matchService.ScheduleMatch(owner, first.ID.String(), MatchSchedule{At: &week1, StreamURL: "twitch.tv/someone"})
+	assert.ErrorAs(t, err, &invalid)
+
+	// The final gets a slot before anyone knows who's in it
+	_, err = matchService.ScheduleMatch(owner, final.ID.String(), MatchSchedule{At: &week2})
+	require.NoError(t, err)
+	_, err = matchService.ScheduleMatch(owner, second.ID.String(), MatchSchedule{At: &week1, StreamURL: "https://example.com/live"})
+	require.NoError(t, err)
+
+	data, err = tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	require.Len(t, data.Upcoming, 2)
+	assert.Equal(t, second.ID, data.Upcoming[0].ID)
+	assert.Equal(t, bracket.MatchScheduled, data.Upcoming[0].Status)
+	assert.Equal(t, "https://example.com/live", *data.Upcoming[0].StreamURL)
+	assert.Equal(t, final.ID, data.Upcoming[1].ID)
+
+	upcoming, err := tournaments.GetUpcomingMatchesForUser(owner)
+	require.NoError(t, err)
+	require.Len(t, upcoming, 2)
+	assert.Equal(t, *second.Entry1ID, upcoming[0].Entry1.ID)
+	assert.Nil(t, upcoming[1].Entry1)
+
+	_, err = matchService.AdvanceWinner(owner, first.ID, *first.Entry1ID)
+	require.NoError(t, err)
+	_, err = matchService.ScheduleMatch(owner, first.ID.String(), MatchSchedule{At: &week1})
+	assert.ErrorIs(t, err, ErrMatchDecided)
+
+	// Decided matches drop off the upcoming list but stay in the calendar
+	_, err = matchService.AdvanceWinner(owner, second.ID, *second.Entry1ID)
+	require.NoError(t, err)
+	data, err = tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	require.Len(t, data.Upcoming, 1)
+	assert.Equal(t, final.ID, data.Upcoming[0].ID)
+
+	calendar, err := tournaments.GetCalendar(owner, id.String(), "https://example.com")
+	require.NoError(t, err)
+	require.Len(t, calendar.Events, 2)
+	names := make(map[uuid.UUID]string)
+	for _, e := range data.Entries {
+		names[e.ID] = e.Name
+	}
+	assert.Equal(t, "League: "+names[*second.Entry1ID]+" vs "+names[*second.Entry2ID], calendar.Events[0].Summary)
+	assert.Equal(t, "https://example.com/live", calendar.Events[0].Location)
+	assert.Equal(t, "League: "+names[*first.Entry1ID]+" vs "+names[*second.Entry1ID], calendar.Events[1].Summary)
+	assert.Equal(t, "https://example.com/matches/"+final.ID.String(), calendar.Events[1].URL)
+
+	_, err = matchService.ScheduleMatch(owner, final.ID.String(), MatchSchedule{})
+	require.NoError(t, err)
+	data, err = tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	assert.Empty(t, data.Upcoming)
+	assert.Equal(t, bracket.MatchPending, data.Matches[2].Status)
+}
+
+// Hands out the match as it was before anything happened to it, like a read that lost the race to a vote
+type staleMatchStore struct {
+	store.TournamentRepository
+	stale bracket.Match
+}
+
+func (s *staleMatchStore) GetMatch(ctx context.Context, id string) (*bracket.Match, error) {
+	m := s.stale
+	return &m, nil
+}
+
+func TestScheduleMatch_KeepsConcurrentChanges(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	tournaments := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
+	owner := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+
+	id, err := tournaments.CreateTournament(owner, "League", bracket.SingleElimination, []EntryInput{
+		{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"},
+	})
+	require.NoError(t, err)
+	data, err := tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	first, second, final := data.Matches[0], data.Matches[1], data.Matches[2]
+
+	// Both semis are decided between the schedule request reading the final and writing it
+	_, err = matchService.AdvanceWinner(owner, first.ID, *first.Entry1ID)
+	require.NoError(t, err)
+	_, err = matchService.AdvanceWinner(owner, second.ID, *second.Entry1ID)
+	require.NoError(t, err)
+
+	at := time.Date(2026, 11, 2, 19, 30, 0, 0, time.UTC)
+	stale := NewMatchService(&staleMatchStore{TournamentRepository: tournamentStore, stale: final})
+	_, err = stale.ScheduleMatch(owner, final.ID.String(), MatchSchedule{At: &at})
+	require.NoError(t, err)
+
+	updated, err := tournamentStore.GetMatch(owner, final.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, first.Entry1ID, updated.Entry1ID)
+	assert.Equal(t, second.Entry1ID, updated.Entry2ID)
+	assert.Equal(t, bracket.MatchScheduled, updated.Status)
+
+	// A vote that got in first wins over the schedule
+	stale = NewMatchService(&staleMatchStore{TournamentRepository: tournamentStore, stale: first})
+	_, err = stale.ScheduleMatch(owner, first.ID.String(), MatchSchedule{At: &at})
+	assert.ErrorIs(t, err, ErrMatchDecided)
+	decided, err := tournamentStore.GetMatch(owner, first.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.MatchFinished, decided.Status)
+}
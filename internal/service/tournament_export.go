@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/google/uuid"
+)
+
+const exportVersion = 1
+
+// A whole tournament as JSON, for moving it between instances with `web tournament export/import`.
+// Uploaded videos aren't included, only the records pointing at them
+type TournamentExport struct {
+	Version    int                `json:"version"`
+	Tournament bracket.Tournament `json:"tournament"`
+	Entries    []bracket.Entry    `json:"entries"`
+	Matches    []bracket.Match    `json:"matches"`
+}
+
+func (s *TournamentService) ListAllTournaments(ctx context.Context) ([]bracket.Tournament, error) {
+	return s.store.ListTournaments(ctx)
+}
+
+// No ownership check, only for admin tooling
+func (s *TournamentService) AdminDeleteTournament(ctx context.Context, id string) error {
+	tournament, err := s.store.GetTournament(ctx, id)
+	if err != nil {
+		return notFound(err, "tournament")
+	}
+	return s.deleteTournament(ctx, tournament)
+}
+
+func (s *TournamentService) ExportTournament(ctx context.Context, id string) (*TournamentExport, error) {
+	tournament, err := s.store.GetTournament(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "tournament")
+	}
+	data, err := s.loadTournamentData(ctx, tournament)
+	if err != nil {
+		return nil, err
+	}
+	return &TournamentExport{
+		Version:    exportVersion,
+		Tournament: *data.Tournament,
+		Entries:    data.Entries,
+		Matches:    data.Matches,
+	}, nil
+}
+
+// Every ID gets replaced so the same export can be imported more than once, even into the instance it came from.
+// Entries lose their upload since the file itself isn't part of the export
+func (s *TournamentService) ImportTournament(ctx context.Context, export *TournamentExport, ownerID uuid.UUID) (uuid.UUID, error) {
+	if export.Version != exportVersion {
+		return uuid.Nil, fmt.Errorf("unsupported export version %d", export.Version)
+	}
+
+	tournament := export.Tournament
+	tournament.ID = uuid.New()
+	tournament.OwnerID = ownerID
+
+	entryIDs := make(map[uuid.UUID]uuid.UUID, len(export.Entries))
+	entries := make([]bracket.Entry, len(export.Entries))
+	for i, e := range export.Entries {
+		entryIDs[e.ID] = uuid.New()
+		e.ID = entryIDs[e.ID]
+		e.TournamentID = tournament.ID
+		e.UploadID = nil
+		entries[i] = e
+	}
+
+	matchIDs := make(map[uuid.UUID]uuid.UUID, len(export.Matches))
+	for _, m := range export.Matches {
+		matchIDs[m.ID] = uuid.New()
+	}
+	remap := func(ids map[uuid.UUID]uuid.UUID, id *uuid.UUID) (*uuid.UUID, error) {
+		if id == nil {
+			return nil, nil
+		}
+		newID, ok := ids[*id]
+		if !ok {
+			return nil, fmt.Errorf("export refers to unknown ID %s", id)
+		}
+		return &newID, nil
+	}
+
+	matches := make([]bracket.Match, len(export.Matches))
+	for i, m := range export.Matches {
+		var err error
+		m.ID = matchIDs[m.ID]
+		m.TournamentID = tournament.ID
+		if m.Entry1ID, err = remap(entryIDs, m.Entry1ID); err != nil {
+			return uuid.Nil, err
+		}
+		if m.Entry2ID, err = remap(entryIDs, m.Entry2ID); err != nil {
+			return uuid.Nil, err
+		}
+		if m.WinnerNextMatchID, err = remap(matchIDs, m.WinnerNextMatchID); err != nil {
+			return uuid.Nil, err
+		}
+		if m.LoserNextMatchID, err = remap(matchIDs, m.LoserNextMatchID); err != nil {
+			return uuid.Nil, err
+		}
+		matches[i] = m
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.store.CreateTournament(ctx, tx, &tournament); err != nil {
+		return uuid.Nil, err
+	}
+	if err := s.store.CreateEntries(ctx, tx, entries); err != nil {
+		return uuid.Nil, err
+	}
+	if err := s.store.CreateMatches(ctx, tx, matches); err != nil {
+		return uuid.Nil, err
+	}
+	if err := recordAudit(ctx, s.store, tx, bracket.AuditEvent{
+		TournamentID: tournament.ID,
+		Action:       bracket.AuditTournamentCreated,
+		After:        bracket.AuditSnapshot{Matches: matches},
+	}); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return tournament.ID, tx.Commit()
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportImportTournament(t *testing.T) {
+	tournamentStore := store.NewMemoryTournamentStore()
+	tournaments := NewTournamentService(tournamentStore)
+	matches := NewMatchService(tournamentStore)
+
+	ownerID := uuid.MustParse(middleware.SuperUserID)
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, ownerID)
+
+	sourceID, err := tournaments.CreateTournament(ctx, "Export Me", bracket.DoubleElimination, []EntryInput{
+		{Name: "Entry 1", EmbedLink: "https://example.com/1.webm"}, {Name: "Entry 2"}, {Name: "Entry 3"}, {Name: "Entry 4"},
+	})
+	require.NoError(t, err)
+	data, err := tournaments.GetTournamentData(ctx, sourceID.String())
+	require.NoError(t, err)
+	first, err := matches.GetMatchViewData(ctx, data.NextMatchID.String())
+	require.NoError(t, err)
+	_, err = matches.AdvanceWinner(ctx, first.Match.ID, first.Entry1.ID)
+	require.NoError(t, err)
+
+	export, err := tournaments.ExportTournament(ctx, sourceID.String())
+	require.NoError(t, err)
+
+	// Goes through JSON like the CLI does
+	payload, err := json.Marshal(export)
+	require.NoError(t, err)
+	var decoded TournamentExport
+	require.NoError(t, json.Unmarshal(payload, &decoded))
+
+	newOwner := uuid.New()
+	importedID, err := tournaments.ImportTournament(ctx, &decoded, newOwner)
+	require.NoError(t, err)
+	assert.NotEqual(t, sourceID, importedID)
+
+	imported, err := tournaments.GetTournamentData(ctx, importedID.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Export Me", imported.Tournament.Name)
+	assert.Equal(t, newOwner, imported.Tournament.OwnerID)
+	require.Len(t, imported.Entries, len(export.Entries))
+	require.Len(t, imported.Matches, len(export.Matches))
+	assert.Equal(t, "https://example.com/1.webm", *imported.Entries[0].EmbedLink)
+
+	entryIDs := map[uuid.UUID]bool{}
+	for _, e := range imported.Entries {
+		entryIDs[e.ID] = true
+	}
+	matchIDs := map[uuid.UUID]bool{}
+	for _, m := range imported.Matches {
+		matchIDs[m.ID] = true
+	}
+	for i, m := range imported.Matches {
+		original := export.Matches[i]
+		assert.NotEqual(t, original.ID, m.ID)
+		assert.Equal(t, original.Status, m.Status)
+		assert.Equal(t, original.WinnerSlot, m.WinnerSlot)
+		for _, id := range []*uuid.UUID{m.Entry1ID, m.Entry2ID} {
+			if id != nil {
+				assert.True(t, entryIDs[*id], "entry reference should point into the imported tournament")
+			}
+		}
+		for _, id := range []*uuid.UUID{m.WinnerNextMatchID, m.LoserNextMatchID} {
+			if id != nil {
+				assert.True(t, matchIDs[*id], "match reference should point into the imported tournament")
+			}
+		}
+	}
+
+	// The imported copy picks up where the original left off
+	require.NotNil(t, imported.NextMatchID)
+	next, err := matches.GetMatchViewData(ctx, imported.NextMatchID.String())
+	require.NoError(t, err)
+	_, err = matches.AdvanceWinner(ctx, next.Match.ID, next.Entry1.ID)
+	require.NoError(t, err)
+
+	decoded.Version = 99
+	_, err = tournaments.ImportTournament(ctx, &decoded, newOwner)
+	assert.Error(t, err)
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	"github.com/AdamBeresnev/op-rating-app/internal/utils"
+	"github.com/google/uuid"
+)
+
+// Looks up a tournament the current user is allowed to change, which means they own it or are an admin
+func getManagedTournament(ctx context.Context, repo store.TournamentRepository, id string) (*bracket.Tournament, error) {
+	user := middleware.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, ErrUnauthenticated
+	}
+	tournament, err := repo.GetTournament(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "tournament")
+	}
+	if !canManage(ctx, tournament) {
+		return nil, ErrForbidden
+	}
+	return tournament, nil
+}
+
+// Private tournaments look like they don't exist to anyone who couldn't manage them
+func (s *TournamentService) getVisibleTournament(ctx context.Context, id string) (*bracket.Tournament, error) {
+	tournament, err := s.store.GetTournament(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "tournament")
+	}
+	if !canView(ctx, tournament) {
+		return nil, &NotFoundError{What: "tournament"}
+	}
+	return tournament, nil
+}
+
+func canManage(ctx context.Context, tournament *bracket.Tournament) bool {
+	user := middleware.GetAuthenticatedUser(ctx)
+	return user != nil && (user.ID == tournament.OwnerID || user.IsAdmin)
+}
+
+func canView(ctx context.Context, tournament *bracket.Tournament) bool {
+	return tournament.Visibility != bracket.VisibilityPrivate || canManage(ctx, tournament)
+}
+
+func (s *TournamentService) DeleteTournament(ctx context.Context, id string) error {
+	tournament, err := getManagedTournament(ctx, s.store, id)
+	if err != nil {
+		return err
+	}
+	return s.deleteTournament(ctx, tournament)
+}
+
+// The matches as they were go into the audit log, it's the only trace of the tournament left afterwards
+func (s *TournamentService) deleteTournament(ctx context.Context, tournament *bracket.Tournament) error {
+	matches, err := s.store.GetMatches(ctx, tournament.ID.String())
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.store.DeleteTournament(ctx, tx, tournament.ID.String()); err != nil {
+		return notFound(err, "tournament")
+	}
+	if err := recordAudit(ctx, s.store, tx, bracket.AuditEvent{
+		TournamentID: tournament.ID,
+		Action:       bracket.AuditTournamentDeleted,
+		Before:       bracket.AuditSnapshot{Matches: matches},
+	}); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Archiving twice, or restoring something that isn't archived, does nothing
+func (s *TournamentService) SetTournamentArchived(ctx context.Context, id string, archived bool) error {
+	tournament, err := getManagedTournament(ctx, s.store, id)
+	if err != nil {
+		return err
+	}
+	if tournament.IsArchived() == archived {
+		return nil
+	}
+
+	var archivedAt *time.Time
+	action := bracket.AuditTournamentUnarchived
+	if archived {
+		archivedAt = utils.Ptr(time.Now().UTC())
+		action = bracket.AuditTournamentArchived
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.store.SetTournamentArchivedTx(ctx, tx, id, archivedAt); err != nil {
+		return notFound(err, "tournament")
+	}
+	if err := recordAudit(ctx, s.store, tx, bracket.AuditEvent{TournamentID: tournament.ID, Action: action}); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Opens a draft for voting. The bracket already exists, drafts get theirs on creation like everything else
+func (s *TournamentService) StartTournament(ctx context.Context, id string) error {
+	tournament, err := getManagedTournament(ctx, s.store, id)
+	if err != nil {
+		return err
+	}
+	if tournament.Status != bracket.TournamentDraft {
+		return ErrTournamentStarted
+	}
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.store.UpdateTournamentStatusTx(ctx, tx, id, bracket.TournamentStarted); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, s.store, tx, bracket.AuditEvent{TournamentID: tournament.ID, Action: bracket.AuditTournamentStarted}); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Copies the entries with their links, clips and metadata and the tournament settings into a fresh draft
+// owned by the current user. Results don't come along, the new bracket starts from the seeding
+func (s *TournamentService) CloneTournament(ctx context.Context, id string) (uuid.UUID, error) {
+	tournament, err := getManagedTournament(ctx, s.store, id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	entries, err := s.store.GetEntries(ctx, id)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	inputs := make([]EntryInput, len(entries))
+	for i, e := range entries {
+		inputs[i] = EntryInput{
+			Name:         e.Name,
+			EmbedLink:    utils.OrZero(e.EmbedLink),
+			UploadID:     e.UploadID,
+			StartSeconds: e.StartSeconds,
+			EndSeconds:   e.EndSeconds,
+			Metadata:     e.EntryMetadata,
+		}
+	}
+
+	return s.createTournament(ctx, bracket.Tournament{
+		Name:             tournament.Name + " (copy)",
+		Status:           bracket.TournamentDraft,
+		Type:             tournament.Type,
+		ScoreRequirement: tournament.ScoreRequirement,
+		Description:      tournament.Description,
+		CoverImageURL:    tournament.CoverImageURL,
+		Visibility:       tournament.Visibility,
+		BronzeMatch:      tournament.BronzeMatch,
+		GroupCount:       tournament.GroupCount,
+		GroupAdvance:     tournament.GroupAdvance,
+		Ordering:         tournament.Ordering,
+	}, inputs)
+}
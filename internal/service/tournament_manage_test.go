@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func asUser(user *users.User) context.Context {
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, user.ID)
+	return context.WithValue(ctx, users.UserKey, user)
+}
+
+func TestManageTournament_Permissions(t *testing.T) {
+	tournaments := NewTournamentService(store.NewMemoryTournamentStore())
+
+	owner := asUser(&users.User{ID: uuid.New(), Username: "owner"})
+	stranger := asUser(&users.User{ID: uuid.New(), Username: "stranger"})
+	admin := asUser(&users.User{ID: uuid.New(), Username: "admin", IsAdmin: true})
+
+	id, err := tournaments.CreateTournament(owner, "Mine", bracket.SingleElimination, []EntryInput{{Name: "A"}, {Name: "B"}})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, tournaments.DeleteTournament(stranger, id.String()), ErrForbidden)
+	assert.ErrorIs(t, tournaments.SetTournamentArchived(stranger, id.String(), true), ErrForbidden)
+	_, err = tournaments.CloneTournament(stranger, id.String())
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.ErrorIs(t, tournaments.DeleteTournament(context.Background(), id.String()), ErrUnauthenticated)
+
+	// An admin's clone belongs to the admin
+	cloneID, err := tournaments.CloneTournament(admin, id.String())
+	require.NoError(t, err)
+	clone, err := tournaments.GetTournamentData(admin, cloneID.String())
+	require.NoError(t, err)
+	assert.NotEqual(t, id, clone.Tournament.ID)
+	assert.Equal(t, bracket.TournamentDraft, clone.Tournament.Status)
+
+	require.NoError(t, tournaments.SetTournamentArchived(owner, id.String(), true))
+	require.NoError(t, tournaments.SetTournamentArchived(owner, id.String(), true))
+	require.NoError(t, tournaments.DeleteTournament(admin, id.String()))
+	assert.ErrorIs(t, tournaments.DeleteTournament(admin, id.String()), ErrNotFound)
+
+	// Archiving twice only counts once
+	events, err := tournaments.GetAuditLog(owner, id.String())
+	require.NoError(t, err)
+	var actions []bracket.AuditAction
+	for _, e := range events {
+		actions = append(actions, e.Action)
+	}
+	assert.Equal(t, []bracket.AuditAction{bracket.AuditTournamentCreated, bracket.AuditTournamentArchived, bracket.AuditTournamentDeleted}, actions)
+}
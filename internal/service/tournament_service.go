@@ -6,25 +6,42 @@ import (
 	"math"
 
 	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/metrics"
 	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
 	"github.com/AdamBeresnev/op-rating-app/internal/store"
 	"github.com/AdamBeresnev/op-rating-app/internal/utils"
+	"github.com/AdamBeresnev/op-rating-app/internal/video"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 )
 
 type TournamentService struct {
-	db    *sqlx.DB
-	store *store.TournamentStore
+	store  store.TournamentRepository
+	limits Limits
 }
 
-func NewTournamentService(db *sqlx.DB, store *store.TournamentStore) *TournamentService {
-	return &TournamentService{db: db, store: store}
+func NewTournamentService(store store.TournamentRepository) *TournamentService {
+	return &TournamentService{store: store}
+}
+
+// Caps checked by CreateTournament, zero means no limit. The guest account is shared, so guests share one quota
+type Limits struct {
+	MaxEntries            int
+	MaxTournamentsPerUser int
+}
+
+func (s *TournamentService) WithLimits(limits Limits) *TournamentService {
+	s.limits = limits
+	return s
 }
 
 type EntryInput struct {
 	Name      string
 	EmbedLink string
+	UploadID  *uuid.UUID
+	// Manually entered clip range, falls back to whatever timestamps are in the link
+	StartSeconds *int
+	EndSeconds   *int
+	Metadata     bracket.EntryMetadata
 }
 
 type TournamentData struct {
@@ -32,14 +49,21 @@ type TournamentData struct {
 	Entries     []bracket.Entry
 	Matches     []bracket.Match
 	NextMatchID *uuid.UUID
+	Upcoming    []bracket.Match
+	// Only for tournaments with a group stage
+	Groups []Group
 }
 
 func (s *TournamentService) GetTournamentData(ctx context.Context, id string) (*TournamentData, error) {
-	tournament, err := s.store.GetTournament(ctx, id)
+	tournament, err := s.getVisibleTournament(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	return s.loadTournamentData(ctx, tournament)
+}
 
+func (s *TournamentService) loadTournamentData(ctx context.Context, tournament *bracket.Tournament) (*TournamentData, error) {
+	id := tournament.ID.String()
 	entries, err := s.store.GetEntries(ctx, id)
 	if err != nil {
 		return nil, err
@@ -50,29 +74,111 @@ func (s *TournamentService) GetTournamentData(ctx context.Context, id string) (*
 		return nil, err
 	}
 
-	nextMatch, err := s.store.GetNextPendingMatch(ctx, id)
+	data := &TournamentData{
+		Tournament:  tournament,
+		Entries:     entries,
+		Matches:     matches,
+		NextMatchID: nextMatchID(tournament.Ordering, matches),
+		Upcoming:    upcomingMatches(matches),
+	}
+	if tournament.HasGroupStage() {
+		data.Groups = groupTables(entries, matches)
+	}
+	return data, nil
+}
+
+// Owner only, it's what the edit page loads
+func (s *TournamentService) GetEntry(ctx context.Context, id string) (*bracket.Entry, error) {
+	entry, err := s.store.GetEntry(ctx, id)
 	if err != nil {
+		return nil, notFound(err, "entry")
+	}
+	if _, err := getManagedTournament(ctx, s.store, entry.TournamentID.String()); err != nil {
 		return nil, err
 	}
+	return entry, nil
+}
 
-	var nextMatchID *uuid.UUID
-	if nextMatch != nil {
-		id := nextMatch.ID
-		nextMatchID = &id
+// Owner only. Renames an entry and replaces its metadata. Safe at any point since the bracket only cares about IDs
+func (s *TournamentService) UpdateEntryMetadata(ctx context.Context, id string, name string, metadata bracket.EntryMetadata) (*bracket.Entry, error) {
+	entry, err := s.store.GetEntry(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "entry")
+	}
+	if _, err := getManagedTournament(ctx, s.store, entry.TournamentID.String()); err != nil {
+		return nil, err
+	}
+	if err := validateEntryMetadata(name, metadata); err != nil {
+		return nil, err
 	}
+	before := *entry
 
-	return &TournamentData{
-		Tournament:  tournament,
-		Entries:     entries,
-		Matches:     matches,
-		NextMatchID: nextMatchID,
-	}, nil
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	entry.Name = name
+	entry.EntryMetadata = metadata
+	if err := s.store.UpdateEntryMetadata(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+	if err := recordAudit(ctx, s.store, tx, bracket.AuditEvent{
+		TournamentID: entry.TournamentID,
+		Action:       bracket.AuditEntryUpdated,
+		Before:       bracket.AuditSnapshot{Entry: &before},
+		After:        bracket.AuditSnapshot{Entry: entry},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record audit event: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// The thumbnail ends up in an <img src>, same rule as the cover image
+func validateEntryMetadata(name string, metadata bracket.EntryMetadata) error {
+	if metadata.ThumbnailURL != nil && !isWebURL(*metadata.ThumbnailURL) {
+		return &ValidationError{Message: fmt.Sprintf("The thumbnail for '%s' has to be an http or https link", name)}
+	}
+	return nil
 }
 
+// Archived ones are left out, see GetArchivedTournamentsForUser
 func (s *TournamentService) GetTournamentsForUser(ctx context.Context) ([]bracket.Tournament, error) {
+	all, err := s.getAllTournamentsForUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var active []bracket.Tournament
+	for _, t := range all {
+		if !t.IsArchived() {
+			active = append(active, t)
+		}
+	}
+	return active, nil
+}
+
+func (s *TournamentService) GetArchivedTournamentsForUser(ctx context.Context) ([]bracket.Tournament, error) {
+	all, err := s.getAllTournamentsForUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var archived []bracket.Tournament
+	for _, t := range all {
+		if t.IsArchived() {
+			archived = append(archived, t)
+		}
+	}
+	return archived, nil
+}
+
+func (s *TournamentService) getAllTournamentsForUser(ctx context.Context) ([]bracket.Tournament, error) {
 	userID, ok := middleware.GetUserIDFromContext(ctx)
 	if !ok {
-		return nil, fmt.Errorf("user ID not found in the context")
+		return nil, ErrUnauthenticated
 	}
 	return s.store.GetTournamentsByUserID(ctx, userID)
 }
@@ -167,6 +273,31 @@ func (s *TournamentService) GenerateSingleElimBracket(tournamentID uuid.UUID, en
 	return matches
 }
 
+// Third place match between the semifinal losers. Only added from four entries up, below that a semifinal can be a bye
+// and the match would never get its second entry
+func addBronzeMatch(tournamentID uuid.UUID, matches []bracket.Match) []bracket.Match {
+	finalRound := 0
+	for _, m := range matches {
+		finalRound = max(finalRound, m.RoundNumber)
+	}
+
+	bronze := bracket.Match{
+		ID:           uuid.New(),
+		TournamentID: tournamentID,
+		BracketSide:  bracket.FinalsSide,
+		RoundNumber:  1,
+		MatchOrder:   1,
+		Status:       bracket.MatchPending,
+	}
+	for i := range matches {
+		if m := &matches[i]; m.RoundNumber == finalRound-1 {
+			m.LoserNextMatchID = &bronze.ID
+			m.LoserNextSlot = utils.Ptr(m.MatchOrder)
+		}
+	}
+	return append(matches, bronze)
+}
+
 // This sucked
 func (s *TournamentService) GenerateDoubleElimBracket(tournamentID uuid.UUID, entries []bracket.Entry) []bracket.Match {
 	var matches []bracket.Match
@@ -341,35 +472,61 @@ func (s *TournamentService) GenerateDoubleElimBracket(tournamentID uuid.UUID, en
 }
 
 func (s *TournamentService) CreateTournament(ctx context.Context, name string, tournamentType bracket.TournamentType, entryInputs []EntryInput) (uuid.UUID, error) {
-	tx, err := s.db.BeginTxx(ctx, nil)
+	return s.createTournament(ctx, bracket.Tournament{
+		Name:   name,
+		Status: bracket.TournamentStarted,
+		Type:   tournamentType,
+	}, entryInputs)
+}
+
+// Takes name, type, status and settings from the template, the ID and owner are filled in here
+func (s *TournamentService) createTournament(ctx context.Context, tournament bracket.Tournament, entryInputs []EntryInput) (uuid.UUID, error) {
+	ownerID, _ := middleware.GetUserIDFromContext(ctx)
+
+	tx, err := s.store.BeginTx(ctx)
 	if err != nil {
 		return uuid.Nil, err
 	}
 	defer tx.Rollback()
 
-	tournamentID := uuid.New()
-	ownerID, _ := middleware.GetUserIDFromContext(ctx)
-	tournament := bracket.Tournament{
-		ID:               tournamentID,
-		OwnerID:          ownerID,
-		Name:             name,
-		Status:           bracket.TournamentStarted,
-		Type:             tournamentType,
-		ScoreRequirement: 0,
+	if err := s.checkLimits(ctx, tx, ownerID, len(entryInputs)); err != nil {
+		return uuid.Nil, err
 	}
 
+	tournamentID := uuid.New()
+	tournament.ID = tournamentID
+	tournament.OwnerID = ownerID
+
 	if err := s.store.CreateTournament(ctx, tx, &tournament); err != nil {
 		return uuid.Nil, err
 	}
 
 	var entries []bracket.Entry
 	for i, input := range entryInputs {
+		if err := validateEntryMetadata(input.Name, input.Metadata); err != nil {
+			return uuid.Nil, err
+		}
+		linkStart, linkEnd := video.ParseClipRange(input.EmbedLink)
 		e := bracket.Entry{
-			ID:           uuid.New(),
-			TournamentID: tournamentID,
-			Name:         input.Name,
-			Seed:         i + 1,
-			EmbedLink:    utils.StringOrNil(input.EmbedLink),
+			ID:            uuid.New(),
+			TournamentID:  tournamentID,
+			Name:          input.Name,
+			Seed:          i + 1,
+			EmbedLink:     utils.StringOrNil(input.EmbedLink),
+			UploadID:      input.UploadID,
+			StartSeconds:  input.StartSeconds,
+			EndSeconds:    input.EndSeconds,
+			EntryMetadata: input.Metadata,
+		}
+		if e.StartSeconds == nil {
+			e.StartSeconds = linkStart
+		}
+		if e.EndSeconds == nil {
+			e.EndSeconds = linkEnd
+		}
+		// Only one half might come from the link, so the pair is checked once both are known
+		if e.StartSeconds != nil && e.EndSeconds != nil && *e.EndSeconds <= *e.StartSeconds {
+			return uuid.Nil, &ValidationError{Message: fmt.Sprintf("End time for entry '%s' must be after the start time", e.Name)}
 		}
 
 		entries = append(entries, e)
@@ -379,11 +536,44 @@ func (s *TournamentService) CreateTournament(ctx context.Context, name string, t
 		return uuid.Nil, err
 	}
 
+	matches := s.buildBracket(&tournament, entries)
+	if err := s.store.CreateMatches(ctx, tx, matches); err != nil {
+		return uuid.Nil, err
+	}
+	if err := recordAudit(ctx, s.store, tx, bracket.AuditEvent{
+		TournamentID: tournamentID,
+		Action:       bracket.AuditTournamentCreated,
+		After:        bracket.AuditSnapshot{Matches: matches},
+	}); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to record audit event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	metrics.TournamentsCreated.Inc()
+	return tournamentID, nil
+}
+
+// What a new tournament starts with, the group matches for a group stage and the whole bracket otherwise.
+// Entries have to be in seed order
+func (s *TournamentService) buildBracket(tournament *bracket.Tournament, entries []bracket.Entry) []bracket.Match {
+	if tournament.HasGroupStage() {
+		return generateGroupStage(tournament.ID, entries, tournament.GroupCount)
+	}
+	return s.buildElimination(tournament, entries)
+}
+
+// The elimination bracket for the tournament's format with entries placed by seed and round 1 byes already settled
+func (s *TournamentService) buildElimination(tournament *bracket.Tournament, entries []bracket.Entry) []bracket.Match {
 	var matches []bracket.Match
 	if tournament.Type == bracket.DoubleElimination {
-		matches = s.GenerateDoubleElimBracket(tournamentID, entries)
+		matches = s.GenerateDoubleElimBracket(tournament.ID, entries)
 	} else {
-		matches = s.GenerateSingleElimBracket(tournamentID, entries)
+		matches = s.GenerateSingleElimBracket(tournament.ID, entries)
+		if tournament.BronzeMatch && len(entries) >= 4 {
+			matches = addBronzeMatch(tournament.ID, matches)
+		}
 	}
 
 	if len(entries) > 1 {
@@ -473,9 +663,21 @@ func (s *TournamentService) CreateTournament(ctx context.Context, name string, t
 		}
 	}
 
-	if err := s.store.CreateMatches(ctx, tx, matches); err != nil {
-		return uuid.Nil, err
-	}
+	return matches
+}
 
-	return tournamentID, tx.Commit()
+func (s *TournamentService) checkLimits(ctx context.Context, tx store.Tx, ownerID uuid.UUID, entryCount int) error {
+	if s.limits.MaxEntries > 0 && entryCount > s.limits.MaxEntries {
+		return &ValidationError{Message: fmt.Sprintf("A tournament can have at most %d entries, this one has %d", s.limits.MaxEntries, entryCount)}
+	}
+	if s.limits.MaxTournamentsPerUser > 0 {
+		count, err := s.store.CountTournamentsByUserIDTx(ctx, tx, ownerID)
+		if err != nil {
+			return err
+		}
+		if count >= s.limits.MaxTournamentsPerUser {
+			return &ValidationError{Message: fmt.Sprintf("You've reached the limit of %d tournaments", s.limits.MaxTournamentsPerUser)}
+		}
+	}
+	return nil
 }
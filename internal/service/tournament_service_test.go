@@ -3,47 +3,29 @@ package service
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	appdb "github.com/AdamBeresnev/op-rating-app/internal/db"
 	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
 	"github.com/AdamBeresnev/op-rating-app/internal/store"
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/sqlite3"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/AdamBeresnev/op-rating-app/internal/utils"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-// setupTestDB creates an in-memory SQLite database and applies migrations
+// setupTestDB creates a SQLite database in a temp dir and applies migrations.
+// Same DSN as the server's default and opened through InitDB, so tests get the same pooled connections production does
 func setupTestDB(t *testing.T) *sqlx.DB {
 	t.Helper()
 
-	database, err := sqlx.Connect("sqlite3", "file::memory:")
-	require.NoError(t, err, "Failed to connect to in-memory DB")
-
-	database.SetMaxOpenConns(1)
-
-	_, err = database.Exec("PRAGMA foreign_keys = ON;")
-	require.NoError(t, err)
-
-	driver, err := sqlite3.WithInstance(database.DB, &sqlite3.Config{})
-	require.NoError(t, err, "Failed to create migrate driver instance")
-
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://../../migrations",
-		"sqlite3",
-		driver,
-	)
-	require.NoError(t, err, "Failed to create migrate instance")
-
-	err = m.Up()
-	if err != nil && err != migrate.ErrNoChange {
-		require.NoError(t, err, "Failed to apply migrations")
-	}
-
+	database, err := appdb.InitDB(appdb.DriverSQLite, filepath.Join(t.TempDir(), "test.db")+"?_journal_mode=WAL")
+	require.NoError(t, err, "Failed to connect to test DB")
+	require.NoError(t, appdb.RunMigrations(database), "Failed to apply migrations")
 	return database
 }
 
@@ -88,7 +70,7 @@ func TestCreateTournament(t *testing.T) {
 	defer db.Close()
 
 	tournamentStore := store.NewTournamentStore(db)
-	bracketService := NewTournamentService(db, tournamentStore)
+	bracketService := NewTournamentService(tournamentStore)
 
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, middleware.UserIDKey, uuid.MustParse(middleware.SuperUserID))
@@ -210,6 +192,94 @@ func TestCreateTournament(t *testing.T) {
 	}
 }
 
+func TestCreateTournament_Limits(t *testing.T) {
+	tournamentStore := store.NewMemoryTournamentStore()
+	tournaments := NewTournamentService(tournamentStore).WithLimits(Limits{MaxEntries: 4, MaxTournamentsPerUser: 2})
+
+	alice := context.WithValue(context.Background(), middleware.UserIDKey, uuid.New())
+	bob := context.WithValue(context.Background(), middleware.UserIDKey, uuid.New())
+	entries := []EntryInput{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}}
+
+	var invalid *ValidationError
+	_, err := tournaments.CreateTournament(alice, "Too big", bracket.SingleElimination, append(entries, EntryInput{Name: "E"}))
+	require.ErrorAs(t, err, &invalid)
+	assert.Contains(t, invalid.Message, "at most 4 entries")
+
+	for range 2 {
+		_, err := tournaments.CreateTournament(alice, "Fits", bracket.SingleElimination, entries)
+		require.NoError(t, err)
+	}
+	_, err = tournaments.CreateTournament(alice, "One too many", bracket.SingleElimination, entries)
+	require.ErrorAs(t, err, &invalid)
+	assert.Contains(t, invalid.Message, "limit of 2 tournaments")
+
+	// Everyone has their own quota
+	_, err = tournaments.CreateTournament(bob, "Bob's", bracket.SingleElimination, entries)
+	assert.NoError(t, err)
+}
+
+// Holds every transaction back until all the creates have got that far
+type gatedStore struct {
+	store.TournamentRepository
+	gate sync.WaitGroup
+}
+
+func (s *gatedStore) BeginTx(ctx context.Context) (store.Tx, error) {
+	s.gate.Done()
+	s.gate.Wait()
+	return s.TournamentRepository.BeginTx(ctx)
+}
+
+func TestCreateTournament_LimitsConcurrent(t *testing.T) {
+	tournamentStore := store.NewMemoryTournamentStore()
+	gated := &gatedStore{TournamentRepository: tournamentStore}
+	tournaments := NewTournamentService(gated).WithLimits(Limits{MaxTournamentsPerUser: 2})
+	ownerID := uuid.New()
+	owner := context.WithValue(context.Background(), middleware.UserIDKey, ownerID)
+
+	// All of them get going before any is saved, the quota still only lets two through
+	const attempts = 8
+	gated.gate.Add(attempts)
+	var wg sync.WaitGroup
+	for range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tournaments.CreateTournament(owner, "Rush", bracket.SingleElimination, []EntryInput{{Name: "A"}, {Name: "B"}})
+		}()
+	}
+	wg.Wait()
+
+	created, err := tournamentStore.GetTournamentsByUserID(owner, ownerID)
+	require.NoError(t, err)
+	assert.Len(t, created, 2)
+}
+
+func TestCreateTournament_ClipRange(t *testing.T) {
+	tournamentStore := store.NewMemoryTournamentStore()
+	tournaments := NewTournamentService(tournamentStore)
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, uuid.New())
+
+	// The form gives the start, the link the end, together they don't make a clip
+	var invalid *ValidationError
+	_, err := tournaments.CreateTournament(ctx, "Backwards", bracket.SingleElimination, []EntryInput{
+		{Name: "A", EmbedLink: "https://www.youtube.com/embed/abc?end=30", StartSeconds: utils.Ptr(60)},
+		{Name: "B"},
+	})
+	require.ErrorAs(t, err, &invalid)
+	assert.Contains(t, invalid.Message, "'A'")
+
+	id, err := tournaments.CreateTournament(ctx, "Fine", bracket.SingleElimination, []EntryInput{
+		{Name: "A", EmbedLink: "https://www.youtube.com/embed/abc?end=90", StartSeconds: utils.Ptr(60)},
+		{Name: "B"},
+	})
+	require.NoError(t, err)
+	entries, err := tournamentStore.GetEntries(ctx, id.String())
+	require.NoError(t, err)
+	assert.Equal(t, 60, *entries[0].StartSeconds)
+	assert.Equal(t, 90, *entries[0].EndSeconds)
+}
+
 func TestGenerateDoubleElimBracket(t *testing.T) {
 	service := &TournamentService{}
 
@@ -243,3 +313,21 @@ func TestGenerateDoubleElimBracket(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateTournament_Thumbnail(t *testing.T) {
+	tournaments := NewTournamentService(store.NewMemoryTournamentStore())
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, uuid.New())
+
+	var invalid *ValidationError
+	_, err := tournaments.CreateTournament(ctx, "Thumbnails", bracket.SingleElimination, []EntryInput{
+		{Name: "A", Metadata: bracket.EntryMetadata{ThumbnailURL: utils.Ptr("javascript:alert(1)")}},
+		{Name: "B"},
+	})
+	require.ErrorAs(t, err, &invalid)
+
+	_, err = tournaments.CreateTournament(ctx, "Thumbnails", bracket.SingleElimination, []EntryInput{
+		{Name: "A", Metadata: bracket.EntryMetadata{ThumbnailURL: utils.Ptr("https://example.com/a.jpg")}},
+		{Name: "B"},
+	})
+	assert.NoError(t, err)
+}
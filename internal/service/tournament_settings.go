@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/utils"
+)
+
+const (
+	maxTournamentNameLength = 100
+	maxDescriptionLength    = 2000
+)
+
+// Everything the settings page can change. Type, BronzeMatch and Groups are the format, the rest is cosmetic
+type TournamentSettings struct {
+	Name             string
+	Description      string
+	CoverImageURL    string
+	Visibility       bracket.Visibility
+	ScoreRequirement int
+	Type             bracket.TournamentType
+	BronzeMatch      bool
+	Groups           GroupStage
+	Ordering         bracket.Ordering
+}
+
+func SettingsOf(t *bracket.Tournament) TournamentSettings {
+	return TournamentSettings{
+		Name:             t.Name,
+		Description:      utils.OrZero(t.Description),
+		CoverImageURL:    utils.OrZero(t.CoverImageURL),
+		Visibility:       t.Visibility,
+		ScoreRequirement: t.ScoreRequirement,
+		Type:             t.Type,
+		BronzeMatch:      t.BronzeMatch,
+		Groups:           GroupStage{Groups: t.GroupCount, Advance: t.GroupAdvance},
+		Ordering:         t.Ordering,
+	}
+}
+
+type SettingsData struct {
+	Tournament *bracket.Tournament
+	// Someone already voted, the form shows the format but can't change it
+	FormatLocked bool
+}
+
+func (s *TournamentService) GetSettings(ctx context.Context, id string) (*SettingsData, error) {
+	tournament, err := getManagedTournament(ctx, s.store, id)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.store.GetMatches(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &SettingsData{
+		Tournament:   tournament,
+		FormatLocked: tournament.Status == bracket.TournamentCompleted || anyMatchDecided(matches),
+	}, nil
+}
+
+// Cosmetic settings and the ordering can change at any time. Changing the format throws the bracket away and seeds a new one,
+// so that's only allowed while no real match has been decided yet
+func (s *TournamentService) UpdateSettings(ctx context.Context, id string, settings TournamentSettings) error {
+	tournament, err := getManagedTournament(ctx, s.store, id)
+	if err != nil {
+		return err
+	}
+	settings, err = validateSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	before := *tournament
+	updated := *tournament
+	updated.Name = settings.Name
+	updated.Description = utils.StringOrNil(settings.Description)
+	updated.CoverImageURL = utils.StringOrNil(settings.CoverImageURL)
+	updated.Visibility = settings.Visibility
+	updated.ScoreRequirement = settings.ScoreRequirement
+	updated.Type = settings.Type
+	updated.BronzeMatch = settings.BronzeMatch
+	updated.GroupCount = settings.Groups.Groups
+	updated.GroupAdvance = settings.Groups.Advance
+	updated.Ordering = settings.Ordering
+
+	// The bronze flag means nothing for double elimination, flipping it there doesn't touch the bracket
+	formatChanged := updated.Type != before.Type ||
+		(updated.Type == bracket.SingleElimination && updated.BronzeMatch != before.BronzeMatch) ||
+		updated.GroupCount != before.GroupCount || updated.GroupAdvance != before.GroupAdvance
+
+	tx, err := s.store.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Read inside the transaction, a vote landing while the page was open must not be thrown away with the old bracket
+	var oldMatches, newMatches []bracket.Match
+	if formatChanged {
+		oldMatches, err = s.store.GetMatchesTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if tournament.Status == bracket.TournamentCompleted || anyMatchDecided(oldMatches) {
+			return ErrFormatLocked
+		}
+		entries, err := s.store.GetEntriesTx(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := validateGroupStage(settings.Groups, len(entries)); err != nil {
+			return err
+		}
+		newMatches = s.buildBracket(&updated, entries)
+	}
+
+	if err := s.store.UpdateTournamentSettingsTx(ctx, tx, &updated); err != nil {
+		return notFound(err, "tournament")
+	}
+	if formatChanged {
+		if err := s.store.DeleteMatchesTx(ctx, tx, id); err != nil {
+			return err
+		}
+		if err := s.store.CreateMatches(ctx, tx, newMatches); err != nil {
+			return err
+		}
+	}
+	if err := recordAudit(ctx, s.store, tx, bracket.AuditEvent{
+		TournamentID: tournament.ID,
+		Action:       bracket.AuditTournamentUpdated,
+		Before:       bracket.AuditSnapshot{Tournament: &before, Matches: oldMatches},
+		After:        bracket.AuditSnapshot{Tournament: &updated, Matches: newMatches},
+	}); err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Byes are settled when the bracket is generated, they don't count as someone having voted
+func anyMatchDecided(matches []bracket.Match) bool {
+	for _, m := range matches {
+		if m.Status == bracket.MatchFinished && !m.IsBye {
+			return true
+		}
+	}
+	return false
+}
+
+func validateSettings(settings TournamentSettings) (TournamentSettings, error) {
+	settings.Name = strings.TrimSpace(settings.Name)
+	settings.Description = strings.TrimSpace(settings.Description)
+	settings.CoverImageURL = strings.TrimSpace(settings.CoverImageURL)
+
+	switch {
+	case settings.Name == "":
+		return settings, &ValidationError{Message: "The tournament needs a name"}
+	case utf8.RuneCountInString(settings.Name) > maxTournamentNameLength:
+		return settings, &ValidationError{Message: fmt.Sprintf("The name can be at most %d characters", maxTournamentNameLength)}
+	case utf8.RuneCountInString(settings.Description) > maxDescriptionLength:
+		return settings, &ValidationError{Message: fmt.Sprintf("The description can be at most %d characters", maxDescriptionLength)}
+	case settings.CoverImageURL != "" && !isWebURL(settings.CoverImageURL):
+		return settings, &ValidationError{Message: "The cover image has to be an http or https link"}
+	case settings.Visibility != bracket.VisibilityPublic && settings.Visibility != bracket.VisibilityPrivate:
+		return settings, &ValidationError{Message: "Unknown visibility"}
+	case settings.Type != bracket.SingleElimination && settings.Type != bracket.DoubleElimination:
+		return settings, &ValidationError{Message: "Unknown tournament type"}
+	case settings.Ordering != bracket.OrderingStrict && settings.Ordering != bracket.OrderingFree && settings.Ordering != bracket.OrderingInterleaved:
+		return settings, &ValidationError{Message: "Unknown match ordering"}
+	case settings.ScoreRequirement < 0:
+		return settings, &ValidationError{Message: "The score requirement can't be negative"}
+	case settings.Groups.Groups < 0:
+		return settings, &ValidationError{Message: "The number of groups can't be negative"}
+	}
+	// The advance count means nothing without groups
+	if settings.Groups.Groups == 0 {
+		settings.Groups = GroupStage{}
+	}
+	return settings, nil
+}
+
+func isWebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateSettings_Validation(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournaments := NewTournamentService(store.NewTournamentStore(db))
+	owner := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+	stranger := asUser(&users.User{ID: uuid.New(), Username: "stranger"})
+
+	id, err := tournaments.CreateTournament(owner, "Mine", bracket.SingleElimination, []EntryInput{{Name: "A"}, {Name: "B"}})
+	require.NoError(t, err)
+	data, err := tournaments.GetSettings(owner, id.String())
+	require.NoError(t, err)
+	valid := SettingsOf(data.Tournament)
+
+	assert.ErrorIs(t, tournaments.UpdateSettings(stranger, id.String(), valid), ErrForbidden)
+
+	for name, change := range map[string]func(*TournamentSettings){
+		"empty name":     func(s *TournamentSettings) { s.Name = "   " },
+		"not a web link": func(s *TournamentSettings) { s.CoverImageURL = "javascript:alert(1)" },
+		"visibility":     func(s *TournamentSettings) { s.Visibility = "friends" },
+		"type":           func(s *TournamentSettings) { s.Type = "swiss" },
+		"negative score": func(s *TournamentSettings) { s.ScoreRequirement = -1 },
+	} {
+		settings := valid
+		change(&settings)
+		var invalid *ValidationError
+		assert.ErrorAs(t, tournaments.UpdateSettings(owner, id.String(), settings), &invalid, name)
+	}
+
+	valid.Name = "  Renamed  "
+	valid.Description = "All the openings"
+	valid.CoverImageURL = "https://example.com/cover.png"
+	require.NoError(t, tournaments.UpdateSettings(owner, id.String(), valid))
+
+	data, err = tournaments.GetSettings(owner, id.String())
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", data.Tournament.Name)
+	assert.Equal(t, "All the openings", *data.Tournament.Description)
+	assert.Equal(t, "https://example.com/cover.png", *data.Tournament.CoverImageURL)
+}
+
+func TestUpdateSettings_PrivateTournament(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournaments := NewTournamentService(store.NewTournamentStore(db))
+	matches := NewMatchService(store.NewTournamentStore(db))
+	owner := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+	stranger := asUser(&users.User{ID: uuid.New(), Username: "stranger"})
+
+	id, err := tournaments.CreateTournament(owner, "Secret", bracket.SingleElimination, []EntryInput{{Name: "A"}, {Name: "B"}})
+	require.NoError(t, err)
+	data, err := tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+
+	settings := SettingsOf(data.Tournament)
+	settings.Visibility = bracket.VisibilityPrivate
+	require.NoError(t, tournaments.UpdateSettings(owner, id.String(), settings))
+
+	_, err = tournaments.GetTournamentData(stranger, id.String())
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = tournaments.GetResults(stranger, id.String(), ResultsFilter{})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = matches.GetMatchViewData(stranger, data.Matches[0].ID.String())
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = matches.AdvanceWinner(stranger, data.Matches[0].ID, *data.Matches[0].Entry1ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = tournaments.GetTournamentData(owner, id.String())
+	assert.NoError(t, err)
+}
+
+func TestUpdateSettings_FormatChange(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	tournamentStore := store.NewTournamentStore(db)
+	tournaments := NewTournamentService(tournamentStore)
+	matchService := NewMatchService(tournamentStore)
+	owner := asUser(&users.User{ID: uuid.MustParse(middleware.SuperUserID), Username: "owner"})
+
+	id, err := tournaments.CreateTournament(owner, "Bronze", bracket.SingleElimination, []EntryInput{
+		{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"},
+	})
+	require.NoError(t, err)
+	data, err := tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	require.Len(t, data.Matches, 3)
+
+	settings := SettingsOf(data.Tournament)
+	settings.BronzeMatch = true
+	require.NoError(t, tournaments.UpdateSettings(owner, id.String(), settings))
+
+	data, err = tournaments.GetTournamentData(owner, id.String())
+	require.NoError(t, err)
+	require.Len(t, data.Matches, 4)
+
+	// The final alone doesn't finish it while the bronze match is still open
+	decide := func(side bracket.BracketSide, round int) {
+		t.Helper()
+		for _, m := range data.Matches {
+			if m.BracketSide != side || m.RoundNumber != round {
+				continue
+			}
+			current, err := tournamentStore.GetMatch(owner, m.ID.String())
+			require.NoError(t, err)
+			_, err = matchService.AdvanceWinner(owner, m.ID, *current.Entry1ID)
+			require.NoError(t, err)
+		}
+	}
+	decide(bracket.WinnersSide, 1)
+
+	settings.Type = bracket.DoubleElimination
+	assert.ErrorIs(t, tournaments.UpdateSettings(owner, id.String(), settings), ErrFormatLocked)
+
+	// Everything but the format still works
+	settings.Type = bracket.SingleElimination
+	settings.Name = "Bronze, renamed"
+	require.NoError(t, tournaments.UpdateSettings(owner, id.String(), settings))
+
+	decide(bracket.WinnersSide, 2)
+	tournament, err := tournamentStore.GetTournament(owner, id.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.TournamentStarted, tournament.Status)
+
+	decide(bracket.FinalsSide, 1)
+	tournament, err = tournamentStore.GetTournament(owner, id.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.TournamentCompleted, tournament.Status)
+
+	events, err := tournaments.GetAuditLog(owner, id.String())
+	require.NoError(t, err)
+	var updates int
+	for _, e := range events {
+		if e.Action == bracket.AuditTournamentUpdated {
+			updates++
+		}
+	}
+	assert.Equal(t, 2, updates)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/media"
+	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
+	"github.com/AdamBeresnev/op-rating-app/internal/store"
+	"github.com/google/uuid"
+)
+
+type UploadService struct {
+	library *media.Library
+	store   store.UploadRepository
+}
+
+func NewUploadService(library *media.Library, store store.UploadRepository) *UploadService {
+	return &UploadService{library: library, store: store}
+}
+
+func (s *UploadService) Upload(ctx context.Context, filename string, r io.Reader) (*media.Upload, error) {
+	ownerID, ok := middleware.GetUserIDFromContext(ctx)
+	if !ok {
+		return nil, ErrUnauthenticated
+	}
+
+	id := uuid.New()
+	contentType, size, err := s.library.Save(id, r)
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &media.Upload{
+		ID:           id,
+		OwnerID:      ownerID,
+		OriginalName: filepath.Base(filename),
+		ContentType:  contentType,
+		Size:         size,
+	}
+	if err := s.store.CreateUpload(ctx, upload); err != nil {
+		// Don't leave orphaned files around if the DB write fails
+		s.library.Remove(upload)
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+func (s *UploadService) GetUpload(ctx context.Context, id string) (*media.Upload, error) {
+	upload, err := s.store.GetUpload(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "media")
+	}
+	return upload, nil
+}
+
+// Same as GetUpload, but only lets people attach their own files to entries
+func (s *UploadService) GetUploadForUser(ctx context.Context, id string) (*media.Upload, error) {
+	upload, err := s.GetUpload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	userID, _ := middleware.GetUserIDFromContext(ctx)
+	if upload.OwnerID != userID {
+		return nil, ErrForbidden
+	}
+	return upload, nil
+}
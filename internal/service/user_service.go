@@ -3,22 +3,27 @@ package service
 import (
 	"context"
 	"database/sql"
+	"fmt"
 
 	"github.com/AdamBeresnev/op-rating-app/internal/store"
 	users "github.com/AdamBeresnev/op-rating-app/internal/user"
 	"github.com/AdamBeresnev/op-rating-app/internal/utils"
 	"github.com/google/uuid"
-	"github.com/jmoiron/sqlx"
 	"github.com/markbates/goth"
 )
 
+var (
+	guestUserID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	// The one and only user in local mode
+	localUserID = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+)
+
 type UserService struct {
-	db    *sqlx.DB
-	store *store.UserStore
+	store store.UserRepository
 }
 
-func NewUserService(db *sqlx.DB, store *store.UserStore) *UserService {
-	return &UserService{db: db, store: store}
+func NewUserService(store store.UserRepository) *UserService {
+	return &UserService{store: store}
 }
 
 func (s *UserService) FindOrCreateUserByProvider(ctx context.Context, gothUser goth.User) (*users.User, error) {
@@ -59,8 +64,44 @@ func (s *UserService) FindOrCreateUserByProvider(ctx context.Context, gothUser g
 	return nil, err
 }
 
+func (s *UserService) GetUser(ctx context.Context, id string) (*users.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, sql.ErrNoRows
+	}
+	return s.store.GetUser(ctx, userID)
+}
+
+func (s *UserService) ListUsers(ctx context.Context) ([]users.User, error) {
+	return s.store.ListUsers(ctx)
+}
+
+func (s *UserService) PromoteUser(ctx context.Context, id string) (*users.User, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.SetUserAdmin(ctx, user.ID, true); err != nil {
+		return nil, err
+	}
+	user.IsAdmin = true
+	return user, nil
+}
+
+// Takes the user's tournaments with them. The shared guest account can't be deleted, everyone without a login uses it
+func (s *UserService) DeleteUser(ctx context.Context, id string) error {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.ID == guestUserID {
+		return fmt.Errorf("the guest user can't be deleted")
+	}
+	return s.store.DeleteUser(ctx, user.ID)
+}
+
 func (s *UserService) EnsureGuestUser(ctx context.Context) (*users.User, error) {
-	guestID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	guestID := guestUserID
 	user, err := s.store.GetUser(ctx, guestID)
 	if err == nil {
 		return user, nil
@@ -77,3 +118,26 @@ func (s *UserService) EnsureGuestUser(ctx context.Context) (*users.User, error)
 	}
 	return nil, err
 }
+
+// Local mode logs everyone in as this user. name is only used the first time, after that it's whatever is stored
+func (s *UserService) EnsureLocalUser(ctx context.Context, name string) (*users.User, error) {
+	user, err := s.store.GetUser(ctx, localUserID)
+	if err == nil {
+		return user, nil
+	}
+
+	if err == sql.ErrNoRows {
+		localUser := &users.User{
+			ID:       localUserID,
+			Email:    "local@op-rating.app",
+			Username: name,
+			IsAdmin:  true,
+		}
+		if err := s.store.CreateUser(ctx, localUser); err != nil {
+			return nil, err
+		}
+		// CreateUser doesn't write the admin flag
+		return localUser, s.store.SetUserAdmin(ctx, localUserID, true)
+	}
+	return nil, err
+}
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/media"
+	"github.com/AdamBeresnev/op-rating-app/internal/metrics"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
+)
+
+// Wrappers that time every repository call into the db_query_duration_seconds histogram.
+// Only the SQL stores get wrapped, the memory ones aren't worth measuring
+
+type instrumentedTournamentRepository struct {
+	next TournamentRepository
+}
+
+func InstrumentTournaments(next TournamentRepository) TournamentRepository {
+	return &instrumentedTournamentRepository{next: next}
+}
+
+func (s *instrumentedTournamentRepository) BeginTx(ctx context.Context) (Tx, error) {
+	defer metrics.ObserveQuery("tournament", "BeginTx", time.Now())
+	return s.next.BeginTx(ctx)
+}
+
+func (s *instrumentedTournamentRepository) CreateTournament(ctx context.Context, tx Tx, tournament *bracket.Tournament) error {
+	defer metrics.ObserveQuery("tournament", "CreateTournament", time.Now())
+	return s.next.CreateTournament(ctx, tx, tournament)
+}
+
+func (s *instrumentedTournamentRepository) CreateEntries(ctx context.Context, tx Tx, entries []bracket.Entry) error {
+	defer metrics.ObserveQuery("tournament", "CreateEntries", time.Now())
+	return s.next.CreateEntries(ctx, tx, entries)
+}
+
+func (s *instrumentedTournamentRepository) CreateMatches(ctx context.Context, tx Tx, matches []bracket.Match) error {
+	defer metrics.ObserveQuery("tournament", "CreateMatches", time.Now())
+	return s.next.CreateMatches(ctx, tx, matches)
+}
+
+func (s *instrumentedTournamentRepository) GetTournament(ctx context.Context, id string) (*bracket.Tournament, error) {
+	defer metrics.ObserveQuery("tournament", "GetTournament", time.Now())
+	return s.next.GetTournament(ctx, id)
+}
+
+func (s *instrumentedTournamentRepository) GetTournamentsByUserID(ctx context.Context, userID uuid.UUID) ([]bracket.Tournament, error) {
+	defer metrics.ObserveQuery("tournament", "GetTournamentsByUserID", time.Now())
+	return s.next.GetTournamentsByUserID(ctx, userID)
+}
+
+func (s *instrumentedTournamentRepository) CountTournamentsByUserIDTx(ctx context.Context, tx Tx, userID uuid.UUID) (int, error) {
+	defer metrics.ObserveQuery("tournament", "CountTournamentsByUserIDTx", time.Now())
+	return s.next.CountTournamentsByUserIDTx(ctx, tx, userID)
+}
+
+func (s *instrumentedTournamentRepository) ListTournaments(ctx context.Context) ([]bracket.Tournament, error) {
+	defer metrics.ObserveQuery("tournament", "ListTournaments", time.Now())
+	return s.next.ListTournaments(ctx)
+}
+
+func (s *instrumentedTournamentRepository) GetEntries(ctx context.Context, tournamentID string) ([]bracket.Entry, error) {
+	defer metrics.ObserveQuery("tournament", "GetEntries", time.Now())
+	return s.next.GetEntries(ctx, tournamentID)
+}
+
+func (s *instrumentedTournamentRepository) GetEntry(ctx context.Context, id string) (*bracket.Entry, error) {
+	defer metrics.ObserveQuery("tournament", "GetEntry", time.Now())
+	return s.next.GetEntry(ctx, id)
+}
+
+func (s *instrumentedTournamentRepository) GetMatches(ctx context.Context, tournamentID string) ([]bracket.Match, error) {
+	defer metrics.ObserveQuery("tournament", "GetMatches", time.Now())
+	return s.next.GetMatches(ctx, tournamentID)
+}
+
+func (s *instrumentedTournamentRepository) GetMatch(ctx context.Context, id string) (*bracket.Match, error) {
+	defer metrics.ObserveQuery("tournament", "GetMatch", time.Now())
+	return s.next.GetMatch(ctx, id)
+}
+
+func (s *instrumentedTournamentRepository) GetMatchTx(ctx context.Context, tx Tx, id string) (*bracket.Match, error) {
+	defer metrics.ObserveQuery("tournament", "GetMatchTx", time.Now())
+	return s.next.GetMatchTx(ctx, tx, id)
+}
+
+func (s *instrumentedTournamentRepository) GetMatchesTx(ctx context.Context, tx Tx, tournamentID string) ([]bracket.Match, error) {
+	defer metrics.ObserveQuery("tournament", "GetMatchesTx", time.Now())
+	return s.next.GetMatchesTx(ctx, tx, tournamentID)
+}
+
+func (s *instrumentedTournamentRepository) GetEntriesTx(ctx context.Context, tx Tx, tournamentID string) ([]bracket.Entry, error) {
+	defer metrics.ObserveQuery("tournament", "GetEntriesTx", time.Now())
+	return s.next.GetEntriesTx(ctx, tx, tournamentID)
+}
+
+func (s *instrumentedTournamentRepository) UpdateMatch(ctx context.Context, tx Tx, match *bracket.Match) error {
+	defer metrics.ObserveQuery("tournament", "UpdateMatch", time.Now())
+	return s.next.UpdateMatch(ctx, tx, match)
+}
+
+func (s *instrumentedTournamentRepository) UpdateTournamentStatusTx(ctx context.Context, tx Tx, tournamentID string, status bracket.TournamentStatus) error {
+	defer metrics.ObserveQuery("tournament", "UpdateTournamentStatusTx", time.Now())
+	return s.next.UpdateTournamentStatusTx(ctx, tx, tournamentID, status)
+}
+
+func (s *instrumentedTournamentRepository) SetTournamentArchivedTx(ctx context.Context, tx Tx, tournamentID string, archivedAt *time.Time) error {
+	defer metrics.ObserveQuery("tournament", "SetTournamentArchivedTx", time.Now())
+	return s.next.SetTournamentArchivedTx(ctx, tx, tournamentID, archivedAt)
+}
+
+func (s *instrumentedTournamentRepository) UpdateTournamentSettingsTx(ctx context.Context, tx Tx, tournament *bracket.Tournament) error {
+	defer metrics.ObserveQuery("tournament", "UpdateTournamentSettingsTx", time.Now())
+	return s.next.UpdateTournamentSettingsTx(ctx, tx, tournament)
+}
+
+func (s *instrumentedTournamentRepository) DeleteMatchesTx(ctx context.Context, tx Tx, tournamentID string) error {
+	defer metrics.ObserveQuery("tournament", "DeleteMatchesTx", time.Now())
+	return s.next.DeleteMatchesTx(ctx, tx, tournamentID)
+}
+
+func (s *instrumentedTournamentRepository) HasPendingMatchesTx(ctx context.Context, tx Tx, tournamentID string) (bool, error) {
+	defer metrics.ObserveQuery("tournament", "HasPendingMatchesTx", time.Now())
+	return s.next.HasPendingMatchesTx(ctx, tx, tournamentID)
+}
+
+func (s *instrumentedTournamentRepository) GetActiveLinkedEntries(ctx context.Context) ([]bracket.Entry, error) {
+	defer metrics.ObserveQuery("tournament", "GetActiveLinkedEntries", time.Now())
+	return s.next.GetActiveLinkedEntries(ctx)
+}
+
+func (s *instrumentedTournamentRepository) UpdateEntryLinkStatus(ctx context.Context, entryID uuid.UUID, status bracket.LinkStatus, checkedAt time.Time) error {
+	defer metrics.ObserveQuery("tournament", "UpdateEntryLinkStatus", time.Now())
+	return s.next.UpdateEntryLinkStatus(ctx, entryID, status, checkedAt)
+}
+
+func (s *instrumentedTournamentRepository) UpdateEntryMetadata(ctx context.Context, tx Tx, entry *bracket.Entry) error {
+	defer metrics.ObserveQuery("tournament", "UpdateEntryMetadata", time.Now())
+	return s.next.UpdateEntryMetadata(ctx, tx, entry)
+}
+
+func (s *instrumentedTournamentRepository) DisqualifyEntryTx(ctx context.Context, tx Tx, entryID string, at time.Time) error {
+	defer metrics.ObserveQuery("tournament", "DisqualifyEntryTx", time.Now())
+	return s.next.DisqualifyEntryTx(ctx, tx, entryID, at)
+}
+
+func (s *instrumentedTournamentRepository) DeleteTournament(ctx context.Context, tx Tx, id string) error {
+	defer metrics.ObserveQuery("tournament", "DeleteTournament", time.Now())
+	return s.next.DeleteTournament(ctx, tx, id)
+}
+
+func (s *instrumentedTournamentRepository) CreateAuditEvent(ctx context.Context, tx Tx, event *bracket.AuditEvent) error {
+	defer metrics.ObserveQuery("tournament", "CreateAuditEvent", time.Now())
+	return s.next.CreateAuditEvent(ctx, tx, event)
+}
+
+func (s *instrumentedTournamentRepository) GetAuditEvents(ctx context.Context, tournamentID string) ([]bracket.AuditEvent, error) {
+	defer metrics.ObserveQuery("tournament", "GetAuditEvents", time.Now())
+	return s.next.GetAuditEvents(ctx, tournamentID)
+}
+
+type instrumentedUserRepository struct {
+	next UserRepository
+}
+
+func InstrumentUsers(next UserRepository) UserRepository {
+	return &instrumentedUserRepository{next: next}
+}
+
+func (s *instrumentedUserRepository) GetUser(ctx context.Context, id interface{}) (*users.User, error) {
+	defer metrics.ObserveQuery("user", "GetUser", time.Now())
+	return s.next.GetUser(ctx, id)
+}
+
+func (s *instrumentedUserRepository) GetUserByProvider(ctx context.Context, provider string, providerID string) (*users.User, error) {
+	defer metrics.ObserveQuery("user", "GetUserByProvider", time.Now())
+	return s.next.GetUserByProvider(ctx, provider, providerID)
+}
+
+func (s *instrumentedUserRepository) GetUserByEmail(ctx context.Context, email string) (*users.User, error) {
+	defer metrics.ObserveQuery("user", "GetUserByEmail", time.Now())
+	return s.next.GetUserByEmail(ctx, email)
+}
+
+func (s *instrumentedUserRepository) CreateUser(ctx context.Context, user *users.User) error {
+	defer metrics.ObserveQuery("user", "CreateUser", time.Now())
+	return s.next.CreateUser(ctx, user)
+}
+
+func (s *instrumentedUserRepository) UpdateUserNameAndAvatar(ctx context.Context, user *users.User) error {
+	defer metrics.ObserveQuery("user", "UpdateUserNameAndAvatar", time.Now())
+	return s.next.UpdateUserNameAndAvatar(ctx, user)
+}
+
+func (s *instrumentedUserRepository) ListUsers(ctx context.Context) ([]users.User, error) {
+	defer metrics.ObserveQuery("user", "ListUsers", time.Now())
+	return s.next.ListUsers(ctx)
+}
+
+func (s *instrumentedUserRepository) SetUserAdmin(ctx context.Context, id uuid.UUID, isAdmin bool) error {
+	defer metrics.ObserveQuery("user", "SetUserAdmin", time.Now())
+	return s.next.SetUserAdmin(ctx, id, isAdmin)
+}
+
+func (s *instrumentedUserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	defer metrics.ObserveQuery("user", "DeleteUser", time.Now())
+	return s.next.DeleteUser(ctx, id)
+}
+
+type instrumentedUploadRepository struct {
+	next UploadRepository
+}
+
+func InstrumentUploads(next UploadRepository) UploadRepository {
+	return &instrumentedUploadRepository{next: next}
+}
+
+func (s *instrumentedUploadRepository) CreateUpload(ctx context.Context, upload *media.Upload) error {
+	defer metrics.ObserveQuery("upload", "CreateUpload", time.Now())
+	return s.next.CreateUpload(ctx, upload)
+}
+
+func (s *instrumentedUploadRepository) GetUpload(ctx context.Context, id string) (*media.Upload, error) {
+	defer metrics.ObserveQuery("upload", "GetUpload", time.Now())
+	return s.next.GetUpload(ctx, id)
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Key/value cache for responses from external metadata APIs
+type LookupCacheStore struct {
+	db *sqlx.DB
+}
+
+const (
+	getLookupCacheQuery = "SELECT payload, fetched_at FROM lookup_cache WHERE cache_key = ?"
+	putLookupCacheQuery = `INSERT INTO lookup_cache (cache_key, payload, fetched_at) VALUES (?, ?, ?)
+		ON CONFLICT(cache_key) DO UPDATE SET payload = excluded.payload, fetched_at = excluded.fetched_at`
+)
+
+func NewLookupCacheStore(db *sqlx.DB) *LookupCacheStore {
+	return &LookupCacheStore{db: db}
+}
+
+// Returns ok = false on a cache miss
+func (s *LookupCacheStore) Get(ctx context.Context, key string) (payload []byte, fetchedAt time.Time, ok bool, err error) {
+	var row struct {
+		Payload   string    `db:"payload"`
+		FetchedAt time.Time `db:"fetched_at"`
+	}
+	err = s.db.GetContext(ctx, &row, s.db.Rebind(getLookupCacheQuery), key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+	return []byte(row.Payload), row.FetchedAt, true, nil
+}
+
+func (s *LookupCacheStore) Put(ctx context.Context, key string, payload []byte) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(putLookupCacheQuery), key, string(payload), time.Now().UTC())
+	return err
+}
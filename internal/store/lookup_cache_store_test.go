@@ -0,0 +1,31 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCache(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	cache := NewLookupCacheStore(db)
+	ctx := context.Background()
+
+	_, _, ok, err := cache.Get(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Put(ctx, "key", []byte(`{"name": "first"}`)))
+	require.NoError(t, cache.Put(ctx, "key", []byte(`{"name": "second"}`)))
+
+	payload, fetchedAt, ok, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"name": "second"}`, string(payload))
+	assert.WithinDuration(t, time.Now(), fetchedAt, time.Minute)
+}
@@ -0,0 +1,734 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/media"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
+)
+
+// In-memory versions of the stores, for fast tests and --demo mode. Nothing ever touches disk.
+
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+type memoryTournamentState struct {
+	tournaments map[uuid.UUID]bracket.Tournament
+	entries     map[uuid.UUID]bracket.Entry
+	matches     map[uuid.UUID]bracket.Match
+	// Insertion order, so ties sort the same way they do in SQL
+	entryOrder []uuid.UUID
+	matchOrder []uuid.UUID
+	audit      []bracket.AuditEvent
+}
+
+func newMemoryTournamentState() *memoryTournamentState {
+	return &memoryTournamentState{
+		tournaments: make(map[uuid.UUID]bracket.Tournament),
+		entries:     make(map[uuid.UUID]bracket.Entry),
+		matches:     make(map[uuid.UUID]bracket.Match),
+	}
+}
+
+func (s *memoryTournamentState) clone() *memoryTournamentState {
+	c := &memoryTournamentState{
+		tournaments: make(map[uuid.UUID]bracket.Tournament, len(s.tournaments)),
+		entries:     make(map[uuid.UUID]bracket.Entry, len(s.entries)),
+		matches:     make(map[uuid.UUID]bracket.Match, len(s.matches)),
+		entryOrder:  append([]uuid.UUID(nil), s.entryOrder...),
+		matchOrder:  append([]uuid.UUID(nil), s.matchOrder...),
+		audit:       append([]bracket.AuditEvent(nil), s.audit...),
+	}
+	for k, v := range s.tournaments {
+		c.tournaments[k] = v
+	}
+	for k, v := range s.entries {
+		c.entries[k] = v
+	}
+	for k, v := range s.matches {
+		c.matches[k] = v
+	}
+	return c
+}
+
+// Writers are serialized the same way SQLite does it: a transaction works on its own copy of the data
+// and swaps it in on commit, so a rollback just throws the copy away. Reads never wait on a transaction.
+// Don't call the non-Tx write methods while holding a transaction on the same goroutine, they'd wait forever.
+type MemoryTournamentStore struct {
+	writeMu sync.Mutex
+	mu      sync.RWMutex
+	state   *memoryTournamentState
+}
+
+func NewMemoryTournamentStore() *MemoryTournamentStore {
+	return &MemoryTournamentStore{state: newMemoryTournamentState()}
+}
+
+type memoryTx struct {
+	store *MemoryTournamentStore
+	state *memoryTournamentState
+	done  bool
+}
+
+func (tx *memoryTx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.store.mu.Lock()
+	tx.store.state = tx.state
+	tx.store.mu.Unlock()
+	tx.store.writeMu.Unlock()
+	return nil
+}
+
+func (tx *memoryTx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.store.writeMu.Unlock()
+	return nil
+}
+
+func (s *MemoryTournamentStore) BeginTx(ctx context.Context) (Tx, error) {
+	s.writeMu.Lock()
+	s.mu.RLock()
+	state := s.state.clone()
+	s.mu.RUnlock()
+	return &memoryTx{store: s, state: state}, nil
+}
+
+func (s *MemoryTournamentStore) txState(tx Tx) (*memoryTournamentState, error) {
+	t, ok := tx.(*memoryTx)
+	if !ok || t.store != s {
+		panic(fmt.Sprintf("store: transaction %T does not belong to this memory store", tx))
+	}
+	if t.done {
+		return nil, ErrTxDone
+	}
+	return t.state, nil
+}
+
+// Runs fn against the committed data
+func (s *MemoryTournamentStore) read(fn func(state *memoryTournamentState)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn(s.state)
+}
+
+// Single statement writes outside a transaction, still have to queue behind open transactions
+func (s *MemoryTournamentStore) write(fn func(state *memoryTournamentState) error) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.state)
+}
+
+func (s *MemoryTournamentStore) CreateTournament(ctx context.Context, tx Tx, tournament *bracket.Tournament) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	if _, ok := state.tournaments[tournament.ID]; ok {
+		return fmt.Errorf("tournament %s already exists", tournament.ID)
+	}
+	setTournamentDefaults(tournament)
+	t := *tournament
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now().UTC()
+	}
+	state.tournaments[t.ID] = t
+	return nil
+}
+
+func (s *MemoryTournamentStore) CreateEntries(ctx context.Context, tx Tx, entries []bracket.Entry) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, ok := state.entries[e.ID]; ok {
+			return fmt.Errorf("entry %s already exists", e.ID)
+		}
+		if _, ok := state.tournaments[e.TournamentID]; !ok {
+			return fmt.Errorf("entry %s references unknown tournament %s", e.ID, e.TournamentID)
+		}
+		state.entries[e.ID] = e
+		state.entryOrder = append(state.entryOrder, e.ID)
+	}
+	return nil
+}
+
+func (s *MemoryTournamentStore) CreateMatches(ctx context.Context, tx Tx, matches []bracket.Match) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	for _, m := range matches {
+		if _, ok := state.matches[m.ID]; ok {
+			return fmt.Errorf("match %s already exists", m.ID)
+		}
+		if _, ok := state.tournaments[m.TournamentID]; !ok {
+			return fmt.Errorf("match %s references unknown tournament %s", m.ID, m.TournamentID)
+		}
+		if m.CreatedAt.IsZero() {
+			m.CreatedAt = time.Now().UTC()
+		}
+		state.matches[m.ID] = m
+		state.matchOrder = append(state.matchOrder, m.ID)
+	}
+	return nil
+}
+
+func (s *MemoryTournamentStore) GetTournament(ctx context.Context, id string) (*bracket.Tournament, error) {
+	tournamentID, err := uuid.Parse(id)
+	if err != nil {
+		return &bracket.Tournament{}, sql.ErrNoRows
+	}
+	var tournament bracket.Tournament
+	var ok bool
+	s.read(func(state *memoryTournamentState) {
+		tournament, ok = state.tournaments[tournamentID]
+	})
+	if !ok {
+		return &tournament, sql.ErrNoRows
+	}
+	return &tournament, nil
+}
+
+func (s *MemoryTournamentStore) GetTournamentsByUserID(ctx context.Context, userID uuid.UUID) ([]bracket.Tournament, error) {
+	var tournaments []bracket.Tournament
+	s.read(func(state *memoryTournamentState) {
+		for _, t := range state.tournaments {
+			if t.OwnerID == userID {
+				tournaments = append(tournaments, t)
+			}
+		}
+	})
+	sort.SliceStable(tournaments, func(i, j int) bool {
+		return tournaments[i].CreatedAt.After(tournaments[j].CreatedAt)
+	})
+	return tournaments, nil
+}
+
+func (s *MemoryTournamentStore) CountTournamentsByUserIDTx(ctx context.Context, tx Tx, userID uuid.UUID) (int, error) {
+	state, err := s.txState(tx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, t := range state.tournaments {
+		if t.OwnerID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryTournamentStore) GetEntries(ctx context.Context, tournamentID string) ([]bracket.Entry, error) {
+	var entries []bracket.Entry
+	s.read(func(state *memoryTournamentState) {
+		entries = sortedEntries(state, tournamentID)
+	})
+	return entries, nil
+}
+
+func (s *MemoryTournamentStore) GetEntriesTx(ctx context.Context, tx Tx, tournamentID string) ([]bracket.Entry, error) {
+	state, err := s.txState(tx)
+	if err != nil {
+		return nil, err
+	}
+	return sortedEntries(state, tournamentID), nil
+}
+
+// Seed order, same as the SQL query
+func sortedEntries(state *memoryTournamentState, tournamentID string) []bracket.Entry {
+	var entries []bracket.Entry
+	for _, id := range state.entryOrder {
+		if e := state.entries[id]; e.TournamentID.String() == tournamentID {
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Seed < entries[j].Seed })
+	return entries
+}
+
+func (s *MemoryTournamentStore) GetEntry(ctx context.Context, id string) (*bracket.Entry, error) {
+	entryID, err := uuid.Parse(id)
+	if err != nil {
+		return &bracket.Entry{}, sql.ErrNoRows
+	}
+	var entry bracket.Entry
+	var ok bool
+	s.read(func(state *memoryTournamentState) {
+		entry, ok = state.entries[entryID]
+	})
+	if !ok {
+		return &entry, sql.ErrNoRows
+	}
+	return &entry, nil
+}
+
+// Same order as the SQL store: round, then match order, then insertion order
+func sortedMatches(state *memoryTournamentState, keep func(m *bracket.Match) bool) []bracket.Match {
+	var matches []bracket.Match
+	for _, id := range state.matchOrder {
+		m := state.matches[id]
+		if keep(&m) {
+			matches = append(matches, m)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].RoundNumber != matches[j].RoundNumber {
+			return matches[i].RoundNumber < matches[j].RoundNumber
+		}
+		return matches[i].MatchOrder < matches[j].MatchOrder
+	})
+	return matches
+}
+
+func (s *MemoryTournamentStore) GetMatches(ctx context.Context, tournamentID string) ([]bracket.Match, error) {
+	var matches []bracket.Match
+	s.read(func(state *memoryTournamentState) {
+		matches = sortedMatches(state, func(m *bracket.Match) bool {
+			return m.TournamentID.String() == tournamentID
+		})
+	})
+	return matches, nil
+}
+
+func getMemoryMatch(state *memoryTournamentState, id string) (*bracket.Match, error) {
+	matchID, err := uuid.Parse(id)
+	if err != nil {
+		return &bracket.Match{}, sql.ErrNoRows
+	}
+	match, ok := state.matches[matchID]
+	if !ok {
+		return &match, sql.ErrNoRows
+	}
+	return &match, nil
+}
+
+func (s *MemoryTournamentStore) GetMatch(ctx context.Context, id string) (*bracket.Match, error) {
+	var match *bracket.Match
+	var err error
+	s.read(func(state *memoryTournamentState) {
+		match, err = getMemoryMatch(state, id)
+	})
+	return match, err
+}
+
+func (s *MemoryTournamentStore) GetMatchTx(ctx context.Context, tx Tx, id string) (*bracket.Match, error) {
+	state, err := s.txState(tx)
+	if err != nil {
+		return nil, err
+	}
+	return getMemoryMatch(state, id)
+}
+
+func (s *MemoryTournamentStore) GetMatchesTx(ctx context.Context, tx Tx, tournamentID string) ([]bracket.Match, error) {
+	state, err := s.txState(tx)
+	if err != nil {
+		return nil, err
+	}
+	return sortedMatches(state, func(m *bracket.Match) bool {
+		return m.TournamentID.String() == tournamentID
+	}), nil
+}
+
+func (s *MemoryTournamentStore) UpdateMatch(ctx context.Context, tx Tx, match *bracket.Match) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	existing, ok := state.matches[match.ID]
+	if !ok {
+		// UPDATE on a missing row isn't an error in SQL either
+		return nil
+	}
+	updated := *match
+	updated.CreatedAt = existing.CreatedAt
+	state.matches[match.ID] = updated
+	return nil
+}
+
+func (s *MemoryTournamentStore) UpdateTournamentStatusTx(ctx context.Context, tx Tx, tournamentID string, status bracket.TournamentStatus) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(tournamentID)
+	if err != nil {
+		return nil
+	}
+	if t, ok := state.tournaments[id]; ok {
+		t.Status = status
+		state.tournaments[id] = t
+	}
+	return nil
+}
+
+func (s *MemoryTournamentStore) SetTournamentArchivedTx(ctx context.Context, tx Tx, tournamentID string, archivedAt *time.Time) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(tournamentID)
+	if err != nil {
+		return sql.ErrNoRows
+	}
+	t, ok := state.tournaments[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	t.ArchivedAt = archivedAt
+	state.tournaments[id] = t
+	return nil
+}
+
+func (s *MemoryTournamentStore) UpdateTournamentSettingsTx(ctx context.Context, tx Tx, tournament *bracket.Tournament) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	t, ok := state.tournaments[tournament.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	t.Name = tournament.Name
+	t.Type = tournament.Type
+	t.ScoreRequirement = tournament.ScoreRequirement
+	t.Description = tournament.Description
+	t.CoverImageURL = tournament.CoverImageURL
+	t.Visibility = tournament.Visibility
+	t.BronzeMatch = tournament.BronzeMatch
+	t.Ordering = tournament.Ordering
+	t.GroupCount = tournament.GroupCount
+	t.GroupAdvance = tournament.GroupAdvance
+	state.tournaments[tournament.ID] = t
+	return nil
+}
+
+func (s *MemoryTournamentStore) DeleteMatchesTx(ctx context.Context, tx Tx, tournamentID string) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	state.matchOrder = slices.DeleteFunc(state.matchOrder, func(id uuid.UUID) bool {
+		if state.matches[id].TournamentID.String() != tournamentID {
+			return false
+		}
+		delete(state.matches, id)
+		return true
+	})
+	return nil
+}
+
+func (s *MemoryTournamentStore) HasPendingMatchesTx(ctx context.Context, tx Tx, tournamentID string) (bool, error) {
+	state, err := s.txState(tx)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range state.matches {
+		if m.TournamentID.String() == tournamentID && m.Status != bracket.MatchFinished {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryTournamentStore) GetActiveLinkedEntries(ctx context.Context) ([]bracket.Entry, error) {
+	var entries []bracket.Entry
+	s.read(func(state *memoryTournamentState) {
+		for _, id := range state.entryOrder {
+			e := state.entries[id]
+			t, ok := state.tournaments[e.TournamentID]
+			if ok && t.Status != bracket.TournamentCompleted && e.EmbedLink != nil && e.UploadID == nil {
+				entries = append(entries, e)
+			}
+		}
+	})
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].TournamentID != entries[j].TournamentID {
+			return entries[i].TournamentID.String() < entries[j].TournamentID.String()
+		}
+		return entries[i].Seed < entries[j].Seed
+	})
+	return entries, nil
+}
+
+func (s *MemoryTournamentStore) UpdateEntryLinkStatus(ctx context.Context, entryID uuid.UUID, status bracket.LinkStatus, checkedAt time.Time) error {
+	return s.write(func(state *memoryTournamentState) error {
+		if e, ok := state.entries[entryID]; ok {
+			e.LinkStatus = &status
+			e.LinkCheckedAt = &checkedAt
+			state.entries[entryID] = e
+		}
+		return nil
+	})
+}
+
+func (s *MemoryTournamentStore) UpdateEntryMetadata(ctx context.Context, tx Tx, entry *bracket.Entry) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	if e, ok := state.entries[entry.ID]; ok {
+		e.Name = entry.Name
+		e.EntryMetadata = entry.EntryMetadata
+		state.entries[entry.ID] = e
+	}
+	return nil
+}
+
+func (s *MemoryTournamentStore) DisqualifyEntryTx(ctx context.Context, tx Tx, entryID string, at time.Time) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	id, err := uuid.Parse(entryID)
+	if err != nil {
+		return sql.ErrNoRows
+	}
+	e, ok := state.entries[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	e.DisqualifiedAt = &at
+	state.entries[id] = e
+	return nil
+}
+
+func (s *MemoryTournamentStore) ListTournaments(ctx context.Context) ([]bracket.Tournament, error) {
+	var tournaments []bracket.Tournament
+	s.read(func(state *memoryTournamentState) {
+		for _, t := range state.tournaments {
+			tournaments = append(tournaments, t)
+		}
+	})
+	sort.SliceStable(tournaments, func(i, j int) bool {
+		return tournaments[i].CreatedAt.After(tournaments[j].CreatedAt)
+	})
+	return tournaments, nil
+}
+
+func (s *MemoryTournamentStore) DeleteTournament(ctx context.Context, tx Tx, id string) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	tournamentID, err := uuid.Parse(id)
+	if err != nil {
+		return sql.ErrNoRows
+	}
+	if _, ok := state.tournaments[tournamentID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(state.tournaments, tournamentID)
+	state.entryOrder = slices.DeleteFunc(state.entryOrder, func(id uuid.UUID) bool {
+		if state.entries[id].TournamentID != tournamentID {
+			return false
+		}
+		delete(state.entries, id)
+		return true
+	})
+	state.matchOrder = slices.DeleteFunc(state.matchOrder, func(id uuid.UUID) bool {
+		if state.matches[id].TournamentID != tournamentID {
+			return false
+		}
+		delete(state.matches, id)
+		return true
+	})
+	return nil
+}
+
+func (s *MemoryTournamentStore) CreateAuditEvent(ctx context.Context, tx Tx, event *bracket.AuditEvent) error {
+	state, err := s.txState(tx)
+	if err != nil {
+		return err
+	}
+	state.audit = append(state.audit, *event)
+	return nil
+}
+
+// Kept in insertion order, which is already oldest first
+func (s *MemoryTournamentStore) GetAuditEvents(ctx context.Context, tournamentID string) ([]bracket.AuditEvent, error) {
+	var events []bracket.AuditEvent
+	s.read(func(state *memoryTournamentState) {
+		for _, e := range state.audit {
+			if e.TournamentID.String() == tournamentID {
+				events = append(events, e)
+			}
+		}
+	})
+	return events, nil
+}
+
+type MemoryUserStore struct {
+	mu    sync.RWMutex
+	users map[uuid.UUID]users.User
+}
+
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: make(map[uuid.UUID]users.User)}
+}
+
+// Accepts the same things the SQL store does, a uuid.UUID or its string form
+func (s *MemoryUserStore) GetUser(ctx context.Context, id interface{}) (*users.User, error) {
+	var userID uuid.UUID
+	switch v := id.(type) {
+	case uuid.UUID:
+		userID = v
+	case string:
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			return nil, sql.ErrNoRows
+		}
+		userID = parsed
+	default:
+		return nil, fmt.Errorf("unsupported user ID type %T", id)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &user, nil
+}
+
+func (s *MemoryUserStore) find(match func(u *users.User) bool) (*users.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if match(&u) {
+			return &u, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *MemoryUserStore) GetUserByProvider(ctx context.Context, provider string, providerID string) (*users.User, error) {
+	return s.find(func(u *users.User) bool {
+		return u.Provider != nil && *u.Provider == provider && u.ProviderID != nil && *u.ProviderID == providerID
+	})
+}
+
+func (s *MemoryUserStore) GetUserByEmail(ctx context.Context, email string) (*users.User, error) {
+	return s.find(func(u *users.User) bool { return u.Email == email })
+}
+
+func (s *MemoryUserStore) CreateUser(ctx context.Context, user *users.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.users {
+		if u.ID == user.ID || u.Email == user.Email {
+			return fmt.Errorf("user %s already exists", user.Email)
+		}
+	}
+	u := *user
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now().UTC()
+	}
+	s.users[u.ID] = u
+	return nil
+}
+
+func (s *MemoryUserStore) UpdateUserNameAndAvatar(ctx context.Context, user *users.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[user.ID]; ok {
+		u.Username = user.Username
+		u.AvatarURL = user.AvatarURL
+		s.users[user.ID] = u
+	}
+	return nil
+}
+
+func (s *MemoryUserStore) ListUsers(ctx context.Context) ([]users.User, error) {
+	s.mu.RLock()
+	list := make([]users.User, 0, len(s.users))
+	for _, u := range s.users {
+		list = append(list, u)
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list, nil
+}
+
+func (s *MemoryUserStore) SetUserAdmin(ctx context.Context, id uuid.UUID, isAdmin bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	u.IsAdmin = isAdmin
+	s.users[id] = u
+	return nil
+}
+
+// Only the user goes, the memory stores don't know about each other so their tournaments stay behind
+func (s *MemoryUserStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(s.users, id)
+	return nil
+}
+
+type MemoryUploadStore struct {
+	mu      sync.RWMutex
+	uploads map[uuid.UUID]media.Upload
+}
+
+func NewMemoryUploadStore() *MemoryUploadStore {
+	return &MemoryUploadStore{uploads: make(map[uuid.UUID]media.Upload)}
+}
+
+func (s *MemoryUploadStore) CreateUpload(ctx context.Context, upload *media.Upload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.uploads[upload.ID]; ok {
+		return fmt.Errorf("upload %s already exists", upload.ID)
+	}
+	u := *upload
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now().UTC()
+	}
+	s.uploads[u.ID] = u
+	return nil
+}
+
+func (s *MemoryUploadStore) GetUpload(ctx context.Context, id string) (*media.Upload, error) {
+	uploadID, err := uuid.Parse(id)
+	if err != nil {
+		return &media.Upload{}, sql.ErrNoRows
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	upload, ok := s.uploads[uploadID]
+	if !ok {
+		return &upload, sql.ErrNoRows
+	}
+	return &upload, nil
+}
+
+var (
+	_ TournamentRepository = (*MemoryTournamentStore)(nil)
+	_ UserRepository       = (*MemoryUserStore)(nil)
+	_ UploadRepository     = (*MemoryUploadStore)(nil)
+)
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createMemoryTournament(t *testing.T, store *MemoryTournamentStore) (*bracket.Tournament, []bracket.Match) {
+	t.Helper()
+	ctx := context.Background()
+
+	tournament := &bracket.Tournament{
+		ID:      uuid.New(),
+		OwnerID: uuid.MustParse(testSuperUserID),
+		Name:    "Memory Tournament",
+		Status:  bracket.TournamentStarted,
+		Type:    bracket.SingleElimination,
+	}
+	matches := []bracket.Match{
+		{ID: uuid.New(), TournamentID: tournament.ID, BracketSide: bracket.WinnersSide, RoundNumber: 2, MatchOrder: 1, Status: bracket.MatchPending},
+		{ID: uuid.New(), TournamentID: tournament.ID, BracketSide: bracket.WinnersSide, RoundNumber: 1, MatchOrder: 2, Status: bracket.MatchPending},
+		{ID: uuid.New(), TournamentID: tournament.ID, BracketSide: bracket.WinnersSide, RoundNumber: 1, MatchOrder: 1, Status: bracket.MatchPending},
+	}
+
+	tx, err := store.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, store.CreateTournament(ctx, tx, tournament))
+	require.NoError(t, store.CreateMatches(ctx, tx, matches))
+	require.NoError(t, tx.Commit())
+
+	return tournament, matches
+}
+
+func TestMemoryTournamentStore_CommitAndOrdering(t *testing.T) {
+	store := NewMemoryTournamentStore()
+	ctx := context.Background()
+	tournament, matches := createMemoryTournament(t, store)
+
+	fetched, err := store.GetTournament(ctx, tournament.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, tournament.Name, fetched.Name)
+	assert.False(t, fetched.CreatedAt.IsZero())
+
+	fetchedMatches, err := store.GetMatches(ctx, tournament.ID.String())
+	require.NoError(t, err)
+	require.Len(t, fetchedMatches, 3)
+	assert.Equal(t, matches[2].ID, fetchedMatches[0].ID)
+	assert.Equal(t, matches[1].ID, fetchedMatches[1].ID)
+	assert.Equal(t, matches[0].ID, fetchedMatches[2].ID)
+
+	_, err = store.GetTournament(ctx, uuid.NewString())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = store.GetMatch(ctx, "not-a-uuid")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestMemoryTournamentStore_Rollback(t *testing.T) {
+	store := NewMemoryTournamentStore()
+	ctx := context.Background()
+	tournament, matches := createMemoryTournament(t, store)
+
+	tx, err := store.BeginTx(ctx)
+	require.NoError(t, err)
+
+	match, err := store.GetMatchTx(ctx, tx, matches[2].ID.String())
+	require.NoError(t, err)
+	match.Status = bracket.MatchFinished
+	require.NoError(t, store.UpdateMatch(ctx, tx, match))
+	require.NoError(t, store.UpdateTournamentStatusTx(ctx, tx, tournament.ID.String(), bracket.TournamentCompleted))
+
+	// Visible inside the transaction, not outside of it
+	inTx, err := store.GetMatchTx(ctx, tx, matches[2].ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.MatchFinished, inTx.Status)
+	outside, err := store.GetMatch(ctx, matches[2].ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.MatchPending, outside.Status)
+
+	require.NoError(t, tx.Rollback())
+	assert.ErrorIs(t, tx.Commit(), ErrTxDone)
+	_, err = store.GetMatchTx(ctx, tx, matches[2].ID.String())
+	assert.ErrorIs(t, err, ErrTxDone)
+
+	after, err := store.GetMatch(ctx, matches[2].ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.MatchPending, after.Status)
+	fetched, err := store.GetTournament(ctx, tournament.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, bracket.TournamentStarted, fetched.Status)
+
+	// The write lock is released, so new transactions still work
+	tx, err = store.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+}
+
+func TestMemoryTournamentStore_ConcurrentTransactions(t *testing.T) {
+	store := NewMemoryTournamentStore()
+	ctx := context.Background()
+	tournament, _ := createMemoryTournament(t, store)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tx, err := store.BeginTx(ctx)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer tx.Rollback()
+			entry := bracket.Entry{ID: uuid.New(), TournamentID: tournament.ID, Name: "Entry", Seed: i + 1}
+			assert.NoError(t, store.CreateEntries(ctx, tx, []bracket.Entry{entry}))
+			assert.NoError(t, tx.Commit())
+		}(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.GetEntries(ctx, tournament.ID.String())
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	entries, err := store.GetEntries(ctx, tournament.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, entries, 20)
+	for i, e := range entries {
+		assert.Equal(t, i+1, e.Seed)
+	}
+}
+
+func TestMemoryUserStore(t *testing.T) {
+	store := NewMemoryUserStore()
+	ctx := context.Background()
+
+	provider, providerID := "discord", "1234"
+	user := &users.User{ID: uuid.New(), Email: "someone@example.com", Username: "someone", Provider: &provider, ProviderID: &providerID}
+	require.NoError(t, store.CreateUser(ctx, user))
+	assert.Error(t, store.CreateUser(ctx, user))
+
+	fetched, err := store.GetUser(ctx, user.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, user.Email, fetched.Email)
+
+	fetched, err = store.GetUserByProvider(ctx, provider, providerID)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, fetched.ID)
+
+	_, err = store.GetUserByEmail(ctx, "nobody@example.com")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.NoError(t, store.SetUserAdmin(ctx, user.ID, true))
+	list, err := store.ListUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.True(t, list[0].IsAdmin)
+
+	require.NoError(t, store.DeleteUser(ctx, user.ID))
+	assert.ErrorIs(t, store.DeleteUser(ctx, user.ID), sql.ErrNoRows)
+	assert.ErrorIs(t, store.SetUserAdmin(ctx, user.ID, true), sql.ErrNoRows)
+}
+
+func TestMemoryTournamentStore_Delete(t *testing.T) {
+	store := NewMemoryTournamentStore()
+	ctx := context.Background()
+	tournament, _ := createMemoryTournament(t, store)
+	other, _ := createMemoryTournament(t, store)
+
+	tx, err := store.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteTournament(ctx, tx, tournament.ID.String()))
+	assert.ErrorIs(t, store.DeleteTournament(ctx, tx, tournament.ID.String()), sql.ErrNoRows)
+	require.NoError(t, store.CreateAuditEvent(ctx, tx, &bracket.AuditEvent{ID: uuid.New(), TournamentID: tournament.ID, Action: bracket.AuditTournamentDeleted}))
+	require.NoError(t, tx.Commit())
+
+	_, err = store.GetTournament(ctx, tournament.ID.String())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	events, err := store.GetAuditEvents(ctx, tournament.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+	matches, err := store.GetMatches(ctx, tournament.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+
+	remaining, err := store.ListTournaments(ctx)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, other.ID, remaining[0].ID)
+	matches, err = store.GetMatches(ctx, other.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, matches, 3)
+}
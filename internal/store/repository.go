@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/media"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
+)
+
+// What services see of the storage layer. The sqlx stores in this package implement these for both SQLite and Postgres.
+// Lookups of a single row return sql.ErrNoRows when nothing matches, whatever the backend.
+
+// An open transaction. Only pass it back to the repository that started it
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+type TournamentRepository interface {
+	BeginTx(ctx context.Context) (Tx, error)
+
+	CreateTournament(ctx context.Context, tx Tx, tournament *bracket.Tournament) error
+	CreateEntries(ctx context.Context, tx Tx, entries []bracket.Entry) error
+	CreateMatches(ctx context.Context, tx Tx, matches []bracket.Match) error
+
+	GetTournament(ctx context.Context, id string) (*bracket.Tournament, error)
+	GetTournamentsByUserID(ctx context.Context, userID uuid.UUID) ([]bracket.Tournament, error)
+	// Every tournament regardless of owner, newest first. Only for admin tooling
+	ListTournaments(ctx context.Context) ([]bracket.Tournament, error)
+	GetEntries(ctx context.Context, tournamentID string) ([]bracket.Entry, error)
+	GetEntry(ctx context.Context, id string) (*bracket.Entry, error)
+	GetMatches(ctx context.Context, tournamentID string) ([]bracket.Match, error)
+	GetMatch(ctx context.Context, id string) (*bracket.Match, error)
+
+	GetMatchTx(ctx context.Context, tx Tx, id string) (*bracket.Match, error)
+	GetMatchesTx(ctx context.Context, tx Tx, tournamentID string) ([]bracket.Match, error)
+	GetEntriesTx(ctx context.Context, tx Tx, tournamentID string) ([]bracket.Entry, error)
+	// Counted in the transaction that creates the tournament, so concurrent creates can't all squeeze under a quota
+	CountTournamentsByUserIDTx(ctx context.Context, tx Tx, userID uuid.UUID) (int, error)
+	UpdateMatch(ctx context.Context, tx Tx, match *bracket.Match) error
+	UpdateTournamentStatusTx(ctx context.Context, tx Tx, tournamentID string, status bracket.TournamentStatus) error
+	SetTournamentArchivedTx(ctx context.Context, tx Tx, tournamentID string, archivedAt *time.Time) error
+	UpdateTournamentSettingsTx(ctx context.Context, tx Tx, tournament *bracket.Tournament) error
+	DeleteMatchesTx(ctx context.Context, tx Tx, tournamentID string) error
+	// Byes count as decided
+	HasPendingMatchesTx(ctx context.Context, tx Tx, tournamentID string) (bool, error)
+
+	GetActiveLinkedEntries(ctx context.Context) ([]bracket.Entry, error)
+	UpdateEntryLinkStatus(ctx context.Context, entryID uuid.UUID, status bracket.LinkStatus, checkedAt time.Time) error
+	UpdateEntryMetadata(ctx context.Context, tx Tx, entry *bracket.Entry) error
+	DisqualifyEntryTx(ctx context.Context, tx Tx, entryID string, at time.Time) error
+
+	// Entries and matches go with it
+	DeleteTournament(ctx context.Context, tx Tx, id string) error
+
+	// Append only, there's deliberately no way to change or remove an event
+	CreateAuditEvent(ctx context.Context, tx Tx, event *bracket.AuditEvent) error
+	// Oldest first. Still returns the events of a deleted tournament
+	GetAuditEvents(ctx context.Context, tournamentID string) ([]bracket.AuditEvent, error)
+}
+
+type UserRepository interface {
+	GetUser(ctx context.Context, id interface{}) (*users.User, error)
+	GetUserByProvider(ctx context.Context, provider string, providerID string) (*users.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*users.User, error)
+	CreateUser(ctx context.Context, user *users.User) error
+	UpdateUserNameAndAvatar(ctx context.Context, user *users.User) error
+
+	ListUsers(ctx context.Context) ([]users.User, error)
+	SetUserAdmin(ctx context.Context, id uuid.UUID, isAdmin bool) error
+	// The SQL stores also remove everything the user owns, their tournaments and upload records
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+}
+
+type UploadRepository interface {
+	CreateUpload(ctx context.Context, upload *media.Upload) error
+	GetUpload(ctx context.Context, id string) (*media.Upload, error)
+}
+
+var (
+	_ TournamentRepository = (*TournamentStore)(nil)
+	_ UserRepository       = (*UserStore)(nil)
+	_ UploadRepository     = (*UploadStore)(nil)
+)
@@ -3,6 +3,8 @@ package store
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
 	"github.com/google/uuid"
@@ -14,19 +16,27 @@ type TournamentStore struct {
 }
 
 const (
-	createTournamentQuery = `INSERT INTO tournaments (id, owner_id, name, status, tournament_type, score_requirement)
-        VALUES (:id, :owner_id, :name, :status, :tournament_type, :score_requirement)`
-	createEntriesQuery = `INSERT INTO entries (id, tournament_id, name, seed, embed_link)
-            VALUES (:id, :tournament_id, :name, :seed, :embed_link)`
-	createMatchesQuery = `INSERT INTO matches (id, tournament_id, bracket_side, round_number, match_order, entry_1_id, entry_2_id, status, winner_next_match_id, winner_next_slot, loser_next_match_id, loser_next_slot, winner_slot, is_bye)
-		VALUES (:id, :tournament_id, :bracket_side, :round_number, :match_order, :entry_1_id, :entry_2_id, :status, :winner_next_match_id, :winner_next_slot, :loser_next_match_id, :loser_next_slot, :winner_slot, :is_bye)`
-	getTournamentQuery        = "SELECT * FROM tournaments WHERE id = ?"
-	getTournamentsByUserQuery = "SELECT * FROM tournaments WHERE owner_id = ? ORDER BY created_at DESC"
-	getEntriesQuery           = "SELECT * FROM entries WHERE tournament_id = ? ORDER BY seed ASC"
-	getEntryQuery             = "SELECT * FROM entries WHERE id = ?"
-	getMatchesQuery           = "SELECT * FROM matches WHERE tournament_id = ? ORDER BY round_number ASC, match_order ASC"
-	getMatchQuery             = "SELECT * FROM matches WHERE id = ?"
-	updateMatchQuery          = `UPDATE matches SET
+	createTournamentQuery = `INSERT INTO tournaments (id, owner_id, name, status, tournament_type, score_requirement,
+			description, cover_image_url, visibility, bronze_match, ordering, group_count, group_advance)
+        VALUES (:id, :owner_id, :name, :status, :tournament_type, :score_requirement,
+			:description, :cover_image_url, :visibility, :bronze_match, :ordering, :group_count, :group_advance)`
+	createEntriesQuery = `INSERT INTO entries (id, tournament_id, name, seed, embed_link, start_seconds, end_seconds, upload_id,
+			series_title, theme_type, theme_sequence, song_title, artist, season, year, thumbnail_url, disqualified_at)
+            VALUES (:id, :tournament_id, :name, :seed, :embed_link, :start_seconds, :end_seconds, :upload_id,
+			:series_title, :theme_type, :theme_sequence, :song_title, :artist, :season, :year, :thumbnail_url, :disqualified_at)`
+	createMatchesQuery = `INSERT INTO matches (id, tournament_id, bracket_side, round_number, match_order, entry_1_id, entry_2_id, score_1, score_2, status, winner_next_match_id, winner_next_slot, loser_next_match_id, loser_next_slot, winner_slot, is_bye, decided_at, forfeit, scheduled_at, stream_url, group_number)
+		VALUES (:id, :tournament_id, :bracket_side, :round_number, :match_order, :entry_1_id, :entry_2_id, :score_1, :score_2, :status, :winner_next_match_id, :winner_next_slot, :loser_next_match_id, :loser_next_slot, :winner_slot, :is_bye, :decided_at, :forfeit, :scheduled_at, :stream_url, :group_number)`
+	getTournamentQuery          = "SELECT * FROM tournaments WHERE id = ?"
+	getTournamentsByUserQuery   = "SELECT * FROM tournaments WHERE owner_id = ? ORDER BY created_at DESC"
+	listTournamentsQuery        = "SELECT * FROM tournaments ORDER BY created_at DESC"
+	countTournamentsByUserQuery = "SELECT COUNT(*) FROM tournaments WHERE owner_id = ?"
+	lockUserQuery               = "SELECT id FROM users WHERE id = ? FOR UPDATE"
+	deleteTournamentQuery       = "DELETE FROM tournaments WHERE id = ?"
+	getEntriesQuery             = "SELECT * FROM entries WHERE tournament_id = ? ORDER BY seed ASC"
+	getEntryQuery               = "SELECT * FROM entries WHERE id = ?"
+	getMatchesQuery             = "SELECT * FROM matches WHERE tournament_id = ? ORDER BY round_number ASC, match_order ASC"
+	getMatchQuery               = "SELECT * FROM matches WHERE id = ?"
+	updateMatchQuery            = `UPDATE matches SET
 		tournament_id = :tournament_id,
 		bracket_side = :bracket_side,
 		round_number = :round_number,
@@ -41,114 +51,273 @@ const (
 		loser_next_match_id = :loser_next_match_id,
 		loser_next_slot = :loser_next_slot,
 		winner_slot = :winner_slot,
-		is_bye = :is_bye
+		is_bye = :is_bye,
+		decided_at = :decided_at,
+		forfeit = :forfeit,
+		scheduled_at = :scheduled_at,
+		stream_url = :stream_url,
+		group_number = :group_number
 		WHERE id = :id`
-	hasPreviousPendingMatchesQuery = `SELECT count(*) FROM matches 
-		WHERE tournament_id = ? 
-		AND bracket_side = ?
-		AND status != 'finished'
-		AND (round_number < ? OR (round_number = ? AND match_order < ?))`
-	getNextPendingMatchQuery = `SELECT * FROM matches 
-		WHERE tournament_id = ? 
-		AND status != 'finished' 
-		AND entry_1_id IS NOT NULL 
-		AND entry_2_id IS NOT NULL
-		ORDER BY round_number ASC, match_order ASC 
-		LIMIT 1`
-	updateTournamentStatusQuery = "UPDATE tournaments SET status = ? WHERE id = ?"
+	updateTournamentStatusQuery   = "UPDATE tournaments SET status = ? WHERE id = ?"
+	setTournamentArchivedQuery    = "UPDATE tournaments SET archived_at = ? WHERE id = ?"
+	updateTournamentSettingsQuery = `UPDATE tournaments SET
+		name = :name,
+		tournament_type = :tournament_type,
+		score_requirement = :score_requirement,
+		description = :description,
+		cover_image_url = :cover_image_url,
+		visibility = :visibility,
+		bronze_match = :bronze_match,
+		ordering = :ordering,
+		group_count = :group_count,
+		group_advance = :group_advance
+		WHERE id = :id`
+	deleteMatchesQuery          = "DELETE FROM matches WHERE tournament_id = ?"
+	hasPendingMatchesQuery      = "SELECT count(*) FROM matches WHERE tournament_id = ? AND status != 'finished'"
+	getActiveLinkedEntriesQuery = `SELECT e.* FROM entries e
+		JOIN tournaments t ON t.id = e.tournament_id
+		WHERE t.status != 'completed'
+		AND e.embed_link IS NOT NULL
+		AND e.upload_id IS NULL
+		ORDER BY e.tournament_id, e.seed`
+	updateEntryLinkStatusQuery = "UPDATE entries SET link_status = ?, link_checked_at = ? WHERE id = ?"
+	disqualifyEntryQuery       = "UPDATE entries SET disqualified_at = ? WHERE id = ?"
+	updateEntryMetadataQuery   = `UPDATE entries SET
+		name = :name,
+		series_title = :series_title,
+		theme_type = :theme_type,
+		theme_sequence = :theme_sequence,
+		song_title = :song_title,
+		artist = :artist,
+		season = :season,
+		year = :year,
+		thumbnail_url = :thumbnail_url
+		WHERE id = :id`
+	createAuditEventQuery = `INSERT INTO audit_log (id, tournament_id, match_id, actor_id, actor_name, action, before_state, after_state, created_at)
+		VALUES (:id, :tournament_id, :match_id, :actor_id, :actor_name, :action, :before_state, :after_state, :created_at)`
+	getAuditEventsQuery = "SELECT * FROM audit_log WHERE tournament_id = ? ORDER BY created_at ASC"
 )
 
 func NewTournamentStore(db *sqlx.DB) *TournamentStore {
 	return &TournamentStore{db: db}
 }
 
-func (s *TournamentStore) CreateTournament(ctx context.Context, tx *sqlx.Tx, tournament *bracket.Tournament) error {
-	_, err := tx.NamedExecContext(ctx, createTournamentQuery, tournament)
+func (s *TournamentStore) BeginTx(ctx context.Context) (Tx, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Older callers and exports don't know about visibility or ordering, they get the behaviour from before those existed
+func setTournamentDefaults(tournament *bracket.Tournament) {
+	if tournament.Visibility == "" {
+		tournament.Visibility = bracket.VisibilityPublic
+	}
+	if tournament.Ordering == "" {
+		tournament.Ordering = bracket.OrderingStrict
+	}
+}
+
+// Transactions handed out by the sqlx stores are always *sqlx.Tx, anything else is a bug on the caller's side
+func sqlxTx(tx Tx) *sqlx.Tx {
+	t, ok := tx.(*sqlx.Tx)
+	if !ok {
+		panic(fmt.Sprintf("store: expected *sqlx.Tx, got %T", tx))
+	}
+	return t
+}
+
+func (s *TournamentStore) CreateTournament(ctx context.Context, tx Tx, tournament *bracket.Tournament) error {
+	setTournamentDefaults(tournament)
+	_, err := sqlxTx(tx).NamedExecContext(ctx, createTournamentQuery, tournament)
 	return err
 }
 
-func (s *TournamentStore) CreateEntries(ctx context.Context, tx *sqlx.Tx, entries []bracket.Entry) error {
+func (s *TournamentStore) CreateEntries(ctx context.Context, tx Tx, entries []bracket.Entry) error {
 	if len(entries) == 0 {
 		return nil
 	}
-	_, err := tx.NamedExecContext(ctx, createEntriesQuery, entries)
+	_, err := sqlxTx(tx).NamedExecContext(ctx, createEntriesQuery, entries)
 	return err
 }
 
-func (s *TournamentStore) CreateMatches(ctx context.Context, tx *sqlx.Tx, matches []bracket.Match) error {
+func (s *TournamentStore) CreateMatches(ctx context.Context, tx Tx, matches []bracket.Match) error {
 	if len(matches) == 0 {
 		return nil
 	}
-	_, err := tx.NamedExecContext(ctx, createMatchesQuery, matches)
+	_, err := sqlxTx(tx).NamedExecContext(ctx, createMatchesQuery, matches)
 	return err
 }
 
 func (s *TournamentStore) GetTournament(ctx context.Context, id string) (*bracket.Tournament, error) {
 	var tournament bracket.Tournament
-	err := s.db.GetContext(ctx, &tournament, getTournamentQuery, id)
+	err := s.db.GetContext(ctx, &tournament, s.db.Rebind(getTournamentQuery), id)
 	return &tournament, err
 }
 
 func (s *TournamentStore) GetTournamentsByUserID(ctx context.Context, userID uuid.UUID) ([]bracket.Tournament, error) {
 	var tournaments []bracket.Tournament
-	err := s.db.SelectContext(ctx, &tournaments, getTournamentsByUserQuery, userID)
+	err := s.db.SelectContext(ctx, &tournaments, s.db.Rebind(getTournamentsByUserQuery), userID)
+	return tournaments, err
+}
+
+func (s *TournamentStore) CountTournamentsByUserIDTx(ctx context.Context, tx Tx, userID uuid.UUID) (int, error) {
+	// Postgres runs transactions side by side, locking the owner makes a second create for the same user wait
+	// for the first one to commit. SQLite only lets one transaction write at a time anyway
+	if s.db.DriverName() == "postgres" {
+		if _, err := sqlxTx(tx).ExecContext(ctx, s.db.Rebind(lockUserQuery), userID); err != nil {
+			return 0, err
+		}
+	}
+	var count int
+	err := sqlxTx(tx).GetContext(ctx, &count, s.db.Rebind(countTournamentsByUserQuery), userID)
+	return count, err
+}
+
+func (s *TournamentStore) ListTournaments(ctx context.Context) ([]bracket.Tournament, error) {
+	var tournaments []bracket.Tournament
+	err := s.db.SelectContext(ctx, &tournaments, listTournamentsQuery)
 	return tournaments, err
 }
 
 func (s *TournamentStore) GetEntries(ctx context.Context, tournamentID string) ([]bracket.Entry, error) {
 	var entries []bracket.Entry
-	err := s.db.SelectContext(ctx, &entries, getEntriesQuery, tournamentID)
+	err := s.db.SelectContext(ctx, &entries, s.db.Rebind(getEntriesQuery), tournamentID)
 	return entries, err
 }
 
 func (s *TournamentStore) GetEntry(ctx context.Context, id string) (*bracket.Entry, error) {
 	var entry bracket.Entry
-	err := s.db.GetContext(ctx, &entry, getEntryQuery, id)
+	err := s.db.GetContext(ctx, &entry, s.db.Rebind(getEntryQuery), id)
 	return &entry, err
 }
 
 func (s *TournamentStore) GetMatches(ctx context.Context, tournamentID string) ([]bracket.Match, error) {
 	var matches []bracket.Match
-	err := s.db.SelectContext(ctx, &matches, getMatchesQuery, tournamentID)
+	err := s.db.SelectContext(ctx, &matches, s.db.Rebind(getMatchesQuery), tournamentID)
 	return matches, err
 }
 
 func (s *TournamentStore) GetMatch(ctx context.Context, id string) (*bracket.Match, error) {
 	var match bracket.Match
-	err := s.db.GetContext(ctx, &match, getMatchQuery, id)
+	err := s.db.GetContext(ctx, &match, s.db.Rebind(getMatchQuery), id)
 	return &match, err
 }
 
-func (s *TournamentStore) GetMatchTx(ctx context.Context, tx *sqlx.Tx, id string) (*bracket.Match, error) {
+func (s *TournamentStore) GetMatchTx(ctx context.Context, tx Tx, id string) (*bracket.Match, error) {
 	var match bracket.Match
-	err := tx.GetContext(ctx, &match, getMatchQuery, id)
+	err := sqlxTx(tx).GetContext(ctx, &match, s.db.Rebind(getMatchQuery), id)
 	return &match, err
 }
 
-func (s *TournamentStore) UpdateMatch(ctx context.Context, tx *sqlx.Tx, match *bracket.Match) error {
-	_, err := tx.NamedExecContext(ctx, updateMatchQuery, match)
+func (s *TournamentStore) GetMatchesTx(ctx context.Context, tx Tx, tournamentID string) ([]bracket.Match, error) {
+	var matches []bracket.Match
+	err := sqlxTx(tx).SelectContext(ctx, &matches, s.db.Rebind(getMatchesQuery), tournamentID)
+	return matches, err
+}
+
+func (s *TournamentStore) GetEntriesTx(ctx context.Context, tx Tx, tournamentID string) ([]bracket.Entry, error) {
+	var entries []bracket.Entry
+	err := sqlxTx(tx).SelectContext(ctx, &entries, s.db.Rebind(getEntriesQuery), tournamentID)
+	return entries, err
+}
+
+func (s *TournamentStore) UpdateMatch(ctx context.Context, tx Tx, match *bracket.Match) error {
+	_, err := sqlxTx(tx).NamedExecContext(ctx, updateMatchQuery, match)
 	return err
 }
 
-func (s *TournamentStore) HasPreviousPendingMatchesTx(ctx context.Context, tx *sqlx.Tx, tournamentID string, bracketSide bracket.BracketSide, roundNumber int, matchOrder int) (bool, error) {
+func (s *TournamentStore) UpdateTournamentStatusTx(ctx context.Context, tx Tx, tournamentID string, status bracket.TournamentStatus) error {
+	_, err := sqlxTx(tx).ExecContext(ctx, s.db.Rebind(updateTournamentStatusQuery), status, tournamentID)
+	return err
+}
+
+// A nil archivedAt takes the tournament out of the archive again
+func (s *TournamentStore) SetTournamentArchivedTx(ctx context.Context, tx Tx, tournamentID string, archivedAt *time.Time) error {
+	result, err := sqlxTx(tx).ExecContext(ctx, s.db.Rebind(setTournamentArchivedQuery), archivedAt, tournamentID)
+	if err != nil {
+		return err
+	}
+	return requireRowsAffected(result)
+}
+
+// Everything the settings page can change, status and ownership stay as they are
+func (s *TournamentStore) UpdateTournamentSettingsTx(ctx context.Context, tx Tx, tournament *bracket.Tournament) error {
+	result, err := sqlxTx(tx).NamedExecContext(ctx, updateTournamentSettingsQuery, tournament)
+	if err != nil {
+		return err
+	}
+	return requireRowsAffected(result)
+}
+
+// For throwing the bracket away and generating a new one, entries stay
+func (s *TournamentStore) DeleteMatchesTx(ctx context.Context, tx Tx, tournamentID string) error {
+	_, err := sqlxTx(tx).ExecContext(ctx, s.db.Rebind(deleteMatchesQuery), tournamentID)
+	return err
+}
+
+func (s *TournamentStore) HasPendingMatchesTx(ctx context.Context, tx Tx, tournamentID string) (bool, error) {
 	var count int
-	err := tx.GetContext(ctx, &count, hasPreviousPendingMatchesQuery, tournamentID, bracketSide, roundNumber, roundNumber, matchOrder)
+	err := sqlxTx(tx).GetContext(ctx, &count, s.db.Rebind(hasPendingMatchesQuery), tournamentID)
 	return count > 0, err
 }
 
-func (s *TournamentStore) GetNextPendingMatch(ctx context.Context, tournamentID string) (*bracket.Match, error) {
-	var match bracket.Match
-	err := s.db.GetContext(ctx, &match, getNextPendingMatchQuery, tournamentID)
+// Entries with a pasted link in tournaments that aren't finished yet, for the background link checker
+func (s *TournamentStore) GetActiveLinkedEntries(ctx context.Context) ([]bracket.Entry, error) {
+	var entries []bracket.Entry
+	err := s.db.SelectContext(ctx, &entries, s.db.Rebind(getActiveLinkedEntriesQuery))
+	return entries, err
+}
+
+func (s *TournamentStore) UpdateEntryLinkStatus(ctx context.Context, entryID uuid.UUID, status bracket.LinkStatus, checkedAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(updateEntryLinkStatusQuery), status, checkedAt, entryID)
+	return err
+}
+
+// Only touches the name and descriptive fields, never anything the bracket depends on
+func (s *TournamentStore) UpdateEntryMetadata(ctx context.Context, tx Tx, entry *bracket.Entry) error {
+	_, err := sqlxTx(tx).NamedExecContext(ctx, updateEntryMetadataQuery, entry)
+	return err
+}
+
+func (s *TournamentStore) DisqualifyEntryTx(ctx context.Context, tx Tx, entryID string, at time.Time) error {
+	result, err := sqlxTx(tx).ExecContext(ctx, s.db.Rebind(disqualifyEntryQuery), at, entryID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
+		return err
 	}
-	return &match, nil
+	return requireRowsAffected(result)
 }
 
-func (s *TournamentStore) UpdateTournamentStatusTx(ctx context.Context, tx *sqlx.Tx, tournamentID string, status bracket.TournamentStatus) error {
-	_, err := tx.ExecContext(ctx, updateTournamentStatusQuery, status, tournamentID)
+// Entries and matches are removed by ON DELETE CASCADE, the audit log stays
+func (s *TournamentStore) DeleteTournament(ctx context.Context, tx Tx, id string) error {
+	result, err := sqlxTx(tx).ExecContext(ctx, s.db.Rebind(deleteTournamentQuery), id)
+	if err != nil {
+		return err
+	}
+	return requireRowsAffected(result)
+}
+
+func (s *TournamentStore) CreateAuditEvent(ctx context.Context, tx Tx, event *bracket.AuditEvent) error {
+	_, err := sqlxTx(tx).NamedExecContext(ctx, createAuditEventQuery, event)
 	return err
 }
+
+func (s *TournamentStore) GetAuditEvents(ctx context.Context, tournamentID string) ([]bracket.AuditEvent, error) {
+	var events []bracket.AuditEvent
+	err := s.db.SelectContext(ctx, &events, s.db.Rebind(getAuditEventsQuery), tournamentID)
+	return events, err
+}
+
+// Updates and deletes don't fail on a missing row by themselves, this turns that into the usual sql.ErrNoRows
+func requireRowsAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
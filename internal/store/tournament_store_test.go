@@ -2,14 +2,15 @@ package store
 
 import (
 	"context"
+	"database/sql"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	appdb "github.com/AdamBeresnev/op-rating-app/internal/db"
+	users "github.com/AdamBeresnev/op-rating-app/internal/user"
 	"github.com/AdamBeresnev/op-rating-app/internal/utils"
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/sqlite3"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
@@ -18,31 +19,14 @@ import (
 
 const testSuperUserID = "00000000-0000-0000-0000-000000000001"
 
-// setupTestDB creates an in-memory SQLite database and applies migrations
+// setupTestDB creates a SQLite database in a temp dir and applies migrations.
+// Goes through InitDB so the connections are set up exactly like the server's
 func setupTestDB(t *testing.T) *sqlx.DB {
 	t.Helper()
 
-	database, err := sqlx.Connect("sqlite3", "file::memory:")
-	require.NoError(t, err, "Failed to connect to in-memory DB")
-
-	_, err = database.Exec("PRAGMA foreign_keys = ON;")
-	require.NoError(t, err)
-
-	driver, err := sqlite3.WithInstance(database.DB, &sqlite3.Config{})
-	require.NoError(t, err, "Failed to create migrate driver instance")
-
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://../../migrations",
-		"sqlite3",
-		driver,
-	)
-	require.NoError(t, err, "Failed to create migrate instance")
-
-	err = m.Up()
-	if err != nil && err != migrate.ErrNoChange {
-		require.NoError(t, err, "Failed to apply migrations")
-	}
-
+	database, err := appdb.InitDB(appdb.DriverSQLite, filepath.Join(t.TempDir(), "test.db")+"?_journal_mode=WAL")
+	require.NoError(t, err, "Failed to connect to test DB")
+	require.NoError(t, appdb.RunMigrations(database), "Failed to apply migrations")
 	return database
 }
 
@@ -112,7 +96,7 @@ func TestCreateEntries(t *testing.T) {
 	require.NoError(t, err)
 
 	entries := []bracket.Entry{
-		{ID: uuid.New(), TournamentID: tournamentID, Name: "Entry 1", Seed: 1, EmbedLink: utils.StringOrNil("link1")},
+		{ID: uuid.New(), TournamentID: tournamentID, Name: "Entry 1", Seed: 1, EmbedLink: utils.StringOrNil("link1"), StartSeconds: utils.Ptr(30), EndSeconds: utils.Ptr(120)},
 		{ID: uuid.New(), TournamentID: tournamentID, Name: "Entry 2", Seed: 2, EmbedLink: nil},
 	}
 
@@ -134,11 +118,14 @@ func TestCreateEntries(t *testing.T) {
 	assert.Equal(t, entries[0].Name, fetchedEntries[0].Name)
 	assert.Equal(t, entries[0].Seed, fetchedEntries[0].Seed)
 	assert.Equal(t, *entries[0].EmbedLink, *fetchedEntries[0].EmbedLink)
+	assert.Equal(t, entries[0].StartSeconds, fetchedEntries[0].StartSeconds)
+	assert.Equal(t, entries[0].EndSeconds, fetchedEntries[0].EndSeconds)
 
 	assert.Equal(t, entries[1].ID, fetchedEntries[1].ID)
 	assert.Equal(t, entries[1].Name, fetchedEntries[1].Name)
 	assert.Equal(t, entries[1].Seed, fetchedEntries[1].Seed)
 	assert.Nil(t, fetchedEntries[1].EmbedLink)
+	assert.Nil(t, fetchedEntries[1].StartSeconds)
 }
 
 func TestCreateMatches(t *testing.T) {
@@ -234,3 +221,121 @@ func TestCreateMatches(t *testing.T) {
 	assert.Nil(t, fetchedMatches[2].WinnerNextMatchID)
 	assert.Nil(t, fetchedMatches[2].WinnerNextSlot)
 }
+
+func TestDeleteUserAndTournaments(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	tournamentStore := NewTournamentStore(db)
+	userStore := NewUserStore(db)
+
+	user := &users.User{ID: uuid.New(), Email: "someone@example.com", Username: "someone"}
+	require.NoError(t, userStore.CreateUser(ctx, user))
+	require.NoError(t, userStore.SetUserAdmin(ctx, user.ID, true))
+	fetched, err := userStore.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, fetched.IsAdmin)
+
+	createTournament := func(name string) uuid.UUID {
+		tournament := &bracket.Tournament{ID: uuid.New(), OwnerID: user.ID, Name: name, Status: bracket.TournamentStarted, Type: bracket.SingleElimination}
+		entries := []bracket.Entry{
+			{ID: uuid.New(), TournamentID: tournament.ID, Name: "Entry 1", Seed: 1},
+			{ID: uuid.New(), TournamentID: tournament.ID, Name: "Entry 2", Seed: 2},
+		}
+		match := bracket.Match{ID: uuid.New(), TournamentID: tournament.ID, BracketSide: bracket.WinnersSide, RoundNumber: 1, MatchOrder: 1,
+			Entry1ID: &entries[0].ID, Entry2ID: &entries[1].ID, Status: bracket.MatchPending}
+
+		tx, err := tournamentStore.BeginTx(ctx)
+		require.NoError(t, err)
+		require.NoError(t, tournamentStore.CreateTournament(ctx, tx, tournament))
+		require.NoError(t, tournamentStore.CreateEntries(ctx, tx, entries))
+		require.NoError(t, tournamentStore.CreateMatches(ctx, tx, []bracket.Match{match}))
+		require.NoError(t, tx.Commit())
+		return tournament.ID
+	}
+
+	first := createTournament("First")
+	createTournament("Second")
+
+	all, err := tournamentStore.ListTournaments(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	deleteTournament := func(id uuid.UUID) error {
+		tx, err := tournamentStore.BeginTx(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		if err := tournamentStore.DeleteTournament(ctx, tx, id.String()); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	require.NoError(t, deleteTournament(first))
+	assert.ErrorIs(t, deleteTournament(first), sql.ErrNoRows)
+	entries, err := tournamentStore.GetEntries(ctx, first.String())
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// The remaining tournament goes with its owner
+	require.NoError(t, userStore.DeleteUser(ctx, user.ID))
+	all, err = tournamentStore.ListTournaments(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, all)
+	var left int
+	require.NoError(t, db.GetContext(ctx, &left, "SELECT COUNT(*) FROM entries"))
+	assert.Zero(t, left, "entries")
+	require.NoError(t, db.GetContext(ctx, &left, "SELECT COUNT(*) FROM matches"))
+	assert.Zero(t, left, "matches")
+	_, err = userStore.GetUser(ctx, user.ID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.ErrorIs(t, userStore.DeleteUser(ctx, user.ID), sql.ErrNoRows)
+}
+
+func TestAuditEvents(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	store := NewTournamentStore(db)
+
+	tournament := &bracket.Tournament{ID: uuid.New(), OwnerID: uuid.MustParse(testSuperUserID), Name: "Audited", Status: bracket.TournamentStarted, Type: bracket.SingleElimination}
+	match := bracket.Match{ID: uuid.New(), TournamentID: tournament.ID, BracketSide: bracket.WinnersSide, RoundNumber: 1, MatchOrder: 1, Status: bracket.MatchPending}
+	decided := match
+	decided.Status = bracket.MatchFinished
+	decided.WinnerSlot = utils.Ptr(1)
+	actorID := uuid.MustParse(testSuperUserID)
+
+	tx, err := store.BeginTx(ctx)
+	require.NoError(t, err)
+	require.NoError(t, store.CreateTournament(ctx, tx, tournament))
+	require.NoError(t, store.CreateMatches(ctx, tx, []bracket.Match{match}))
+	require.NoError(t, store.CreateAuditEvent(ctx, tx, &bracket.AuditEvent{
+		ID: uuid.New(), TournamentID: tournament.ID, MatchID: &match.ID, ActorID: &actorID, ActorName: "Mockinator",
+		Action:    bracket.AuditMatchDecided,
+		Before:    bracket.AuditSnapshot{Matches: []bracket.Match{match}},
+		After:     bracket.AuditSnapshot{Matches: []bracket.Match{decided}},
+		CreatedAt: time.Now().UTC(),
+	}))
+	require.NoError(t, store.DeleteTournament(ctx, tx, tournament.ID.String()))
+	require.NoError(t, store.CreateAuditEvent(ctx, tx, &bracket.AuditEvent{
+		ID: uuid.New(), TournamentID: tournament.ID, Action: bracket.AuditTournamentDeleted, CreatedAt: time.Now().UTC().Add(time.Second),
+	}))
+	require.NoError(t, tx.Commit())
+
+	// Both outlive the tournament
+	events, err := store.GetAuditEvents(ctx, tournament.ID.String())
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, bracket.AuditMatchDecided, events[0].Action)
+	assert.Equal(t, &actorID, events[0].ActorID)
+	assert.Equal(t, "Mockinator", events[0].ActorName)
+	require.Len(t, events[0].Before.Matches, 1)
+	assert.Equal(t, bracket.MatchPending, events[0].Before.Matches[0].Status)
+	after := events[0].After.Match(match.ID)
+	require.NotNil(t, after)
+	assert.True(t, after.IsWinner(1))
+
+	assert.Equal(t, bracket.AuditTournamentDeleted, events[1].Action)
+	assert.Nil(t, events[1].ActorID)
+	assert.Empty(t, events[1].Before.Matches)
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"context"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/media"
+	"github.com/jmoiron/sqlx"
+)
+
+type UploadStore struct {
+	db *sqlx.DB
+}
+
+const (
+	createUploadQuery = `INSERT INTO uploads (id, owner_id, original_name, content_type, size)
+		VALUES (:id, :owner_id, :original_name, :content_type, :size)`
+	getUploadQuery = "SELECT * FROM uploads WHERE id = ?"
+)
+
+func NewUploadStore(db *sqlx.DB) *UploadStore {
+	return &UploadStore{db: db}
+}
+
+func (s *UploadStore) CreateUpload(ctx context.Context, upload *media.Upload) error {
+	_, err := s.db.NamedExecContext(ctx, createUploadQuery, upload)
+	return err
+}
+
+func (s *UploadStore) GetUpload(ctx context.Context, id string) (*media.Upload, error) {
+	var upload media.Upload
+	err := s.db.GetContext(ctx, &upload, s.db.Rebind(getUploadQuery), id)
+	return &upload, err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	users "github.com/AdamBeresnev/op-rating-app/internal/user"
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -19,10 +20,17 @@ const (
         AND provider_id = ?
     `
 	getUserByEmailQuery = "SELECT * FROM users WHERE email = ?"
-	createUserQuery = `
+	createUserQuery     = `
 		INSERT INTO users (id, email, username, provider, provider_id, avatar_url) VALUES
 		(:id, :email, :username, :provider, :provider_id, :avatar_url)
 	`
+	listUsersQuery    = "SELECT * FROM users ORDER BY created_at ASC"
+	setUserAdminQuery = "UPDATE users SET is_admin = ? WHERE id = ?"
+	// Uploads can be used by entries in someone else's tournament, those entries just lose the video
+	detachUserUploadsQuery       = "UPDATE entries SET upload_id = NULL WHERE upload_id IN (SELECT id FROM uploads WHERE owner_id = ?)"
+	deleteUserUploadsQuery       = "DELETE FROM uploads WHERE owner_id = ?"
+	deleteUserTournamentsQuery   = "DELETE FROM tournaments WHERE owner_id = ?"
+	deleteUserQuery              = "DELETE FROM users WHERE id = ?"
 	updateUserNameAndAvatarQuery = `
 		UPDATE users SET
 		username = :username,
@@ -37,7 +45,7 @@ func NewUserStore(db *sqlx.DB) *UserStore {
 
 func (s *UserStore) GetUserByProvider(ctx context.Context, provider string, providerID string) (*users.User, error) {
 	var user users.User
-	err := s.db.GetContext(ctx, &user, getUserByProviderQuery, provider, providerID)
+	err := s.db.GetContext(ctx, &user, s.db.Rebind(getUserByProviderQuery), provider, providerID)
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +55,7 @@ func (s *UserStore) GetUserByProvider(ctx context.Context, provider string, prov
 
 func (s *UserStore) GetUserByEmail(ctx context.Context, email string) (*users.User, error) {
 	var user users.User
-	err := s.db.GetContext(ctx, &user, getUserByEmailQuery, email)
+	err := s.db.GetContext(ctx, &user, s.db.Rebind(getUserByEmailQuery), email)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +64,7 @@ func (s *UserStore) GetUserByEmail(ctx context.Context, email string) (*users.Us
 
 func (s *UserStore) GetUser(ctx context.Context, id interface{}) (*users.User, error) {
 	var user users.User
-	err := s.db.GetContext(ctx, &user, getUserQuery, id)
+	err := s.db.GetContext(ctx, &user, s.db.Rebind(getUserQuery), id)
 	if err != nil {
 		return nil, err
 	}
@@ -72,3 +80,39 @@ func (s *UserStore) UpdateUserNameAndAvatar(ctx context.Context, user *users.Use
 	_, err := s.db.NamedExecContext(ctx, updateUserNameAndAvatarQuery, user)
 	return err
 }
+
+func (s *UserStore) ListUsers(ctx context.Context) ([]users.User, error) {
+	var list []users.User
+	err := s.db.SelectContext(ctx, &list, listUsersQuery)
+	return list, err
+}
+
+func (s *UserStore) SetUserAdmin(ctx context.Context, id uuid.UUID, isAdmin bool) error {
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(setUserAdminQuery), isAdmin, id)
+	if err != nil {
+		return err
+	}
+	return requireRowsAffected(result)
+}
+
+func (s *UserStore) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{detachUserUploadsQuery, deleteUserUploadsQuery, deleteUserTournamentsQuery} {
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(query), id); err != nil {
+			return err
+		}
+	}
+	result, err := tx.ExecContext(ctx, s.db.Rebind(deleteUserQuery), id)
+	if err != nil {
+		return err
+	}
+	if err := requireRowsAffected(result); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
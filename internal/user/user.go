@@ -18,4 +18,6 @@ type User struct {
 	Provider   *string   `db:"provider"`
 	ProviderID *string   `db:"provider_id"`
 	AvatarURL  *string   `db:"avatar_url"`
+	// Set with `web user promote`
+	IsAdmin bool `db:"is_admin"`
 }
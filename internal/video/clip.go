@@ -0,0 +1,146 @@
+package video
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Nothing we embed runs for a whole day, anything past that is a typo or garbage
+const maxTimestamp = 24 * 60 * 60
+
+// Parses a timestamp into seconds. Accepts plain seconds ("90"), YouTube style ("1m30s", "1h2m3s")
+// and clock style ("1:30", "1:02:03")
+func ParseTimestamp(s string) (int, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		return 0, fmt.Errorf("empty timestamp")
+	}
+	total, err := parseTimestamp(s)
+	if err != nil {
+		return 0, err
+	}
+	if total > maxTimestamp {
+		return 0, fmt.Errorf("timestamp %q is too far in", s)
+	}
+	return total, nil
+}
+
+func parseTimestamp(s string) (int, error) {
+	if strings.Contains(s, ":") {
+		parts := strings.Split(s, ":")
+		if len(parts) > 3 {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		total := 0
+		for _, p := range parts {
+			n, err := strconv.Atoi(p)
+			if !isDigits(p) || err != nil || n >= 60 {
+				return 0, fmt.Errorf("invalid timestamp %q", s)
+			}
+			total = total*60 + n
+		}
+		return total, nil
+	}
+
+	// Media fragments allow fractional seconds, we don't care about those
+	whole, fraction, hasFraction := strings.Cut(strings.TrimSuffix(s, "s"), ".")
+	if isDigits(whole) && (!hasFraction || isDigits(fraction)) {
+		return strconv.Atoi(whole)
+	}
+
+	total := 0
+	num := ""
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			num += string(r)
+		case r == 'h' || r == 'm' || r == 's':
+			if num == "" {
+				return 0, fmt.Errorf("invalid timestamp %q", s)
+			}
+			n, _ := strconv.Atoi(num)
+			switch r {
+			case 'h':
+				total += n * 3600
+			case 'm':
+				total += n * 60
+			default:
+				total += n
+			}
+			num = ""
+		default:
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	return total, nil
+}
+
+// Only plain ASCII digits, no signs, exponents or spaces like strconv would take
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Formats seconds as m:ss or h:mm:ss
+func FormatTimestamp(seconds int) string {
+	h := seconds / 3600
+	m := (seconds % 3600) / 60
+	s := seconds % 60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// Pulls a clip range out of a pasted link, looking at t=/start=/end= query parameters and #t= media fragments.
+// Returns nil for anything that isn't there or doesn't parse
+func ParseClipRange(link string) (start *int, end *int) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return nil, nil
+	}
+
+	parse := func(v string) *int {
+		if v == "" {
+			return nil
+		}
+		n, err := ParseTimestamp(v)
+		if err != nil {
+			return nil
+		}
+		return &n
+	}
+
+	q := u.Query()
+	if v := q.Get("start"); v != "" {
+		start = parse(v)
+	} else if v := q.Get("t"); v != "" {
+		start = parse(v)
+	}
+	end = parse(q.Get("end"))
+
+	// Media fragments look like #t=30 or #t=30,120
+	if frag := u.Fragment; strings.HasPrefix(frag, "t=") {
+		times := strings.SplitN(strings.TrimPrefix(frag, "t="), ",", 2)
+		if start == nil {
+			start = parse(times[0])
+		}
+		if len(times) > 1 && end == nil {
+			end = parse(times[1])
+		}
+	}
+
+	return start, end
+}
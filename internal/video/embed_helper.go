@@ -1,7 +1,11 @@
 package video
 
 import (
+	"fmt"
+	"net/url"
 	"strings"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/media"
 )
 
 type EmbedType int
@@ -18,7 +22,8 @@ type EmbedInfo struct {
 	URL  string
 }
 
-func GetEmbedInfo(link *string) EmbedInfo {
+// Builds the embed info for a link. start and end are the clip range in seconds and are optional
+func GetEmbedInfo(link *string, start *int, end *int) EmbedInfo {
 	if link == nil || *link == "" {
 		return EmbedInfo{Type: EmbedTypeNone}
 	}
@@ -48,20 +53,63 @@ func GetEmbedInfo(link *string) EmbedInfo {
 				}
 			}
 		} else if strings.Contains(l, "youtube.com/embed/") {
-			return EmbedInfo{Type: EmbedTypeYouTube, URL: l}
+			return EmbedInfo{Type: EmbedTypeYouTube, URL: youTubeClipURL(l, start, end)}
 		}
 
 		if videoID != "" {
-			return EmbedInfo{Type: EmbedTypeYouTube, URL: "https://www.youtube.com/embed/" + videoID}
+			return EmbedInfo{Type: EmbedTypeYouTube, URL: youTubeClipURL("https://www.youtube.com/embed/"+videoID, start, end)}
 		}
 	}
 
-	// Check for regular video files
+	// Our own uploads are always WebM or MP4
+	if strings.HasPrefix(l, media.URLPrefix) {
+		return EmbedInfo{Type: EmbedTypeVideo, URL: mediaFragmentURL(l, start, end)}
+	}
+
+	// Check for regular video files, ignoring any query or fragment at the end
 	lower := strings.ToLower(l)
+	if idx := strings.IndexAny(lower, "?#"); idx != -1 {
+		lower = lower[:idx]
+	}
 	if strings.HasSuffix(lower, ".mp4") || strings.HasSuffix(lower, ".webm") || strings.HasSuffix(lower, ".ogg") || strings.HasSuffix(lower, ".mov") {
-		return EmbedInfo{Type: EmbedTypeVideo, URL: l}
+		return EmbedInfo{Type: EmbedTypeVideo, URL: mediaFragmentURL(l, start, end)}
 	}
 
 	// Default to generic iframe and hope for the best
 	return EmbedInfo{Type: EmbedTypeIframe, URL: l}
 }
+
+// YouTube embeds take the clip range as start/end query parameters
+func youTubeClipURL(embedURL string, start *int, end *int) string {
+	u, err := url.Parse(embedURL)
+	if err != nil {
+		return embedURL
+	}
+	q := u.Query()
+	if start != nil {
+		q.Set("start", fmt.Sprint(*start))
+	}
+	if end != nil {
+		q.Set("end", fmt.Sprint(*end))
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// HTML5 video understands media fragments, #t=start,end
+func mediaFragmentURL(videoURL string, start *int, end *int) string {
+	if start == nil && end == nil {
+		return videoURL
+	}
+	if idx := strings.Index(videoURL, "#"); idx != -1 {
+		videoURL = videoURL[:idx]
+	}
+	from := 0
+	if start != nil {
+		from = *start
+	}
+	if end != nil {
+		return fmt.Sprintf("%s#t=%d,%d", videoURL, from, *end)
+	}
+	return fmt.Sprintf("%s#t=%d", videoURL, from)
+}
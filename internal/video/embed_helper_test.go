@@ -0,0 +1,136 @@
+package video
+
+import (
+	"testing"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int
+		wantErr  bool
+	}{
+		{input: "90", expected: 90},
+		{input: "90s", expected: 90},
+		{input: "1m30s", expected: 90},
+		{input: "1h2m3s", expected: 3723},
+		{input: "1:30", expected: 90},
+		{input: "1:02:03", expected: 3723},
+		{input: "12.5", expected: 12},
+		{input: "", wantErr: true},
+		{input: "abc", wantErr: true},
+		{input: "1:xx", wantErr: true},
+		{input: "m30", wantErr: true},
+		{input: "1:60", wantErr: true},
+		{input: "1:-5", wantErr: true},
+		{input: "1:+5", wantErr: true},
+		{input: "inf", wantErr: true},
+		{input: "nan", wantErr: true},
+		{input: "1e9", wantErr: true},
+		{input: "-5", wantErr: true},
+		{input: "5.", wantErr: true},
+		{input: "1000000", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			actual, err := ParseTimestamp(tc.input)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestParseClipRange(t *testing.T) {
+	testCases := []struct {
+		name          string
+		link          string
+		expectedStart *int
+		expectedEnd   *int
+	}{
+		{name: "No timestamps", link: "https://www.youtube.com/watch?v=abc"},
+		{name: "YouTube t param", link: "https://youtu.be/abc?t=1m30s", expectedStart: utils.Ptr(90)},
+		{name: "Start and end params", link: "https://www.youtube.com/embed/abc?start=30&end=120", expectedStart: utils.Ptr(30), expectedEnd: utils.Ptr(120)},
+		{name: "Media fragment", link: "https://example.com/op.webm#t=10,100", expectedStart: utils.Ptr(10), expectedEnd: utils.Ptr(100)},
+		{name: "Garbage timestamp", link: "https://youtu.be/abc?t=soon"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			start, end := ParseClipRange(tc.link)
+			assert.Equal(t, tc.expectedStart, start)
+			assert.Equal(t, tc.expectedEnd, end)
+		})
+	}
+}
+
+func TestGetEmbedInfo_ClipRange(t *testing.T) {
+	testCases := []struct {
+		name         string
+		link         string
+		start        *int
+		end          *int
+		expectedType EmbedType
+		expectedURL  string
+	}{
+		{
+			name:         "YouTube without clip",
+			link:         "https://www.youtube.com/watch?v=abc&t=90",
+			expectedType: EmbedTypeYouTube,
+			expectedURL:  "https://www.youtube.com/embed/abc",
+		},
+		{
+			name:         "YouTube with clip",
+			link:         "https://youtu.be/abc?t=90",
+			start:        utils.Ptr(90),
+			end:          utils.Ptr(180),
+			expectedType: EmbedTypeYouTube,
+			expectedURL:  "https://www.youtube.com/embed/abc?end=180&start=90",
+		},
+		{
+			name:         "Video file with start only",
+			link:         "https://example.com/op.webm",
+			start:        utils.Ptr(5),
+			expectedType: EmbedTypeVideo,
+			expectedURL:  "https://example.com/op.webm#t=5",
+		},
+		{
+			name:         "Video file replaces existing fragment",
+			link:         "https://example.com/op.mp4#t=1",
+			start:        utils.Ptr(10),
+			end:          utils.Ptr(100),
+			expectedType: EmbedTypeVideo,
+			expectedURL:  "https://example.com/op.mp4#t=10,100",
+		},
+		{
+			name:         "Local upload",
+			link:         "/media/0b7e7dd2-5c5e-4e2a-9a57-5c3b4f1f4c11",
+			end:          utils.Ptr(90),
+			expectedType: EmbedTypeVideo,
+			expectedURL:  "/media/0b7e7dd2-5c5e-4e2a-9a57-5c3b4f1f4c11#t=0,90",
+		},
+		{
+			name:         "Iframe ignores clip",
+			link:         "https://example.com/player",
+			start:        utils.Ptr(10),
+			expectedType: EmbedTypeIframe,
+			expectedURL:  "https://example.com/player",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := GetEmbedInfo(&tc.link, tc.start, tc.end)
+			assert.Equal(t, tc.expectedType, info.Type)
+			assert.Equal(t, tc.expectedURL, info.URL)
+		})
+	}
+}
@@ -0,0 +1,2 @@
+ALTER TABLE entries DROP COLUMN end_seconds;
+ALTER TABLE entries DROP COLUMN start_seconds;
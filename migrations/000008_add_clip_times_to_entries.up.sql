@@ -0,0 +1,2 @@
+ALTER TABLE entries ADD COLUMN start_seconds INTEGER;
+ALTER TABLE entries ADD COLUMN end_seconds INTEGER;
@@ -0,0 +1,4 @@
+ALTER TABLE entries DROP COLUMN upload_id;
+
+DROP INDEX IF EXISTS idx_uploads_owner;
+DROP TABLE IF EXISTS uploads;
@@ -0,0 +1,13 @@
+CREATE TABLE uploads (
+    id TEXT PRIMARY KEY,
+    owner_id TEXT NOT NULL REFERENCES users(id),
+    original_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_uploads_owner ON uploads(owner_id);
+
+-- No REFERENCES here, sqlite refuses to drop columns with foreign keys in the down migration
+ALTER TABLE entries ADD COLUMN upload_id TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE entries DROP COLUMN link_checked_at;
+ALTER TABLE entries DROP COLUMN link_status;
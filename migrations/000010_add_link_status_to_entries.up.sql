@@ -0,0 +1,2 @@
+ALTER TABLE entries ADD COLUMN link_status TEXT CHECK(link_status IN ('ok', 'broken', 'unreachable'));
+ALTER TABLE entries ADD COLUMN link_checked_at DATETIME;
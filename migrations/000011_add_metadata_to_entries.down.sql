@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_entries_artist;
+
+ALTER TABLE entries DROP COLUMN thumbnail_url;
+ALTER TABLE entries DROP COLUMN year;
+ALTER TABLE entries DROP COLUMN season;
+ALTER TABLE entries DROP COLUMN artist;
+ALTER TABLE entries DROP COLUMN song_title;
+ALTER TABLE entries DROP COLUMN theme_sequence;
+ALTER TABLE entries DROP COLUMN theme_type;
+ALTER TABLE entries DROP COLUMN series_title;
@@ -0,0 +1,10 @@
+ALTER TABLE entries ADD COLUMN series_title TEXT;
+ALTER TABLE entries ADD COLUMN theme_type TEXT CHECK(theme_type IN ('OP', 'ED', 'IN'));
+ALTER TABLE entries ADD COLUMN theme_sequence INTEGER;
+ALTER TABLE entries ADD COLUMN song_title TEXT;
+ALTER TABLE entries ADD COLUMN artist TEXT;
+ALTER TABLE entries ADD COLUMN season TEXT CHECK(season IN ('winter', 'spring', 'summer', 'fall'));
+ALTER TABLE entries ADD COLUMN year INTEGER;
+ALTER TABLE entries ADD COLUMN thumbnail_url TEXT;
+
+CREATE INDEX idx_entries_artist ON entries(artist);
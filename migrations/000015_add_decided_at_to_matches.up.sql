@@ -0,0 +1 @@
+ALTER TABLE matches ADD COLUMN decided_at DATETIME;
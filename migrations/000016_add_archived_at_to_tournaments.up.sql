@@ -0,0 +1 @@
+ALTER TABLE tournaments ADD COLUMN archived_at DATETIME;
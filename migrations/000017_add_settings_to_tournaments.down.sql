@@ -0,0 +1,4 @@
+ALTER TABLE tournaments DROP COLUMN bronze_match;
+ALTER TABLE tournaments DROP COLUMN visibility;
+ALTER TABLE tournaments DROP COLUMN cover_image_url;
+ALTER TABLE tournaments DROP COLUMN description;
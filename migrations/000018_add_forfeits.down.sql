@@ -0,0 +1,2 @@
+ALTER TABLE matches DROP COLUMN forfeit;
+ALTER TABLE entries DROP COLUMN disqualified_at;
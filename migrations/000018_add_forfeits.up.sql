@@ -0,0 +1,2 @@
+ALTER TABLE entries ADD COLUMN disqualified_at DATETIME;
+ALTER TABLE matches ADD COLUMN forfeit BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE matches ADD COLUMN scheduled_at DATETIME;
+ALTER TABLE matches ADD COLUMN stream_url TEXT;
@@ -0,0 +1,49 @@
+PRAGMA defer_foreign_keys = ON;
+
+-- Playoffs point at nothing in the group stage, so the group matches can go on their own
+DELETE FROM matches WHERE bracket_side = 'groups';
+
+CREATE TABLE matches_old (
+    id TEXT PRIMARY KEY,
+    tournament_id TEXT NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+
+    bracket_side TEXT NOT NULL CHECK(bracket_side IN ('winners', 'losers', 'finals')),
+    round_number INTEGER NOT NULL,
+    match_order INTEGER NOT NULL,
+
+    entry_1_id TEXT REFERENCES entries(id),
+    entry_2_id TEXT REFERENCES entries(id),
+
+    score_1 INTEGER NOT NULL DEFAULT 0,
+    score_2 INTEGER NOT NULL DEFAULT 0,
+
+    status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'in_progress', 'finished')),
+    winner_next_match_id TEXT REFERENCES matches_old(id),
+    winner_next_slot INTEGER,
+
+    loser_next_match_id TEXT REFERENCES matches_old(id),
+    loser_next_slot INTEGER,
+
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    winner_slot INTEGER,
+    is_bye BOOLEAN NOT NULL DEFAULT FALSE,
+    decided_at DATETIME,
+    forfeit BOOLEAN NOT NULL DEFAULT FALSE,
+    scheduled_at DATETIME,
+    stream_url TEXT
+);
+
+INSERT INTO matches_old (id, tournament_id, bracket_side, round_number, match_order, entry_1_id, entry_2_id, score_1, score_2, status,
+        winner_next_match_id, winner_next_slot, loser_next_match_id, loser_next_slot, created_at, winner_slot, is_bye, decided_at, forfeit,
+        scheduled_at, stream_url)
+    SELECT id, tournament_id, bracket_side, round_number, match_order, entry_1_id, entry_2_id, score_1, score_2, status,
+        winner_next_match_id, winner_next_slot, loser_next_match_id, loser_next_slot, created_at, winner_slot, is_bye, decided_at, forfeit,
+        scheduled_at, stream_url
+    FROM matches;
+
+DROP TABLE matches;
+ALTER TABLE matches_old RENAME TO matches;
+CREATE INDEX idx_matches_tournament ON matches(tournament_id);
+
+ALTER TABLE tournaments DROP COLUMN group_advance;
+ALTER TABLE tournaments DROP COLUMN group_count;
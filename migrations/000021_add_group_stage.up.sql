@@ -0,0 +1,49 @@
+-- SQLite can't change a CHECK constraint in place, so matches is rebuilt to allow the groups side.
+-- Nothing but matches itself references it, the self references are checked once the copy is done
+PRAGMA defer_foreign_keys = ON;
+
+CREATE TABLE matches_new (
+    id TEXT PRIMARY KEY,
+    tournament_id TEXT NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+
+    bracket_side TEXT NOT NULL CHECK(bracket_side IN ('winners', 'losers', 'finals', 'groups')),
+    round_number INTEGER NOT NULL,
+    match_order INTEGER NOT NULL,
+
+    entry_1_id TEXT REFERENCES entries(id),
+    entry_2_id TEXT REFERENCES entries(id),
+
+    score_1 INTEGER NOT NULL DEFAULT 0,
+    score_2 INTEGER NOT NULL DEFAULT 0,
+
+    status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'in_progress', 'finished')),
+    winner_next_match_id TEXT REFERENCES matches_new(id),
+    winner_next_slot INTEGER,
+
+    loser_next_match_id TEXT REFERENCES matches_new(id),
+    loser_next_slot INTEGER,
+
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    winner_slot INTEGER,
+    is_bye BOOLEAN NOT NULL DEFAULT FALSE,
+    decided_at DATETIME,
+    forfeit BOOLEAN NOT NULL DEFAULT FALSE,
+    scheduled_at DATETIME,
+    stream_url TEXT,
+    group_number INTEGER
+);
+
+INSERT INTO matches_new (id, tournament_id, bracket_side, round_number, match_order, entry_1_id, entry_2_id, score_1, score_2, status,
+        winner_next_match_id, winner_next_slot, loser_next_match_id, loser_next_slot, created_at, winner_slot, is_bye, decided_at, forfeit,
+        scheduled_at, stream_url)
+    SELECT id, tournament_id, bracket_side, round_number, match_order, entry_1_id, entry_2_id, score_1, score_2, status,
+        winner_next_match_id, winner_next_slot, loser_next_match_id, loser_next_slot, created_at, winner_slot, is_bye, decided_at, forfeit,
+        scheduled_at, stream_url
+    FROM matches;
+
+DROP TABLE matches;
+ALTER TABLE matches_new RENAME TO matches;
+CREATE INDEX idx_matches_tournament ON matches(tournament_id);
+
+ALTER TABLE tournaments ADD COLUMN group_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tournaments ADD COLUMN group_advance INTEGER NOT NULL DEFAULT 0;
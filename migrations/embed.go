@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations so the binary doesn't need this folder next to it.
+// SQLite migrations live at the top level, Postgres ones in postgres/
+package migrations
+
+import "embed"
+
+//go:embed *.sql postgres/*.sql
+var FS embed.FS
@@ -0,0 +1,7 @@
+DROP TABLE lookup_cache;
+DROP TABLE sessions;
+DROP TABLE matches;
+DROP TABLE entries;
+DROP TABLE uploads;
+DROP TABLE tournaments;
+DROP TABLE users;
@@ -0,0 +1,104 @@
+-- Postgres starts from the schema SQLite reached after its first 12 migrations, new changes go into both folders
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    username TEXT NOT NULL,
+    provider TEXT,
+    provider_id TEXT,
+    avatar_url TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE tournaments (
+    id TEXT PRIMARY KEY,
+    owner_id TEXT NOT NULL REFERENCES users(id),
+    name TEXT NOT NULL,
+    status TEXT NOT NULL CHECK(status IN ('draft', 'started', 'completed')),
+    tournament_type TEXT NOT NULL CHECK(tournament_type IN ('single', 'double')),
+    score_requirement INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE uploads (
+    id TEXT PRIMARY KEY,
+    owner_id TEXT NOT NULL REFERENCES users(id),
+    original_name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE entries (
+    id TEXT PRIMARY KEY,
+    tournament_id TEXT NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    seed INTEGER NOT NULL,
+    embed_link TEXT,
+    start_seconds INTEGER,
+    end_seconds INTEGER,
+    upload_id TEXT REFERENCES uploads(id),
+    link_status TEXT CHECK(link_status IN ('ok', 'broken', 'unreachable')),
+    link_checked_at TIMESTAMPTZ,
+    series_title TEXT,
+    theme_type TEXT CHECK(theme_type IN ('OP', 'ED', 'IN')),
+    theme_sequence INTEGER,
+    song_title TEXT,
+    artist TEXT,
+    season TEXT CHECK(season IN ('winter', 'spring', 'summer', 'fall')),
+    year INTEGER,
+    thumbnail_url TEXT
+);
+
+CREATE TABLE matches (
+    id TEXT PRIMARY KEY,
+    tournament_id TEXT NOT NULL REFERENCES tournaments(id) ON DELETE CASCADE,
+
+    bracket_side TEXT NOT NULL CHECK(bracket_side IN ('winners', 'losers', 'finals')),
+    round_number INTEGER NOT NULL,
+    match_order INTEGER NOT NULL,
+
+    entry_1_id TEXT REFERENCES entries(id),
+    entry_2_id TEXT REFERENCES entries(id),
+
+    score_1 INTEGER NOT NULL DEFAULT 0,
+    score_2 INTEGER NOT NULL DEFAULT 0,
+
+    status TEXT NOT NULL DEFAULT 'pending' CHECK(status IN ('pending', 'in_progress', 'finished')),
+    winner_next_match_id TEXT REFERENCES matches(id),
+    winner_next_slot INTEGER,
+
+    loser_next_match_id TEXT REFERENCES matches(id),
+    loser_next_slot INTEGER,
+
+    winner_slot INTEGER,
+    is_bye BOOLEAN NOT NULL DEFAULT FALSE,
+
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_entries_tournament ON entries(tournament_id);
+CREATE INDEX idx_entries_artist ON entries(artist);
+CREATE INDEX idx_matches_tournament ON matches(tournament_id);
+CREATE INDEX idx_uploads_owner ON uploads(owner_id);
+
+CREATE TABLE sessions (
+    token TEXT PRIMARY KEY,
+    data BYTEA NOT NULL,
+    expiry TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX sessions_expiry_idx ON sessions(expiry);
+
+CREATE TABLE lookup_cache (
+    cache_key TEXT PRIMARY KEY,
+    payload TEXT NOT NULL,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO users (id, email, username, avatar_url)
+VALUES (
+    '00000000-0000-0000-0000-000000000001',
+    'mock_user@fakeemail.lol',
+    'Honse',
+    'https://cdn.discordapp.com/emojis/1405113841930735768.webp'
+);
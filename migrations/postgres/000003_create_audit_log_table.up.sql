@@ -0,0 +1,14 @@
+-- No foreign keys, the history has to survive the tournament and the user being deleted
+CREATE TABLE audit_log (
+    id TEXT PRIMARY KEY,
+    tournament_id TEXT NOT NULL,
+    match_id TEXT,
+    actor_id TEXT,
+    actor_name TEXT NOT NULL,
+    action TEXT NOT NULL,
+    before_state TEXT NOT NULL,
+    after_state TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_audit_log_tournament ON audit_log(tournament_id, created_at);
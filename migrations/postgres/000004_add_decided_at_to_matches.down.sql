@@ -0,0 +1 @@
+ALTER TABLE matches DROP COLUMN decided_at;
@@ -0,0 +1,4 @@
+ALTER TABLE tournaments ADD COLUMN description TEXT;
+ALTER TABLE tournaments ADD COLUMN cover_image_url TEXT;
+ALTER TABLE tournaments ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public' CHECK(visibility IN ('public', 'private'));
+ALTER TABLE tournaments ADD COLUMN bronze_match BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE entries ADD COLUMN disqualified_at TIMESTAMPTZ;
+ALTER TABLE matches ADD COLUMN forfeit BOOLEAN NOT NULL DEFAULT FALSE;
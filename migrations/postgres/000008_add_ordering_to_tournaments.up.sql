@@ -0,0 +1 @@
+ALTER TABLE tournaments ADD COLUMN ordering TEXT NOT NULL DEFAULT 'strict' CHECK(ordering IN ('strict', 'free', 'interleaved'));
@@ -0,0 +1,2 @@
+ALTER TABLE matches DROP COLUMN stream_url;
+ALTER TABLE matches DROP COLUMN scheduled_at;
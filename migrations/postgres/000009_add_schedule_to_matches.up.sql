@@ -0,0 +1,2 @@
+ALTER TABLE matches ADD COLUMN scheduled_at TIMESTAMPTZ;
+ALTER TABLE matches ADD COLUMN stream_url TEXT;
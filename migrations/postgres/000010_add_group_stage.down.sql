@@ -0,0 +1,6 @@
+ALTER TABLE tournaments DROP COLUMN group_advance;
+ALTER TABLE tournaments DROP COLUMN group_count;
+DELETE FROM matches WHERE bracket_side = 'groups';
+ALTER TABLE matches DROP COLUMN group_number;
+ALTER TABLE matches DROP CONSTRAINT matches_bracket_side_check;
+ALTER TABLE matches ADD CONSTRAINT matches_bracket_side_check CHECK(bracket_side IN ('winners', 'losers', 'finals'));
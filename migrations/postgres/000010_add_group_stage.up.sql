@@ -0,0 +1,5 @@
+ALTER TABLE matches DROP CONSTRAINT matches_bracket_side_check;
+ALTER TABLE matches ADD CONSTRAINT matches_bracket_side_check CHECK(bracket_side IN ('winners', 'losers', 'finals', 'groups'));
+ALTER TABLE matches ADD COLUMN group_number INTEGER;
+ALTER TABLE tournaments ADD COLUMN group_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tournaments ADD COLUMN group_advance INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,123 @@
+// Package static serves the compiled CSS and other assets from the binary itself.
+// URLs carry a hash of the file contents so browsers can cache them forever and still pick up new builds.
+package static
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// output.css has to be built with tailwind before `go build`, otherwise the binary ships without styles
+//
+//go:embed css
+var embedded embed.FS
+
+const hashLength = 8
+
+// name.<hash>.ext
+var hashedName = regexp.MustCompile(`^(.+)\.([0-9a-f]{8})(\.[^./]+)$`)
+
+type Assets struct {
+	fsys fs.FS
+	// Set when serving from disk, files change under us so nothing gets hashed or cached
+	dev bool
+
+	mu     sync.Mutex
+	hashes map[string]string
+}
+
+func New(fsys fs.FS) *Assets {
+	return &Assets{fsys: fsys, hashes: make(map[string]string)}
+}
+
+// Reads straight from dir on every request, for running next to the tailwind watcher
+func NewDir(dir string) *Assets {
+	a := New(os.DirFS(dir))
+	a.dev = true
+	return a
+}
+
+var current = New(embedded)
+
+// Switches the package level assets used by URL and Handler. Call it once at startup
+func Use(a *Assets) {
+	current = a
+}
+
+// URL of an asset for templates, e.g. URL("css/output.css") is /static/css/output.1a2b3c4d.css
+func URL(name string) string {
+	return current.URL(name)
+}
+
+func Handler() http.Handler {
+	return current
+}
+
+func (a *Assets) URL(name string) string {
+	hash := a.hash(name)
+	if hash == "" {
+		return "/static/" + name
+	}
+	ext := path.Ext(name)
+	return "/static/" + strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// Empty when the file doesn't exist or we're in dev mode
+func (a *Assets) hash(name string) string {
+	if a.dev {
+		return ""
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if hash, ok := a.hashes[name]; ok {
+		return hash
+	}
+	data, err := fs.ReadFile(a.fsys, name)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])[:hashLength]
+	a.hashes[name] = hash
+	return hash
+}
+
+// Expects to be mounted with the /static/ prefix stripped
+func (a *Assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/")
+
+	if m := hashedName.FindStringSubmatch(name); m != nil && !a.exists(name) {
+		original := m[1] + m[3]
+		// An old hash still gets the file, just without the long cache so it doesn't stick around
+		if a.hash(original) == m[2] {
+			a.serve(w, r, original, "public, max-age=31536000, immutable")
+		} else {
+			a.serve(w, r, original, "no-cache")
+		}
+		return
+	}
+	a.serve(w, r, name, "no-cache")
+}
+
+func (a *Assets) exists(name string) bool {
+	info, err := fs.Stat(a.fsys, name)
+	return err == nil && !info.IsDir()
+}
+
+// No directory listings, only files
+func (a *Assets) serve(w http.ResponseWriter, r *http.Request, name, cacheControl string) {
+	if !a.exists(name) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeFileFS(w, r, a.fsys, name)
+}
@@ -0,0 +1,50 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashedURLs(t *testing.T) {
+	assets := New(fstest.MapFS{
+		"css/output.css": {Data: []byte("body { color: red; }")},
+	})
+
+	url := assets.URL("css/output.css")
+	require.Regexp(t, `^/static/css/output\.[0-9a-f]{8}\.css$`, url)
+	assert.Equal(t, "/static/css/missing.css", assets.URL("css/missing.css"))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		http.StripPrefix("/static/", assets).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get(url)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "body { color: red; }", rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Cache-Control"), "immutable")
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/css")
+
+	// A page rendered before a deploy still gets the file, but not cached for a year
+	rec = get("/static/css/output.00000000.css")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+
+	rec = get("/static/css/output.css")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+
+	assert.Equal(t, http.StatusNotFound, get("/static/css/").Code)
+	assert.Equal(t, http.StatusNotFound, get("/static/css/nope.css").Code)
+}
+
+func TestDevAssetsSkipHashing(t *testing.T) {
+	assets := NewDir(t.TempDir())
+	assert.Equal(t, "/static/css/output.css", assets.URL("css/output.css"))
+}
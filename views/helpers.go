@@ -2,7 +2,10 @@ package views
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
 	"github.com/AdamBeresnev/op-rating-app/internal/middleware"
 	users "github.com/AdamBeresnev/op-rating-app/internal/user"
 )
@@ -10,3 +13,41 @@ import (
 func GetUser(ctx context.Context) *users.User {
 	return middleware.GetAuthenticatedUser(ctx)
 }
+
+// Owners and admins get the buttons that change a tournament, the service checks the same thing again
+func CanManage(ctx context.Context, t *bracket.Tournament) bool {
+	user := GetUser(ctx)
+	return user != nil && (user.ID == t.OwnerID || user.IsAdmin)
+}
+
+// hx-headers for <body>, every htmx request on the page inherits it
+func CSRFHeaders(ctx context.Context) string {
+	headers, _ := json.Marshal(map[string]string{middleware.CSRFHeader: middleware.CSRFToken(ctx)})
+	return string(headers)
+}
+
+// Form value helpers for optional fields
+func StringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func IntValue(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return fmt.Sprint(*i)
+}
+
+const inputClass = "p-2 rounded-md border-2 border-gray-700 bg-gray-900 text-white placeholder-gray-400 focus:ring-indigo-500 focus:border-indigo-500 shadow-sm"
+
+// Values for re-rendering an entry row on the create form
+type EntryFormValues struct {
+	Name      string
+	EmbedLink string
+	Start     string
+	End       string
+	Metadata  bracket.EntryMetadata
+}
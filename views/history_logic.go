@@ -0,0 +1,199 @@
+package views
+
+import (
+	"fmt"
+
+	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
+	"github.com/google/uuid"
+)
+
+// One match in an audit event, as it was before and after
+type MatchChange struct {
+	Label  string
+	Before string
+	After  string
+}
+
+func AuditActor(e bracket.AuditEvent) string {
+	switch {
+	case e.ActorName != "":
+		return e.ActorName
+	case e.ActorID == nil:
+		return "Command line"
+	default:
+		return "Unknown user"
+	}
+}
+
+func AuditSummary(e bracket.AuditEvent, entries map[uuid.UUID]bracket.Entry) string {
+	switch e.Action {
+	case bracket.AuditTournamentCreated:
+		return fmt.Sprintf("Created the tournament with %d matches", len(e.After.Matches))
+	case bracket.AuditEntryUpdated:
+		if e.Before.Entry != nil && e.After.Entry != nil && e.Before.Entry.Name != e.After.Entry.Name {
+			return fmt.Sprintf("Renamed %s to %s", e.Before.Entry.Name, e.After.Entry.Name)
+		}
+		if e.After.Entry != nil {
+			return "Edited the details of " + e.After.Entry.Name
+		}
+		return "Edited an entry"
+	case bracket.AuditMatchDecided:
+		if e.MatchID != nil {
+			if m := e.After.Match(*e.MatchID); m != nil && m.WinnerSlot != nil {
+				return fmt.Sprintf("Picked %s in %s", entryName(winnerID(m), entries), MatchLabel(m))
+			}
+		}
+		return "Decided a match"
+	case bracket.AuditMatchForced:
+		if e.MatchID != nil {
+			if m := e.After.Match(*e.MatchID); m != nil && m.WinnerSlot != nil {
+				return fmt.Sprintf("Forced a win for %s in %s", entryName(winnerID(m), entries), MatchLabel(m))
+			}
+		}
+		return "Forced a match result"
+	case bracket.AuditMatchScheduled:
+		if e.MatchID != nil {
+			if m := e.After.Match(*e.MatchID); m != nil {
+				if m.ScheduledAt == nil {
+					return "Took " + MatchLabel(m) + " off the schedule"
+				}
+				return fmt.Sprintf("Scheduled %s for %s", MatchLabel(m), FormatSchedule(*m.ScheduledAt))
+			}
+		}
+		return "Scheduled a match"
+	case bracket.AuditEntryDisqualified:
+		if e.After.Entry != nil {
+			return "Disqualified " + e.After.Entry.Name
+		}
+		return "Disqualified an entry"
+	case bracket.AuditPlayoffStarted:
+		return fmt.Sprintf("Group stage finished, started the playoff with %d matches", len(e.After.Matches))
+	case bracket.AuditTournamentDeleted:
+		return "Deleted the tournament"
+	case bracket.AuditTournamentStarted:
+		return "Opened the tournament for voting"
+	case bracket.AuditTournamentArchived:
+		return "Archived the tournament"
+	case bracket.AuditTournamentUnarchived:
+		return "Restored the tournament from the archive"
+	case bracket.AuditTournamentUpdated:
+		if len(e.After.Matches) > 0 && e.After.Tournament != nil {
+			return fmt.Sprintf("Changed the format to %s and rebuilt the bracket", formatLabel(e.After.Tournament))
+		}
+		if e.Before.Tournament != nil && e.After.Tournament != nil && e.Before.Tournament.Name != e.After.Tournament.Name {
+			return fmt.Sprintf("Renamed the tournament from %s to %s", e.Before.Tournament.Name, e.After.Tournament.Name)
+		}
+		return "Changed the settings"
+	default:
+		return string(e.Action)
+	}
+}
+
+// Pairs up the match rows of both snapshots. Creation and deletion only have one side
+func AuditMatchChanges(e bracket.AuditEvent, entries map[uuid.UUID]bracket.Entry) []MatchChange {
+	var changes []MatchChange
+	for _, after := range e.After.Matches {
+		change := MatchChange{Label: MatchLabel(&after), After: matchState(&after, entries)}
+		if before := e.Before.Match(after.ID); before != nil {
+			change.Before = matchState(before, entries)
+		}
+		changes = append(changes, change)
+	}
+	for _, before := range e.Before.Matches {
+		if e.After.Match(before.ID) == nil {
+			changes = append(changes, MatchChange{Label: MatchLabel(&before), Before: matchState(&before, entries)})
+		}
+	}
+	return changes
+}
+
+func formatLabel(t *bracket.Tournament) string {
+	if t.HasGroupStage() {
+		playoff := *t
+		playoff.GroupCount = 0
+		return fmt.Sprintf("%d groups with the top %d going into %s", t.GroupCount, t.GroupAdvance, formatLabel(&playoff))
+	}
+	if t.Type == bracket.DoubleElimination {
+		return "double elimination"
+	}
+	if t.BronzeMatch {
+		return "single elimination with a third place match"
+	}
+	return "single elimination"
+}
+
+// Winners R1 #2, Group B R1 #2 and so on
+func MatchLabel(m *bracket.Match) string {
+	if m.BracketSide == bracket.GroupSide && m.GroupNumber != nil {
+		return fmt.Sprintf("Group %s R%d #%d", GroupName(*m.GroupNumber), m.RoundNumber, m.MatchOrder)
+	}
+	return fmt.Sprintf("%s R%d #%d", sideLabel(m.BracketSide), m.RoundNumber, m.MatchOrder)
+}
+
+func sideLabel(side bracket.BracketSide) string {
+	switch side {
+	case bracket.LosersSide:
+		return "Losers"
+	case bracket.FinalsSide:
+		return "Finals"
+	default:
+		return "Winners"
+	}
+}
+
+func matchState(m *bracket.Match, entries map[uuid.UUID]bracket.Entry) string {
+	players := fmt.Sprintf("%s vs %s", entryName(m.Entry1ID, entries), entryName(m.Entry2ID, entries))
+	if m.Status == bracket.MatchFinished && m.WinnerSlot != nil {
+		if m.IsBye {
+			return fmt.Sprintf("%s, bye for %s", players, entryName(winnerID(m), entries))
+		}
+		if m.Forfeit {
+			return fmt.Sprintf("%s, %s won by forfeit", players, entryName(winnerID(m), entries))
+		}
+		return fmt.Sprintf("%s, %s won", players, entryName(winnerID(m), entries))
+	}
+	if m.ScheduledAt != nil {
+		return fmt.Sprintf("%s, scheduled for %s", players, FormatSchedule(*m.ScheduledAt))
+	}
+	return players
+}
+
+func winnerID(m *bracket.Match) *uuid.UUID {
+	if m.WinnerSlot == nil {
+		return nil
+	}
+	if *m.WinnerSlot == 1 {
+		return m.Entry1ID
+	}
+	return m.Entry2ID
+}
+
+// Entries of a deleted tournament are gone, the ID is all that's left
+func entryName(id *uuid.UUID, entries map[uuid.UUID]bracket.Entry) string {
+	if id == nil {
+		return "TBD"
+	}
+	if e, ok := entries[*id]; ok {
+		return e.Name
+	}
+	return "entry " + id.String()[:8]
+}
+
+// A beat B in Winners R1 #2
+func ReplayCaption(m bracket.Match, entries map[uuid.UUID]bracket.Entry) string {
+	loser := m.Entry1ID
+	if m.WinnerSlot != nil && *m.WinnerSlot == 1 {
+		loser = m.Entry2ID
+	}
+	return fmt.Sprintf("%s beat %s in %s", entryName(winnerID(&m), entries), entryName(loser, entries), MatchLabel(&m))
+}
+
+// The match decided in the current step gets the same highlight as the next match on the bracket page
+func ReplayHighlight(data *service.ReplayData) *uuid.UUID {
+	if data.Step == 0 {
+		return nil
+	}
+	id := data.Steps[data.Step-1].Match.ID
+	return &id
+}
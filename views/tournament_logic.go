@@ -1,9 +1,13 @@
 package views
 
 import (
+	"fmt"
 	"sort"
+	"time"
 
 	"github.com/AdamBeresnev/op-rating-app/internal/bracket"
+	"github.com/AdamBeresnev/op-rating-app/internal/media"
+	"github.com/AdamBeresnev/op-rating-app/internal/service"
 	"github.com/google/uuid"
 )
 
@@ -77,3 +81,75 @@ func sortRounds(rounds map[int][]bracket.Match, roundNums []int) {
 		})
 	}
 }
+
+// The link the player should use, uploads take priority over pasted links
+func VideoLink(e *bracket.Entry) *string {
+	if e.UploadID != nil {
+		link := media.URLFor(*e.UploadID)
+		return &link
+	}
+	return e.EmbedLink
+}
+
+func CountBrokenLinks(entries []bracket.Entry) int {
+	count := 0
+	for i := range entries {
+		if entries[i].IsLinkBroken() {
+			count++
+		}
+	}
+	return count
+}
+
+// Single elimination only has a finals side when there's a bronze match, its final is the last winners round
+func FinalsTitle(t *bracket.Tournament) string {
+	if t.Type == bracket.SingleElimination {
+		return "Third Place"
+	}
+	return "Finals"
+}
+
+// Groups are lettered, past Z they just get numbers
+func GroupName(number int) string {
+	if number >= 1 && number <= 26 {
+		return string(rune('A' + number - 1))
+	}
+	return fmt.Sprint(number)
+}
+
+// The ones making the playoff get highlighted in the group table
+func Qualifies(t *bracket.Tournament, place int, r service.EntryResult) bool {
+	return place < t.GroupAdvance && !r.Entry.IsDisqualified()
+}
+
+func OrderingLabel(ordering bracket.Ordering) string {
+	switch ordering {
+	case bracket.OrderingFree:
+		return "Any match can be played as soon as both entries are in"
+	case bracket.OrderingInterleaved:
+		return "Winners and losers rounds take turns, matches within a round in any order"
+	default:
+		return "Matches are played in order, round by round"
+	}
+}
+
+// Fallback for when JS is off, ScheduledTime replaces it with the viewer's local time
+func FormatSchedule(t time.Time) string {
+	return t.UTC().Format("Mon Jan 2, 15:04 MST")
+}
+
+// Prefills the schedule form, empty when there's nothing scheduled
+func ScheduleValue(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Later rounds can be scheduled before anyone knows who's in them
+func entryLabel(e *bracket.Entry) string {
+	if e == nil {
+		return "TBD"
+	}
+	return e.Name
+}